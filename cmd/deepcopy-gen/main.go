@@ -45,29 +45,36 @@ package main
 
 import (
 	"os"
-	"path/filepath"
 
 	"github.com/spf13/pflag"
 	deepcopy_gen "github.com/vine-io/gogogen/deepcopy-gen"
 	"github.com/vine-io/gogogen/gogenerator/args"
 	"github.com/vine-io/gogogen/util/log"
-
-	utilbuild "github.com/vine-io/gogogen/util/build"
 )
 
 func main() {
 	genericArgs, customArgs := deepcopy_gen.NewDefaults()
 
-	// Override defaults.
-	// TODO: move this out of deepcopy-gen
-	genericArgs.GoHeaderFilePath = filepath.Join(args.DefaultSourceTree(), utilbuild.BoilerplatePath())
-
 	fs := pflag.NewFlagSet("deepcopy", pflag.ExitOnError)
 	genericArgs.AddFlags(fs)
 	customArgs.AddFlags(fs)
-	if err := fs.Parse(os.Args); err != nil {
+	if handled, err := genericArgs.RunSubcommand(fs, "deepcopy-gen", os.Args[1:], os.Stdout); handled {
+		if err != nil {
+			if code, ok := args.VerifyExitCode(err, os.Stdout); ok {
+				os.Exit(code)
+			}
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+	opts, err := args.ParseOptions(fs, "deepcopy-gen", os.Args)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if err := genericArgs.ApplyGoGenerate(opts); err != nil {
 		log.Fatalf("Error: %v", err)
 	}
+	genericArgs.Options = opts
 
 	if err := deepcopy_gen.Validate(genericArgs); err != nil {
 		log.Fatalf("Error: %v", err)
@@ -79,6 +86,9 @@ func main() {
 		deepcopy_gen.DefaultNameSystem(),
 		deepcopy_gen.Package,
 	); err != nil {
+		if code, ok := args.VerifyExitCode(err, os.Stdout); ok {
+			os.Exit(code)
+		}
 		log.Fatalf("Error: %v", err)
 	}
 	log.Infof("Completed successfully.")
@@ -0,0 +1,94 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// gogogen runs several generators on the same packages, parsing them once.
+//
+// The packages and the generators, with their options, are declared in a
+// JSON config file, gogogen.json by default:
+//
+//	{
+//	  "packages": ["example.com/api/..."],
+//	  "goHeaderFile": "hack/boilerplate.go.txt",
+//	  "generators": [
+//	    {"name": "deepcopy"},
+//	    {"name": "getters", "generator": "template", "options": {"config": "templates.json"}, "after": ["deepcopy"]}
+//	  ]
+//	}
+//
+// The generators run in dependency order, and a failing one doesn't stop
+// those which don't come after it: the errors of all of them are reported
+// at the end. See the driver package for the generators and their options.
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/spf13/pflag"
+	"github.com/vine-io/gogogen/driver"
+	"github.com/vine-io/gogogen/gogenerator/args"
+	"github.com/vine-io/gogogen/gogenerator/generator"
+	"github.com/vine-io/gogogen/util/log"
+)
+
+func main() {
+	d := driver.New()
+
+	fs := pflag.NewFlagSet("gogogen", pflag.ExitOnError)
+	d.AddFlags(fs)
+	if handled, err := d.Args.RunSubcommand(fs, "gogogen", os.Args[1:], os.Stdout); handled {
+		if err != nil {
+			if code, ok := args.VerifyExitCode(err, os.Stdout); ok {
+				os.Exit(code)
+			}
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+	if _, err := args.ParseOptions(fs, "gogogen", os.Args); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	if err := d.Load(); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if header := d.Config.GoHeaderFile; header != "" && !fs.Changed("go-header-file") {
+		d.Args.GoHeaderFilePath = d.Config.Path(header)
+	}
+
+	if d.Args.Watch {
+		if err := d.Watch(); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+
+	// Run it.
+	if err := d.Run(); err != nil {
+		if code, ok := args.VerifyExitCode(err, os.Stdout); ok {
+			os.Exit(code)
+		}
+		var errs generator.ErrorList
+		if errors.As(err, &errs) {
+			for _, err := range errs {
+				var verr *generator.VerifyError
+				if errors.As(err, &verr) {
+					verr.Report(os.Stdout)
+				}
+			}
+		}
+		log.Fatalf("Error: %v", err)
+	}
+	log.Infof("Completed successfully.")
+}
@@ -4,13 +4,37 @@ import (
 	"os"
 
 	"github.com/spf13/pflag"
+	"github.com/vine-io/gogogen/gogenerator/args"
 	goproto "github.com/vine-io/gogogen/gogorm-gen"
+	"github.com/vine-io/gogogen/util/log"
 )
 
 func main() {
 	g := goproto.New()
+
 	fs := pflag.NewFlagSet("gogorm", pflag.ExitOnError)
 	g.BindFlags(fs)
-	fs.Parse(os.Args)
-	goproto.Run(g)
+	if handled, err := g.Common.RunSubcommand(fs, "gogorm-gen", os.Args[1:], os.Stdout); handled {
+		if err != nil {
+			if code, ok := args.VerifyExitCode(err, os.Stdout); ok {
+				os.Exit(code)
+			}
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+	opts, err := args.ParseOptions(fs, "gogorm-gen", os.Args)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if err := g.ApplyGoGenerate(opts); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	g.Common.Options = opts
+	if err := goproto.Run(g); err != nil {
+		if code, ok := args.VerifyExitCode(err, os.Stdout); ok {
+			os.Exit(code)
+		}
+		log.Fatalf("Error: %v", err)
+	}
 }
@@ -0,0 +1,86 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// plugin-gen runs a generator built as a separate executable.
+//
+// It parses the input packages and sends them to the plugin on its stdin,
+// which answers on its stdout with the files to generate, see the
+// gogenerator/plugin package for the protocol and the Go SDK:
+//
+//	plugin-gen --plugin gen-stringer -i github.com/vine-io/apimachinery/testdata/a
+//
+// The files are then written like those of the built-in generators, so that
+// --verify-only, --dry-run and --output-archive work the same.
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/vine-io/gogogen/gogenerator/args"
+	"github.com/vine-io/gogogen/gogenerator/namer"
+	"github.com/vine-io/gogogen/gogenerator/plugin"
+	"github.com/vine-io/gogogen/util/log"
+)
+
+func main() {
+	genericArgs := args.Default().WithoutDefaultFlagParsing()
+	driver := &plugin.Driver{}
+
+	fs := pflag.NewFlagSet("plugin", pflag.ExitOnError)
+	genericArgs.AddFlags(fs)
+	fs.StringVar(&driver.Path, "plugin", driver.Path, "The plugin executable, either a path or a name to look up in $PATH.")
+	fs.StringVar(&driver.Parameter, "plugin-parameter", driver.Parameter, "A parameter passed as is to the plugin.")
+	if handled, err := genericArgs.RunSubcommand(fs, "plugin-gen", os.Args[1:], os.Stdout); handled {
+		if err != nil {
+			if code, ok := args.VerifyExitCode(err, os.Stdout); ok {
+				os.Exit(code)
+			}
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+	opts, err := args.ParseOptions(fs, "plugin-gen", os.Args)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if err := genericArgs.ApplyGoGenerate(opts); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	genericArgs.Options = opts
+
+	if driver.Path == "" {
+		log.Fatalf("Error: no plugin given")
+	}
+	if len(genericArgs.InputDirs) == 0 {
+		log.Fatalf("Error: input directories cannot be empty")
+	}
+	// The generated files are owned by the plugin rather than by us.
+	genericArgs.Owner = strings.TrimSuffix(filepath.Base(driver.Path), ".exe")
+
+	// Run it.
+	if err := genericArgs.Execute(
+		namer.NameSystems{"public": namer.NewPublicNamer(0)},
+		"public",
+		driver.Packages,
+	); err != nil {
+		if code, ok := args.VerifyExitCode(err, os.Stdout); ok {
+			os.Exit(code)
+		}
+		log.Fatalf("Error: %v", err)
+	}
+	log.Infof("Completed successfully.")
+}
@@ -24,11 +24,11 @@ package main
 
 import (
 	"os"
-	"path/filepath"
 
+	"github.com/spf13/pflag"
 	"github.com/vine-io/gogogen/gogenerator/args"
+	"github.com/vine-io/gogogen/gogenerator/types"
 	set_gen "github.com/vine-io/gogogen/set-gen"
-	utilbuild "github.com/vine-io/gogogen/util/build"
 	"github.com/vine-io/gogogen/util/log"
 )
 
@@ -36,15 +36,33 @@ func main() {
 	arguments := args.Default()
 
 	// Override defaults.
-	arguments.GoHeaderFilePath = filepath.Join(args.DefaultSourceTree(), utilbuild.BoilerplatePath())
 	arguments.InputDirs = []string{"github.com/vine-io/gogogen/util/sets/types"}
 	arguments.OutputPackagePath = "github.com/vine-io/gogogen/util/sets"
+	arguments.Markers = types.NewMarkerRegistry(set_gen.Markers...)
+	arguments.Owner = "set-gen"
+
+	// The subcommands take the flags of the generator, which are otherwise
+	// parsed by Execute.
+	fs := pflag.NewFlagSet("set", pflag.ExitOnError)
+	arguments.AddFlags(fs)
+	if handled, err := arguments.RunSubcommand(fs, arguments.Owner, os.Args[1:], os.Stdout); handled {
+		if err != nil {
+			if code, ok := args.VerifyExitCode(err, os.Stdout); ok {
+				os.Exit(code)
+			}
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
 
 	if err := arguments.Execute(
 		set_gen.NameSystems(),
 		set_gen.DefaultNameSystem(),
 		set_gen.Packages,
 	); err != nil {
+		if code, ok := args.VerifyExitCode(err, os.Stdout); ok {
+			os.Exit(code)
+		}
 		log.Errorf("Error: %v", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,107 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// template-gen is a tool for generating code from text/template files,
+// without writing a generator.
+//
+// The templates are declared in a JSON config file, which gives for each of
+// them the template file, the name of the go file it generates and the
+// markers selecting the types passed to it:
+//
+//	{
+//	  "templates": [{
+//	    "template": "getters.go.tmpl",
+//	    "output": "getters_generated.go",
+//	    "markers": ["+example:getters=true"]
+//	  }]
+//	}
+//
+// The output file is generated next to every input package which has types
+// with all the markers. The template file is executed once per such type,
+// with a TemplateData holding the type (.Type), all the selected types of the
+// package (.Types), the package (.Package) and the whole universe
+// (.Universe). The templates "init" and "finalize", if the file defines them,
+// are executed once before and after the types. Every name system, e.g.
+// "public" or "raw", is a template function taking a type.
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/pflag"
+	"github.com/vine-io/gogogen/gogenerator/args"
+	template_gen "github.com/vine-io/gogogen/template-gen"
+	"github.com/vine-io/gogogen/util/log"
+)
+
+func main() {
+	genericArgs, customArgs := template_gen.NewDefaults()
+
+	if len(os.Args) > 1 && os.Args[1] == args.MarkersCommand {
+		// The markers are declared by the config, which is loaded first.
+		fs := pflag.NewFlagSet("markers", pflag.ExitOnError)
+		customArgs.AddFlags(fs)
+		if err := fs.Parse(os.Args[2:]); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if err := customArgs.Load(genericArgs); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if err := genericArgs.RunMarkers(fs.Args(), os.Stdout); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+
+	fs := pflag.NewFlagSet("template", pflag.ExitOnError)
+	genericArgs.AddFlags(fs)
+	customArgs.AddFlags(fs)
+	if handled, err := genericArgs.RunSubcommand(fs, "template-gen", os.Args[1:], os.Stdout); handled {
+		if err != nil {
+			if code, ok := args.VerifyExitCode(err, os.Stdout); ok {
+				os.Exit(code)
+			}
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+	opts, err := args.ParseOptions(fs, "template-gen", os.Args)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if err := genericArgs.ApplyGoGenerate(opts); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	genericArgs.Options = opts
+
+	if err := customArgs.Load(genericArgs); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if err := template_gen.Validate(genericArgs); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	// Run it.
+	if err := genericArgs.Execute(
+		template_gen.NameSystems(),
+		template_gen.DefaultNameSystem(),
+		template_gen.Packages,
+	); err != nil {
+		if code, ok := args.VerifyExitCode(err, os.Stdout); ok {
+			os.Exit(code)
+		}
+		log.Fatalf("Error: %v", err)
+	}
+	log.Infof("Completed successfully.")
+}
@@ -19,6 +19,7 @@ import (
 
 	"github.com/spf13/pflag"
 	"github.com/vine-io/gogogen/gogenerator/args"
+	"github.com/vine-io/gogogen/gogenerator/types"
 )
 
 // NewDefaults returns arguments for the generator.
@@ -27,6 +28,8 @@ func NewDefaults() (*args.GeneratorArgs, *CustomArgs) {
 	customArgs := &CustomArgs{}
 	genericArgs.CustomArgs = (*CustomArgs)(customArgs) // convert to upstream type to make type-casts work there
 	genericArgs.OutputFileBaseName = "deepcopy_generated"
+	genericArgs.Owner = "deepcopy-gen"
+	genericArgs.Markers = types.NewMarkerRegistry(Markers...)
 	return genericArgs, customArgs
 }
 
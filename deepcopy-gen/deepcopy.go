@@ -45,25 +45,39 @@ const (
 // Known values for the comment tag.
 const tagValuePackage = "package"
 
+// Markers are the comment markers read by deepcopy-gen.
+var Markers = []*types.Marker{
+	{Name: tagEnableName, Scope: types.PackageScope | types.TypeScope, Value: types.StringMarker, Allowed: []string{tagValuePackage, "true", "false"}, Generator: "deepcopy-gen",
+		Description: "Generates deep-copy functions for all the types of a package (=package), or opts a type in or out (=true, =false). Takes an optional \",register\"."},
+	{Name: interfacesTagName, Scope: types.TypeScope, Value: types.StringMarker, Generator: "deepcopy-gen",
+		Description: "Comma-separated list of interfaces the type gets DeepCopy<Interface> methods for."},
+	{Name: interfaceNonPointerTagName, Scope: types.TypeScope, Value: types.BoolMarker, Generator: "deepcopy-gen",
+		Description: "Attaches the DeepCopy<Interface> methods to the non-pointer type when true."},
+}
+
 // enabledTagValue holds parameters from a tagName tag.
 type enableTagValue struct {
 	value    string
 	register bool
 }
 
-func extractEnableTypeTag(t *types.Type) *enableTagValue {
+func extractEnableTypeTag(t *types.Type) (*enableTagValue, error) {
 	comments := append(append([]string{}, t.SecondClosestCommentLines...), t.CommentLines...)
-	return extractEnableTag(comments)
+	tag, err := extractEnableTag(comments)
+	if err != nil {
+		return nil, generator.Wrapf(t.Position, err, "type %v", t)
+	}
+	return tag, nil
 }
 
-func extractEnableTag(comments []string) *enableTagValue {
+func extractEnableTag(comments []string) (*enableTagValue, error) {
 	tagVals := types.ExtractCommentTags("+", comments)[tagEnableName]
 	if tagVals == nil {
-		return nil
+		return nil, nil
 	}
 	// If there are multiple values, abort.
 	if len(tagVals) > 1 {
-		log.Fatalf("Found %d %s tags: %q", len(tagVals), tagEnableName, tagVals)
+		return nil, fmt.Errorf("found %d %s tags: %q", len(tagVals), tagEnableName, tagVals)
 	}
 
 	// If we got there we returning something.
@@ -90,10 +104,10 @@ func extractEnableTag(comments []string) *enableTagValue {
 				tag.register = true
 			}
 		default:
-			log.Fatalf("Unsupported %s param: %q", tagEnableName, parts[i])
+			return nil, fmt.Errorf("unsupported %s param: %q", tagEnableName, parts[i])
 		}
 	}
-	return tag
+	return tag, nil
 }
 
 // TODO: This is created only to reduce number of changes in a single PR.
@@ -121,10 +135,13 @@ func DefaultNameSystem() string {
 	return "public"
 }
 
+// Package returns the packages to generate deep-copy functions for. The
+// problems found in the tags are reported to context.Diagnostics.
 func Package(context *generator.Context, arguments *args.GeneratorArgs) generator.Packages {
 	boilerplate, err := arguments.LoadGoBoilerplate()
 	if err != nil {
-		log.Fatalf("Failed loading boilerplate: %v", err)
+		context.Diagnostics.Report(fmt.Errorf("failed loading boilerplate: %v", err))
+		return nil
 	}
 
 	inputs := sets.NewString(context.Inputs...)
@@ -151,13 +168,18 @@ func Package(context *generator.Context, arguments *args.GeneratorArgs) generato
 			continue
 		}
 
-		ptag := extractEnableTag(pkg.Comments)
+		ptag, err := extractEnableTag(pkg.Comments)
+		if err != nil {
+			context.Diagnostics.Report(fmt.Errorf("package %v: %v", i, err))
+			continue
+		}
 		ptagValue := ""
 		ptagRegister := false
 		if ptag != nil {
 			ptagValue = ptag.value
 			if ptagValue != tagValuePackage {
-				log.Fatalf("Package %v: unsupported %s value: %q", i, tagEnableName, ptagValue)
+				context.Diagnostics.Report(fmt.Errorf("package %v: unsupported %s value: %q", i, tagEnableName, ptagValue))
+				continue
 			}
 			ptagRegister = ptag.register
 			log.Debugf("  tag.value: %q, tag.register: %t", ptagValue, ptagRegister)
@@ -172,14 +194,18 @@ func Package(context *generator.Context, arguments *args.GeneratorArgs) generato
 			// explicitly wants generation.
 			for _, t := range pkg.Types {
 				log.Debugf("  considering type %q", t.Name.String())
-				ttag := extractEnableTypeTag(t)
+				ttag, err := extractEnableTypeTag(t)
+				if err != nil {
+					context.Diagnostics.Report(err)
+					continue
+				}
 				if ttag != nil && ttag.value == "true" {
 					log.Debugf("    tag=true")
-					if !copyableType(t) {
-						log.Fatalf("Type %v requests deepcopy generation but is not copyable", t)
+					if !copyableType(context, t) {
+						context.Diagnostics.Errorf(t.Position, "type %v requests deepcopy generation but is not copyable", t)
+						continue
 					}
 					pkgNeedsGeneration = true
-					break
 				}
 			}
 		}
@@ -194,16 +220,20 @@ func Package(context *generator.Context, arguments *args.GeneratorArgs) generato
 			// in the output directory.
 			// TODO: build a more fundamental concept in gogenerator for dealing
 			// with modifications to vendor packages.
-			if strings.HasPrefix(pkg.SourcePath, arguments.OutputBase) {
+			if arguments.OutputBase != "" && strings.HasPrefix(pkg.SourcePath, arguments.OutputBase) {
 				expandedPath := strings.TrimPrefix(pkg.SourcePath, arguments.OutputBase)
 				if strings.Contains(expandedPath, "/vendor/") {
 					path = expandedPath
 				}
 			}
+			// The packages may be generated concurrently, which mustn't
+			// change the universe.
+			addInterfacePackages(context, pkg)
 			packages = append(packages,
 				&generator.DefaultPackage{
 					PackageName: strings.Split(filepath.Base(pkg.Path), ".")[0],
 					PackagePath: path,
+					Source:      pkg.SourcePath,
 					HeaderText:  header,
 					GeneratorFunc: func(c *generator.Context) (generators []generator.Generator) {
 						return []generator.Generator{
@@ -213,6 +243,7 @@ func Package(context *generator.Context, arguments *args.GeneratorArgs) generato
 					FilterFunc: func(c *generator.Context, t *types.Type) bool {
 						return t.Name.Package == pkg.Path
 					},
+					TypesFrom: []string{pkg.Path},
 				})
 		}
 	}
@@ -220,6 +251,19 @@ func Package(context *generator.Context, arguments *args.GeneratorArgs) generato
 	return packages
 }
 
+// addInterfacePackages adds the packages of the interfaces the types of pkg
+// implement to the universe. The unknown interfaces are reported when
+// generating.
+func addInterfacePackages(c *generator.Context, pkg *types.Package) {
+	var intfs []string
+	for _, t := range pkg.Types {
+		intfs = append(intfs, extractInterfacesTag(t)...)
+	}
+	for _, intf := range intfs {
+		c.AddDir(types.ParseFullyQualifiedName(intf).Package)
+	}
+}
+
 // genDeepCopy produces a file with auto-generated deep-copy functions.
 type genDeepCopy struct {
 	generator.DefaultGen
@@ -256,7 +300,11 @@ func (g *genDeepCopy) Filter(c *generator.Context, t *types.Type) bool {
 	// Filter out types not being processed or not copyable within the package
 	enabled := g.allTypes
 	if !enabled {
-		ttag := extractEnableTypeTag(t)
+		ttag, err := extractEnableTypeTag(t)
+		if err != nil {
+			c.Diagnostics.Report(err)
+			return false
+		}
 		if ttag != nil && ttag.value == "true" {
 			enabled = true
 		}
@@ -264,7 +312,7 @@ func (g *genDeepCopy) Filter(c *generator.Context, t *types.Type) bool {
 	if !enabled {
 		return false
 	}
-	if !copyableType(t) {
+	if !copyableType(c, t) {
 		log.Infof("Type %v is not copyable", t)
 		return false
 	}
@@ -273,8 +321,8 @@ func (g *genDeepCopy) Filter(c *generator.Context, t *types.Type) bool {
 	return true
 }
 
-func (g *genDeepCopy) copyableAndInBounds(t *types.Type) bool {
-	if !copyableType(t) {
+func (g *genDeepCopy) copyableAndInBounds(c *generator.Context, t *types.Type) bool {
+	if !copyableType(c, t) {
 		return false
 	}
 	// Only packages within the restricted range can be processed
@@ -300,38 +348,38 @@ func deepCopyMethod(t *types.Type) (*types.Signature, error) {
 		return nil, nil
 	}
 	if len(f.Signature.Parameters) != 0 {
-		return nil, fmt.Errorf("types %v: invalid DeepCopy signature, expected no paramerts", t)
+		return nil, generator.Errorf(f.Position, "types %v: invalid DeepCopy signature, expected no paramerts", t)
 	}
 	if len(f.Signature.Results) != 1 {
-		return nil, fmt.Errorf("type %v: invalid DeepCopy signature, expected exactly one result", t)
+		return nil, generator.Errorf(f.Position, "type %v: invalid DeepCopy signature, expected exactly one result", t)
 	}
 
 	ptrResult := f.Signature.Results[0].Kind == types.Pointer && f.Signature.Results[0].Elem.Name == t.Name
 	nonPtrResult := f.Signature.Results[0].Name == t.Name
 
 	if !ptrResult && !nonPtrResult {
-		return nil, fmt.Errorf("type %v, invalid DeepCopy signature, expected to return %s or *%s", t, t.Name.Name, t.Name.Name)
+		return nil, generator.Errorf(f.Position, "type %v, invalid DeepCopy signature, expected to return %s or *%s", t, t.Name.Name, t.Name.Name)
 	}
 
 	ptrRcvr := f.Signature.Receiver != nil && f.Signature.Receiver.Kind == types.Pointer && f.Signature.Receiver.Elem.Name == t.Name
 	nonPtrRcvr := f.Signature.Receiver != nil && f.Signature.Receiver.Name == t.Name
 
 	if ptrRcvr && !ptrResult {
-		return nil, fmt.Errorf("type %v, invalid DeepCopy signature, expected a %s result for a %s receiver", t, t.Name.Name, t.Name.Name)
+		return nil, generator.Errorf(f.Position, "type %v, invalid DeepCopy signature, expected a %s result for a %s receiver", t, t.Name.Name, t.Name.Name)
 	}
 	if nonPtrRcvr && !nonPtrResult {
-		return nil, fmt.Errorf("type %v, invalid DeepCopy signature, expected a %s result for a %s receiver", t, t.Name.Name, t.Name.Name)
+		return nil, generator.Errorf(f.Position, "type %v, invalid DeepCopy signature, expected a %s result for a %s receiver", t, t.Name.Name, t.Name.Name)
 	}
 
 	return f.Signature, nil
 }
 
-// deepCopyMethodOrDie returns the signature of a DeepCopy method, nil or calls log.Fatalf
-// if the type does not match.
-func deepCopyMethodOrDie(t *types.Type) *types.Signature {
+// checkedDeepCopyMethod returns the signature of a DeepCopy method, or nil if
+// there is none or if it does not match, which is reported to c.
+func checkedDeepCopyMethod(c *generator.Context, t *types.Type) *types.Signature {
 	ret, err := deepCopyMethod(t)
 	if err != nil {
-		log.Fatalf("%v", err)
+		c.Diagnostics.Report(err)
 	}
 	return ret
 }
@@ -352,34 +400,34 @@ func deepCopyIntoMethod(t *types.Type, name string) (*types.Signature, error) {
 		return nil, nil
 	}
 	if len(f.Signature.Parameters) != 1 {
-		return nil, fmt.Errorf("type %v, invalid DeepCopy signature, expected extractly one parameter", t)
+		return nil, generator.Errorf(f.Position, "type %v, invalid DeepCopy signature, expected extractly one parameter", t)
 	}
 	if len(f.Signature.Results) != 0 {
-		return nil, fmt.Errorf("types %v, invalid DeepCopy signature, expected no result type", t)
+		return nil, generator.Errorf(f.Position, "types %v, invalid DeepCopy signature, expected no result type", t)
 	}
 
 	ptrParam := f.Signature.Parameters[0].Kind == types.Pointer && f.Signature.Parameters[0].Elem.Name == t.Name
 
 	if !ptrParam {
-		return nil, fmt.Errorf("type %v, invalid DeepCopy signature, expected parameter of type *%s", t, t.Name.Name)
+		return nil, generator.Errorf(f.Position, "type %v, invalid DeepCopy signature, expected parameter of type *%s", t, t.Name.Name)
 	}
 
 	ptrRcvr := f.Signature.Receiver != nil && f.Signature.Receiver.Kind == types.Pointer && f.Signature.Receiver.Elem.Name == t.Name
 	nonPtrRcvr := f.Signature.Receiver != nil && f.Signature.Receiver.Name == t.Name
 
 	if !ptrRcvr && !nonPtrRcvr {
-		return nil, fmt.Errorf("type %v, invalid DeepCopy signature, expected a receiver of type %s or *%s", t, t.Name.Name, t.Name.Name)
+		return nil, generator.Errorf(f.Position, "type %v, invalid DeepCopy signature, expected a receiver of type %s or *%s", t, t.Name.Name, t.Name.Name)
 	}
 
 	return f.Signature, nil
 }
 
-// deepCopyIntoMethodOrDie returns the signature of a DeepCopyInto() method, nil or calls log.Fatalf
-// if the type is wrong.
-func deepCopyIntoMethodOrDie(t *types.Type) *types.Signature {
+// checkedDeepCopyIntoMethod returns the signature of a DeepCopyInto() method,
+// or nil if there is none or if it is wrong, which is reported to c.
+func checkedDeepCopyIntoMethod(c *generator.Context, t *types.Type) *types.Signature {
 	ret, err := deepCopyIntoMethod(t, "DeepCopyInto")
 	if err != nil {
-		log.Fatal(err)
+		c.Diagnostics.Report(err)
 	}
 	return ret
 }
@@ -396,9 +444,13 @@ func isRootedUnder(pkg string, roots []string) bool {
 	return false
 }
 
-func copyableType(t *types.Type) bool {
+func copyableType(c *generator.Context, t *types.Type) bool {
 	// If the type opts out of copy-generation, stop.
-	ttag := extractEnableTypeTag(t)
+	ttag, err := extractEnableTypeTag(t)
+	if err != nil {
+		c.Diagnostics.Report(err)
+		return false
+	}
 	if ttag != nil && ttag.value == "false" {
 		return false
 	}
@@ -408,13 +460,20 @@ func copyableType(t *types.Type) bool {
 		return false
 	}
 
+	// Methods can't be declared on instantiations, and generic types would
+	// need their type parameters on every receiver. Fields of these types
+	// are still copied by the types which use them.
+	if t.IsGeneric() || t.IsInstantiated() {
+		return false
+	}
+
 	if t.Kind == types.Alias {
 		// if the underlying built-in not deepcopy-able, deepcopy is opt-in through definition of custom methods.
 		// Note that aliases of builtins, maps, slices can have deepcopy methods.
-		if deepCopyMethodOrDie(t) != nil || deepCopyIntoMethodOrDie(t) != nil {
+		if checkedDeepCopyMethod(c, t) != nil || checkedDeepCopyIntoMethod(c, t) != nil {
 			return true
 		} else {
-			return t.Underlying.Kind != types.Builtin || copyableType(t.Underlying)
+			return t.Underlying.Kind != types.Builtin || copyableType(c, t.Underlying)
 		}
 	}
 
@@ -466,26 +525,29 @@ func (g *genDeepCopy) Init(c *generator.Context, w io.Writer) error {
 	return nil
 }
 
-func (g *genDeepCopy) needsGeneration(t *types.Type) bool {
-	tag := extractEnableTypeTag(t)
+func (g *genDeepCopy) needsGeneration(t *types.Type) (bool, error) {
+	tag, err := extractEnableTypeTag(t)
+	if err != nil {
+		return false, err
+	}
 	tv := ""
 	if tag != nil {
 		tv = tag.value
 		if tv != "true" && tv != "false" {
-			log.Fatalf("Type %v, unsuported %s value: %q", t, tagEnableName, tag.value)
+			return false, generator.Errorf(t.Position, "type %v, unsupported %s value: %q", t, tagEnableName, tag.value)
 		}
 	}
 	if g.allTypes && tv == "false" {
 		// The whole package is being generated, but this type has opted out.
 		log.Debugf("Not generating for type %v because type opted out", t)
-		return false
+		return false, nil
 	}
 	if !g.allTypes && tv != "true" {
 		// The whole package is NOT being generated, and this type has NOT opted in.
 		log.Debugf("Not generating for type %v because type did not opt in", t)
-		return false
+		return false, nil
 	}
-	return true
+	return true, nil
 }
 
 func extractInterfacesTag(t *types.Type) []string {
@@ -516,7 +578,7 @@ func extractNonPointerInterfaces(t *types.Type) (bool, error) {
 	result := values[0] == "true"
 	for _, v := range values {
 		if v == "true" != result {
-			return false, fmt.Errorf("contradicing %s value %s found to previous value %vv", interfaceNonPointerTagName, v, result)
+			return false, generator.Errorf(t.Position, "contradicting %s value %s found to previous value %v", interfaceNonPointerTagName, v, result)
 		}
 	}
 	return result, nil
@@ -531,14 +593,13 @@ func (g *genDeepCopy) deepCopyableInterfacesInner(c *generator.Context, t *types
 
 	var ts []*types.Type
 	for _, intf := range intfs {
-		t := types.ParseFullyQualifiedName(intf)
-		c.AddDir(t.Package)
-		intfT := c.Universe.Type(t)
+		name := types.ParseFullyQualifiedName(intf)
+		intfT := c.Universe.Type(name)
 		if intfT == nil {
-			return nil, fmt.Errorf("unknown type %q in %s of type %s", intf, interfacesTagName, intfT)
+			return nil, generator.Errorf(t.Position, "unknown type %q in %s tag of type %v", intf, interfacesTagName, t)
 		}
 		if intfT.Kind != types.Interface {
-			return nil, fmt.Errorf("type %q in %s tag of type %v is not an interface, but: %s", intf, interfacesTagName, t, intfT.Kind)
+			return nil, generator.Errorf(t.Position, "type %q in %s tag of type %v is not an interface, but: %s", intf, interfacesTagName, t, intfT.Kind)
 		}
 		g.imports.AddType(intfT)
 		ts = append(ts, intfT)
@@ -581,8 +642,8 @@ func (s TypeSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 func (s TypeSlice) Sort()              { sort.Sort(s) }
 
 func (g *genDeepCopy) GenerateType(c *generator.Context, t *types.Type, w io.Writer) error {
-	if !g.needsGeneration(t) {
-		return nil
+	if ok, err := g.needsGeneration(t); err != nil || !ok {
+		return err
 	}
 
 	log.Debugf("Generating deepcopy function for type %v", t)
@@ -590,7 +651,7 @@ func (g *genDeepCopy) GenerateType(c *generator.Context, t *types.Type, w io.Wri
 	sw := generator.NewSnippetWriter(w, c, "$", "$")
 	args := argsFromType(t)
 
-	if deepCopyIntoMethodOrDie(t) == nil {
+	if checkedDeepCopyIntoMethod(c, t) == nil {
 		sw.Do("// DeepCopyInto is an auto-generated deepcopy function, coping the receiver, writing into out. in must be no-nil.\n", args)
 		if isReference(t) {
 			sw.Do("func (in $.type|raw$) DeepCopyInto(out *$.type|raw$) {\n", args)
@@ -598,7 +659,7 @@ func (g *genDeepCopy) GenerateType(c *generator.Context, t *types.Type, w io.Wri
 		} else {
 			sw.Do("func (in *$.type|raw$) DeepCopyInto(out *$.type|raw$) {\n", args)
 		}
-		if deepCopyMethodOrDie(t) != nil {
+		if checkedDeepCopyMethod(c, t) != nil {
 			if t.Methods["DeepCopy"].Signature.Receiver.Kind == types.Pointer {
 				sw.Do("clone := in.DeepCopy()\n", args)
 				sw.Do("*out = *clone\n", nil)
@@ -607,7 +668,7 @@ func (g *genDeepCopy) GenerateType(c *generator.Context, t *types.Type, w io.Wri
 			}
 			sw.Do("return\n", nil)
 		} else {
-			g.generateFor(t, sw)
+			g.generateFor(c, t, sw)
 			sw.Do("return\n", nil)
 		}
 		if isReference(t) {
@@ -616,7 +677,7 @@ func (g *genDeepCopy) GenerateType(c *generator.Context, t *types.Type, w io.Wri
 		sw.Do("}\n\n", nil)
 	}
 
-	if deepCopyMethodOrDie(t) == nil {
+	if checkedDeepCopyMethod(c, t) == nil {
 		sw.Do("// DeepCopy is an auto-generated deepcopy function, copying the receiver, creating a new $.type|raw$.\n", args)
 		if isReference(t) {
 			sw.Do("func (in $.type|raw$) DeepCopy() $.type|raw$ {\n", args)
@@ -690,12 +751,12 @@ func isReference(t *types.Type) bool {
 // we use the system of shadowing 'in' and 'out' so that the same code is valid
 // at any nesting level. This makes the auto-generator easy to understand, and
 // the compiler shouldn't care.
-func (g *genDeepCopy) generateFor(t *types.Type, sw *generator.SnippetWriter) {
+func (g *genDeepCopy) generateFor(c *generator.Context, t *types.Type, sw *generator.SnippetWriter) {
 	// derive inner types if t is an alias. We all the do* methods below with the alias type.
 	// basic rule: generate according to inner type, but construct objects with the alias type.
 	ut := underlyingType(t)
 
-	var f func(*types.Type, *generator.SnippetWriter)
+	var f func(*generator.Context, *types.Type, *generator.SnippetWriter)
 	switch ut.Kind {
 	case types.Builtin:
 		f = g.doBuiltin
@@ -703,26 +764,31 @@ func (g *genDeepCopy) generateFor(t *types.Type, sw *generator.SnippetWriter) {
 		f = g.doMap
 	case types.Slice:
 		f = g.doSlice
+	case types.Array:
+		f = g.doArray
 	case types.Struct:
 		f = g.doStruct
 	case types.Pointer:
 		f = g.doPointer
 	case types.Interface:
 		// interfaces are handled in-line in the other cases
-		log.Fatalf("Hit an interface type %v, This should never happen.", t)
+		c.Diagnostics.Errorf(t.Position, "hit an interface type %v, this should never happen", t)
+		return
 	case types.Alias:
 		// can never happen because we branch on the underlying type which is never an alias
-		log.Fatalf("Hit an alias type %v. This should never happen.", t)
+		c.Diagnostics.Errorf(t.Position, "hit an alias type %v, this should never happen", t)
+		return
 	default:
-		log.Fatalf("Hit an unsupported type %v", t)
+		c.Diagnostics.Errorf(t.Position, "hit an unsupported type %v", t)
+		return
 	}
-	f(t, sw)
+	f(c, t, sw)
 }
 
 // doBuiltin generates code for a builtin or an alias to a builtin. The generated code is
 // is the same for both cases, i.e. it's the code for the underlying type.
-func (g *genDeepCopy) doBuiltin(t *types.Type, sw *generator.SnippetWriter) {
-	if deepCopyMethodOrDie(t) != nil || deepCopyIntoMethodOrDie(t) != nil {
+func (g *genDeepCopy) doBuiltin(c *generator.Context, t *types.Type, sw *generator.SnippetWriter) {
+	if checkedDeepCopyMethod(c, t) != nil || checkedDeepCopyIntoMethod(c, t) != nil {
 		sw.Do("*out = in.DeepCopy()\n", nil)
 		return
 	}
@@ -732,22 +798,23 @@ func (g *genDeepCopy) doBuiltin(t *types.Type, sw *generator.SnippetWriter) {
 
 // doMap generates code for a map or an alias to a map. The generated code is
 // the same for both cases, i.e. it's the code for the underlying type.
-func (g *genDeepCopy) doMap(t *types.Type, sw *generator.SnippetWriter) {
+func (g *genDeepCopy) doMap(c *generator.Context, t *types.Type, sw *generator.SnippetWriter) {
 	ut := underlyingType(t)
 	uet := underlyingType(ut.Elem)
 
-	if deepCopyMethodOrDie(t) != nil || deepCopyIntoMethodOrDie(t) != nil {
+	if checkedDeepCopyMethod(c, t) != nil || checkedDeepCopyIntoMethod(c, t) != nil {
 		sw.Do("*out = in.DeepCopy()\n", nil)
 		return
 	}
 
 	if !ut.Key.IsAssignable() {
-		log.Fatalf("Hit an unsupported type %v for: %v", uet, t)
+		c.Diagnostics.Errorf(t.Position, "hit an unsupported type %v for %v", uet, t)
+		return
 	}
 
 	sw.Do("*out = make($.|raw$, len(*in))\n", t)
 	sw.Do("for key, val := range *in {\n", nil)
-	dc, dci := deepCopyMethodOrDie(ut.Elem), deepCopyIntoMethodOrDie(ut.Elem)
+	dc, dci := checkedDeepCopyMethod(c, ut.Elem), checkedDeepCopyIntoMethod(c, ut.Elem)
 	switch {
 	case dc != nil || dci != nil:
 		// Note: a DeepCopy exists because it is added if DeepCopyInto is manually defined
@@ -771,9 +838,9 @@ func (g *genDeepCopy) doMap(t *types.Type, sw *generator.SnippetWriter) {
 	case uet.Kind == types.Interface:
 		// Note: do not generate code that won't compile as `DeepCopyInterface{}()` is not a valid function
 		if uet.Name.Name == "interface{}" {
-			log.Fatalf("DeepCopy of %q is unsupported. Instead, use method interfaces with DeepCopy<named-interface> as one of the methods.", uet.Name.Name)
+			c.Diagnostics.Errorf(t.Position, "DeepCopy of %q is unsupported. Instead, use method interfaces with DeepCopy<named-interface> as one of the methods.", uet.Name.Name)
 		}
-		sw.Do("if val == nil { (*out}[key] = nil } else {\n", nil)
+		sw.Do("if val == nil { (*out)[key] = nil } else {\n", nil)
 		// Note: if t.Elem has been an alias "J" of an interface "I" in Go, we will see it
 		// as kind Interface of name "J" here, i.e. generate val.DeepCopyJ(). The golang
 		// parser does not given us the underlying interfaces name. So we cannot do any better.
@@ -783,31 +850,38 @@ func (g *genDeepCopy) doMap(t *types.Type, sw *generator.SnippetWriter) {
 		sw.Do("var outVal $.|raw$\n", uet)
 		sw.Do("if val == nil { (*out)[key] = nil } else {\n", nil)
 		sw.Do("in, out := &val, &outVal\n", nil)
-		g.generateFor(ut.Elem, sw)
+		g.generateFor(c, ut.Elem, sw)
+		sw.Do("}\n", nil)
+		sw.Do("(*out)[key] = outVal\n", nil)
+	case uet.Kind == types.Array:
+		sw.Do("var outVal $.|raw$\n", ut.Elem)
+		sw.Do("{\n", nil)
+		sw.Do("in, out := &val, &outVal\n", nil)
+		g.generateFor(c, ut.Elem, sw)
 		sw.Do("}\n", nil)
 		sw.Do("(*out)[key] = outVal\n", nil)
 	case uet.Kind == types.Struct:
 		sw.Do("(*out)[key] = *val.DeepCopy()\n", nil)
 	default:
-		log.Fatalf("Hit an unsupported type %v for %v", uet, nil)
+		c.Diagnostics.Errorf(t.Position, "hit an unsupported type %v for %v", uet, t)
 	}
 	sw.Do("}\n", nil)
 }
 
 // doSlice generates code for a slice or an alias to a slice. The generated code is
 // the same for both, i.e. it's the code for the underlying type.
-func (g *genDeepCopy) doSlice(t *types.Type, sw *generator.SnippetWriter) {
+func (g *genDeepCopy) doSlice(c *generator.Context, t *types.Type, sw *generator.SnippetWriter) {
 	ut := underlyingType(t)
 	uet := underlyingType(ut.Elem)
 
-	if deepCopyMethodOrDie(t) != nil || deepCopyIntoMethodOrDie(t) != nil {
+	if checkedDeepCopyMethod(c, t) != nil || checkedDeepCopyIntoMethod(c, t) != nil {
 		sw.Do("*out = in.DeepCopy()\n", nil)
 		return
 	}
 
 	sw.Do("*out = make($.|raw$, len(*in))\n", t)
-	if deepCopyMethodOrDie(ut.Elem) != nil || deepCopyIntoMethodOrDie(ut.Elem) != nil {
-		sw.Do("for i := range *in\n", nil)
+	if checkedDeepCopyMethod(c, ut.Elem) != nil || checkedDeepCopyIntoMethod(c, ut.Elem) != nil {
+		sw.Do("for i := range *in {\n", nil)
 		// Note: a DeepCopyInto exists because it is added if DeepCopy is manually defined
 		sw.Do("(*in)[i].DeepCopyInto(&(*out)[i])\n", nil)
 		sw.Do("}\n", nil)
@@ -815,15 +889,15 @@ func (g *genDeepCopy) doSlice(t *types.Type, sw *generator.SnippetWriter) {
 		sw.Do("copy(*out, *in)\n", nil)
 	} else {
 		sw.Do("for i := range *in {\n", nil)
-		if uet.Kind == types.Slice || uet.Kind == types.Map || uet.Kind == types.Pointer || deepCopyMethodOrDie(ut.Elem) != nil || deepCopyIntoMethodOrDie(ut.Elem) != nil {
+		if uet.Kind == types.Slice || uet.Kind == types.Map || uet.Kind == types.Pointer || checkedDeepCopyMethod(c, ut.Elem) != nil || checkedDeepCopyIntoMethod(c, ut.Elem) != nil {
 			sw.Do("if (*in)[i] != nil {\n", nil)
 			sw.Do("in, out := &(*in)[i], &(*out)[i]\n", nil)
-			g.generateFor(ut.Elem, sw)
+			g.generateFor(c, ut.Elem, sw)
 			sw.Do("}\n", nil)
 		} else if uet.Kind == types.Interface {
 			// Note: do not generate code that won't compile as `DeepCopyInterfaces{}()` is not a valid function
 			if uet.Name.Name == "interface{}" {
-				log.Fatalf("DeepCopy of %q unsupported. Instead, use named interfaces with DeepCopy<named-interface> as one of the method", uet.Name.Name)
+				c.Diagnostics.Errorf(t.Position, "DeepCopy of %q unsupported. Instead, use named interfaces with DeepCopy<named-interface> as one of the method", uet.Name.Name)
 			}
 			sw.Do("if (*in)[i] != nil {\n", nil)
 			// Note: if t.Elem has been an alias "J" of an interface "I" in Go, we will see it
@@ -832,20 +906,69 @@ func (g *genDeepCopy) doSlice(t *types.Type, sw *generator.SnippetWriter) {
 			sw.Do(fmt.Sprintf("(*out)[i] = (*in)[i].DeepCopy%s()", uet.Name.Name), nil)
 			sw.Do("}\n", nil)
 		} else if uet.Kind == types.Struct {
-			sw.Do("(*in)[i].DeepCopyInto(*out)[i])\n", nil)
+			sw.Do("(*in)[i].DeepCopyInto(&(*out)[i])\n", nil)
+		} else if uet.Kind == types.Array {
+			sw.Do("in, out := &(*in)[i], &(*out)[i]\n", nil)
+			g.generateFor(c, ut.Elem, sw)
 		} else {
-			log.Fatalf("Hit an unsupported type %v for %v", nil)
+			c.Diagnostics.Errorf(t.Position, "hit an unsupported type %v for %v", uet, t)
+		}
+		sw.Do("}\n", nil)
+	}
+}
+
+// doArray generates code for an array or an alias to an array. The generated code is
+// the same for both, i.e. it's the code for the underlying type. Arrays are values, so
+// only elements which aren't deep-assignable need to be copied one by one.
+func (g *genDeepCopy) doArray(c *generator.Context, t *types.Type, sw *generator.SnippetWriter) {
+	ut := underlyingType(t)
+	uet := underlyingType(ut.Elem)
+
+	if checkedDeepCopyMethod(c, t) != nil || checkedDeepCopyIntoMethod(c, t) != nil {
+		sw.Do("*out = in.DeepCopy()\n", nil)
+		return
+	}
+
+	sw.Do("*out = *in\n", nil)
+	if ut.IsAssignable() {
+		return
+	}
+
+	sw.Do("for i := range *in {\n", nil)
+	switch {
+	case checkedDeepCopyMethod(c, ut.Elem) != nil || checkedDeepCopyIntoMethod(c, ut.Elem) != nil:
+		// Note: a DeepCopyInto exists because it is added if DeepCopy is manually defined
+		sw.Do("(*in)[i].DeepCopyInto(&(*out)[i])\n", nil)
+	case uet.Kind == types.Slice || uet.Kind == types.Map || uet.Kind == types.Pointer:
+		sw.Do("if (*in)[i] != nil {\n", nil)
+		sw.Do("in, out := &(*in)[i], &(*out)[i]\n", nil)
+		g.generateFor(c, ut.Elem, sw)
+		sw.Do("}\n", nil)
+	case uet.Kind == types.Interface:
+		// Note: do not generate code that won't compile as `DeepCopyinterface{}()` is not a valid function
+		if uet.Name.Name == "interface{}" {
+			c.Diagnostics.Errorf(t.Position, "DeepCopy of %q unsupported. Instead, use named interfaces with DeepCopy<named-interface> as one of the method", uet.Name.Name)
 		}
+		sw.Do("if (*in)[i] != nil {\n", nil)
+		sw.Do(fmt.Sprintf("(*out)[i] = (*in)[i].DeepCopy%s()\n", uet.Name.Name), nil)
 		sw.Do("}\n", nil)
+	case uet.Kind == types.Struct:
+		sw.Do("(*in)[i].DeepCopyInto(&(*out)[i])\n", nil)
+	case uet.Kind == types.Array:
+		sw.Do("in, out := &(*in)[i], &(*out)[i]\n", nil)
+		g.generateFor(c, ut.Elem, sw)
+	default:
+		c.Diagnostics.Errorf(t.Position, "hit an unsupported type %v for %v", uet, t)
 	}
+	sw.Do("}\n", nil)
 }
 
 // doStruct generates code for a struct or an alias a struct. The generated code is
 // the same for both cases, i.e. it's the code for the underlying type.
-func (g *genDeepCopy) doStruct(t *types.Type, sw *generator.SnippetWriter) {
+func (g *genDeepCopy) doStruct(c *generator.Context, t *types.Type, sw *generator.SnippetWriter) {
 	ut := underlyingType(t)
 
-	if deepCopyMethodOrDie(t) != nil || deepCopyIntoMethodOrDie(t) != nil {
+	if checkedDeepCopyMethod(c, t) != nil || checkedDeepCopyIntoMethod(c, t) != nil {
 		sw.Do("*out = in.DeepCopy()\n", nil)
 		return
 	}
@@ -863,7 +986,7 @@ func (g *genDeepCopy) doStruct(t *types.Type, sw *generator.SnippetWriter) {
 			"kind": ft.Kind,
 			"name": m.Name,
 		}
-		dc, dci := deepCopyMethodOrDie(ft), deepCopyIntoMethodOrDie(ft)
+		dc, dci := checkedDeepCopyMethod(c, ft), checkedDeepCopyIntoMethod(c, ft)
 		switch {
 		case dc != nil || dci != nil:
 			// Note: a DeepCopyInto exists because it is added if DeepCopy is manually defined
@@ -886,8 +1009,16 @@ func (g *genDeepCopy) doStruct(t *types.Type, sw *generator.SnippetWriter) {
 			// Fix-up non-nil reference-semantic types.
 			sw.Do("if in.$.name$ != nil {\n", args)
 			sw.Do("in, out := &in.$.name$, &out.$.name$\n", args)
-			g.generateFor(ft, sw)
+			g.generateFor(c, ft, sw)
 			sw.Do("}\n", nil)
+		case uft.Kind == types.Array:
+			// The initial *out = *in was enough, unless the elements hold references.
+			if !uft.IsAssignable() {
+				sw.Do("{\n", nil)
+				sw.Do("in, out := &in.$.name$, &out.$.name$\n", args)
+				g.generateFor(c, ft, sw)
+				sw.Do("}\n", nil)
+			}
 		case uft.Kind == types.Struct:
 			if ft.IsAssignable() {
 				sw.Do("out.$.name$ = in.$.name$\n", args)
@@ -897,7 +1028,7 @@ func (g *genDeepCopy) doStruct(t *types.Type, sw *generator.SnippetWriter) {
 		case uft.Kind == types.Interface:
 			// Note: do not generate code won't compile as `DeepCopyInterface{}()` is not a valid function
 			if uft.Name.Name == "interface{}" {
-				log.Fatalf("DeepCopy of %q unsupported. Instead, use named interfaces with DeepCopy<named-interface> as one of the methods", uft.Name.Name)
+				c.Diagnostics.Errorf(m.Position, "DeepCopy of %q unsupported. Instead, use named interfaces with DeepCopy<named-interface> as one of the methods", uft.Name.Name)
 			}
 			sw.Do("if in.$.name$ != nil {\n", nil)
 			// Note: if t.Elem has been an alias "J" of an interface "I" in Go, we will see it
@@ -906,18 +1037,18 @@ func (g *genDeepCopy) doStruct(t *types.Type, sw *generator.SnippetWriter) {
 			sw.Do(fmt.Sprintf("out.$.name$ = in.$.name$.DeepCopy%s()\n", uft.Name.Name), args)
 			sw.Do("}\n", nil)
 		default:
-			log.Fatalf("Hit an unsupported type %v for %v, from %v", uft, ft, t)
+			c.Diagnostics.Errorf(m.Position, "hit an unsupported type %v for %v, from %v", uft, ft, t)
 		}
 	}
 }
 
 // doPointer generates code for a pointer or an alias to a pointer. The generated code is
 // the same for both cases, i.e. it's code for the underlying type.
-func (g *genDeepCopy) doPointer(t *types.Type, sw *generator.SnippetWriter) {
+func (g *genDeepCopy) doPointer(c *generator.Context, t *types.Type, sw *generator.SnippetWriter) {
 	ut := underlyingType(t)
 	uet := underlyingType(ut.Elem)
 
-	dc, dci := deepCopyMethodOrDie(ut.Elem), deepCopyIntoMethodOrDie(ut.Elem)
+	dc, dci := checkedDeepCopyMethod(c, ut.Elem), checkedDeepCopyIntoMethod(c, ut.Elem)
 	switch {
 	case dc != nil || dci != nil:
 		rightPointer := !isReference(ut.Elem)
@@ -937,12 +1068,18 @@ func (g *genDeepCopy) doPointer(t *types.Type, sw *generator.SnippetWriter) {
 		sw.Do("*out = new($.Elem|raw$)\n", ut)
 		sw.Do("if **in != nil {\n", nil)
 		sw.Do("in, out := *in, *out\n", nil)
-		g.generateFor(uet, sw)
+		g.generateFor(c, uet, sw)
 		sw.Do("}\n", nil)
 	case uet.Kind == types.Struct:
 		sw.Do("*out = new($.Elem|raw$)\n", ut)
 		sw.Do("(*in).DeepCopyInto(*out)\n", nil)
+	case uet.Kind == types.Array:
+		sw.Do("*out = new($.Elem|raw$)\n", ut)
+		sw.Do("{\n", nil)
+		sw.Do("in, out := *in, *out\n", nil)
+		g.generateFor(c, ut.Elem, sw)
+		sw.Do("}\n", nil)
 	default:
-		log.Fatalf("Hit an unsupported type %v for %v", uet, t)
+		c.Diagnostics.Errorf(t.Position, "hit an unsupported type %v for %v", uet, t)
 	}
 }
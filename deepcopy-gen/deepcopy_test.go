@@ -0,0 +1,81 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deepcopy_gen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vine-io/gogogen/gogenerator/args"
+	gentesting "github.com/vine-io/gogogen/gogenerator/testing"
+)
+
+func TestGolden(t *testing.T) {
+	g, _ := NewDefaults()
+	// Keep the golden files free of the year.
+	g.GoHeaderFilePath = ""
+
+	gentesting.Run(t, gentesting.Case{
+		Dir:     "testdata/basic",
+		Package: "example.com/deepcopy",
+		Runs: []args.Run{{
+			Args:          g,
+			NameSystems:   NameSystems(),
+			DefaultSystem: DefaultNameSystem(),
+			Packages:      Package,
+		}},
+	})
+}
+
+func TestReportsEveryProblem(t *testing.T) {
+	g, _ := NewDefaults()
+	g.GoHeaderFilePath = ""
+
+	src := map[string][]byte{
+		"example.com/bad/doc.go": []byte(`// +gogo:deepcopy=maybe
+package bad
+`),
+		"example.com/worse/types.go": []byte(`package worse
+
+// +gogo:deepcopy=true
+type A struct {
+	Any interface{}
+}
+
+// +gogo:deepcopy=true
+type B struct {
+	Any map[string]interface{}
+}
+`),
+	}
+	_, err := args.Generate(src, args.Run{
+		Args:          g,
+		NameSystems:   NameSystems(),
+		DefaultSystem: DefaultNameSystem(),
+		Packages:      Package,
+	})
+	if err == nil {
+		t.Fatal("generating: no error")
+	}
+	for _, want := range []string{
+		`package example.com/bad: unsupported gogo:deepcopy value: "maybe"`,
+		`worse/types.go:5:2: DeepCopy of "interface{}" unsupported`,
+		`DeepCopy of "interface{}" is unsupported`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not report %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package api
+
+import "example.com/deepcopy/other"
+
+type Object interface {
+	GetName() string
+}
+
+// +gogo:deepcopy:interfaces=example.com/deepcopy/api.Object
+type Resource struct {
+	Name     string
+	Created  int64
+	Labels   map[string]string
+	Tags     []string
+	Owner    *other.Owner
+	Children []Child
+	ByKey    map[string]Child
+	Data     []byte
+}
+
+func (r *Resource) GetName() string { return r.Name }
+
+type Child struct {
+	ID      int64
+	Enabled *bool
+	Other   other.Owner
+}
+
+// +gogo:deepcopy=false
+type Skipped struct {
+	Items []string
+}
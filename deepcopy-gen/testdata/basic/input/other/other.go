@@ -0,0 +1,6 @@
+package other
+
+type Owner struct {
+	Name  string
+	Roles []string
+}
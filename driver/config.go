@@ -0,0 +1,158 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package driver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/vine-io/gogogen/gogenerator/generator"
+)
+
+// DefaultConfigFile is the name of the config file gogogen reads by default.
+const DefaultConfigFile = "gogogen.json"
+
+// Config declares the generators run by gogogen. It is read from a JSON file
+// such as:
+//
+//	{
+//	  "packages": ["example.com/api/..."],
+//	  "goHeaderFile": "hack/boilerplate.go.txt",
+//	  "generators": [
+//	    {"name": "goproto", "options": {"onlyIDL": true}},
+//	    {"name": "deepcopy"},
+//	    {"name": "getters", "generator": "template", "options": {"config": "templates.json"}}
+//	  ]
+//	}
+type Config struct {
+	// The import paths of the input packages of the generators, as given to
+	// --input-dirs. A trailing "/..." includes the packages below.
+	Packages []string `json:"packages,omitempty"`
+	// The boilerplate header of the generated files, relative to the config
+	// file.
+	GoHeaderFile string             `json:"goHeaderFile,omitempty"`
+	Generators   []*GeneratorConfig `json:"generators"`
+
+	// The directory of the config file.
+	dir string
+}
+
+// GeneratorConfig is one generator of a Config.
+type GeneratorConfig struct {
+	// The name of the generator, unique in the config. It defaults to
+	// Generator.
+	Name string `json:"name,omitempty"`
+	// The generator to run: deepcopy, set, template, plugin, gogorm or
+	// goproto. It defaults to Name.
+	Generator string `json:"generator,omitempty"`
+	// The input packages of the generator, defaults to those of the config.
+	Packages []string `json:"packages,omitempty"`
+	// The names of the generators which must run before this one.
+	After []string `json:"after,omitempty"`
+	// The options of the generator, see the options types in generators.go.
+	Options json.RawMessage `json:"options,omitempty"`
+}
+
+// LoadConfig reads the config file at path and checks the generators it
+// declares. The problems of all the generators are returned together.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	cfg := &Config{dir: filepath.Dir(path)}
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(cfg.Generators) == 0 {
+		return nil, fmt.Errorf("%s: no generators declared", path)
+	}
+
+	var errs generator.ErrorList
+	names := map[string]bool{}
+	for i, gc := range cfg.Generators {
+		if gc.Name == "" {
+			gc.Name = gc.Generator
+		}
+		if gc.Generator == "" {
+			gc.Generator = gc.Name
+		}
+		switch {
+		case gc.Name == "":
+			errs = append(errs, fmt.Errorf("%s: generator %d has no name", path, i))
+		case names[gc.Name]:
+			errs = append(errs, fmt.Errorf("%s: generator %q is declared twice", path, gc.Name))
+		case generators[gc.Generator] == nil:
+			errs = append(errs, fmt.Errorf("%s: generator %q: unknown generator %q", path, gc.Name, gc.Generator))
+		}
+		names[gc.Name] = true
+	}
+	for _, gc := range cfg.Generators {
+		for _, after := range gc.After {
+			if !names[after] {
+				errs = append(errs, fmt.Errorf("%s: generator %q comes after unknown generator %q", path, gc.Name, after))
+			}
+		}
+	}
+	if len(errs) != 0 {
+		return nil, errs
+	}
+	return cfg, nil
+}
+
+// Path returns the path of a file named relative to the config file.
+func (cfg *Config) Path(name string) string {
+	if name == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(cfg.dir, name)
+}
+
+// declares returns whether the config declares the generator name.
+func (cfg *Config) declares(name string) bool {
+	for _, gc := range cfg.Generators {
+		if gc.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// packages returns the input packages of gc.
+func (cfg *Config) packages(gc *GeneratorConfig) []string {
+	if len(gc.Packages) != 0 {
+		return gc.Packages
+	}
+	return cfg.Packages
+}
+
+// decodeOptions decodes the options of gc into v, which holds their
+// defaults.
+func decodeOptions(gc *GeneratorConfig, v interface{}) error {
+	if len(gc.Options) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(gc.Options))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("options: %v", err)
+	}
+	return nil
+}
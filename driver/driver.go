@@ -0,0 +1,479 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package driver runs several generators on the same packages, as declared
+// by a config file.
+//
+// The packages read by deepcopy-gen, set-gen, template-gen and plugins are
+// parsed once into a universe shared by these generators. gogorm-gen and
+// goproto-gen clean and rewrite the sources of their packages, so they parse
+// them themselves and run before the others unless told otherwise.
+//
+// A generator which fails doesn't stop the others, except for those which
+// come after it. The errors of all the generators are returned together.
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/vine-io/gogogen/gogenerator/args"
+	"github.com/vine-io/gogogen/gogenerator/generator"
+	"github.com/vine-io/gogogen/gogenerator/types"
+	"github.com/vine-io/gogogen/util/log"
+)
+
+// Driver runs the generators of a config.
+type Driver struct {
+	// The arguments common to all the generators: how the packages are
+	// resolved and cached, the boilerplate header, and where and whether
+	// the files are written. The others are ignored.
+	Args *args.GeneratorArgs
+
+	// The path of the config file.
+	ConfigPath string
+	// The config, read from ConfigPath by Load.
+	Config *Config
+
+	// If not empty, only the generators with these names run.
+	Only []string
+
+	// If set, the files are written to this output, which is left open,
+	// instead of the one picked by Args.
+	Output generator.OutputFS
+}
+
+// New returns a driver with the default arguments.
+func New() *Driver {
+	return &Driver{
+		Args:       args.Default().WithoutDefaultFlagParsing(),
+		ConfigPath: DefaultConfigFile,
+	}
+}
+
+// AddFlags adds the flags of the driver to the flag set.
+func (d *Driver) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&d.ConfigPath, "config", "c", d.ConfigPath,
+		"The JSON file declaring the packages, the generators to run and their options.")
+	fs.StringSliceVar(&d.Only, "only", d.Only,
+		"Comma-separated list of the names of the generators to run, defaults to all of them.")
+	fs.BoolVar(&d.Args.GoModules, "go-modules", d.Args.GoModules,
+		"If true, resolve packages in module mode; defaults to true inside a Go module or workspace.")
+	fs.StringVarP(&d.Args.OutputBase, "output-base", "o", d.Args.OutputBase,
+		"Output base; if empty, output is written next to the source packages. Defaults to empty in module mode, $GOPATH/src/ or ./ otherwise.")
+	fs.StringVarP(&d.Args.GoHeaderFilePath, "go-header-file", "H", d.Args.GoHeaderFilePath,
+		"File containing boilerplate header text, overrides the one of the config. The string YEAR will be replaced with the current 4-digit year.")
+	fs.BoolVar(&d.Args.VerifyOnly, "verify-only", d.Args.VerifyOnly,
+		"If true, only verify existing output, do not write anything.")
+	fs.BoolVar(&d.Args.DryRun, "dry-run", d.Args.DryRun,
+		"If true, list the files which would be generated or changed instead of writing them.")
+	fs.StringVar(&d.Args.OutputArchive, "output-archive", d.Args.OutputArchive,
+		"If set, write the files which would be generated or changed to this .tar, .tar.gz, .tgz or .zip archive instead of the source tree; - writes a tar to stdout.")
+	fs.IntVar(&d.Args.Parallelism, "parallelism", d.Args.Parallelism,
+		"The maximum number of packages parsed, type-checked or generated at the same time; 0 uses the number of CPUs, 1 disables concurrency.")
+	fs.BoolVar(&d.Args.Watch, "watch", d.Args.Watch,
+		"If true, keep running and run the generators again on the input packages whose files change, instead of exiting.")
+	fs.DurationVar(&d.Args.WatchInterval, "watch-interval", d.Args.WatchInterval,
+		"How often the files of the input packages are polled for changes in watch mode.")
+	fs.StringVar(&d.Args.CacheDir, "cache-dir", d.Args.CacheDir,
+		"If set, cache type-checked packages in this directory to speed up later runs.")
+	args.MarkPathFlags(fs, "config", "output-base", "go-header-file", "output-archive", "cache-dir")
+}
+
+// Load reads the config file.
+func (d *Driver) Load() error {
+	cfg, err := LoadConfig(d.ConfigPath)
+	if err != nil {
+		return err
+	}
+	d.Config = cfg
+	return nil
+}
+
+// Run runs the generators of the config in dependency order. It returns a
+// generator.ErrorList holding the errors of all the generators, or a
+// *generator.VerifyError if they only found out of date files in verify
+// mode.
+func (d *Driver) Run() error {
+	steps, err := d.orderedSteps()
+	if err != nil {
+		return err
+	}
+	_, err = d.run(steps, nil, nil)
+	return err
+}
+
+// Watch runs the generators like Run, then runs them again each time the
+// files of their input packages change, only on the packages which changed,
+// see args.Watcher. It logs their errors and keeps running; it only returns
+// if the packages can't be found or parsed at first.
+func (d *Driver) Watch() error {
+	steps, err := d.orderedSteps()
+	if err != nil {
+		return err
+	}
+	shared, err := d.run(steps, nil, nil)
+	if err != nil {
+		log.Errorf("Error: %v", err)
+	}
+
+	dirs := map[string]string{}
+	parsed := false
+	for _, s := range steps {
+		if s.execute == nil {
+			parsed = true
+			continue
+		}
+		stepDirs, err := d.Args.PackageDirs(s.generated)
+		if err != nil {
+			return err
+		}
+		for pkgPath, dir := range stepDirs {
+			dirs[pkgPath] = dir
+		}
+	}
+	if parsed && shared == nil {
+		if shared, err = d.parse(steps); err != nil {
+			return err
+		}
+	}
+	if shared != nil {
+		for _, pkgPath := range shared.Inputs {
+			dirs[pkgPath] = shared.Universe[pkgPath].SourcePath
+		}
+	}
+
+	d.Args.NewWatcher(dirs).Run(d.Args.WatchInterval, func(changed []string) error {
+		var err error
+		shared, err = d.run(steps, shared, changed)
+		return err
+	})
+	return nil
+}
+
+// orderedSteps returns the steps of the generators to run, in dependency
+// order.
+func (d *Driver) orderedSteps() ([]*step, error) {
+	steps, err := d.steps()
+	if err != nil {
+		return nil, err
+	}
+	return orderSteps(steps)
+}
+
+// run runs steps, which are ordered, on the universe of shared, which is
+// parsed if it is nil. If changed is not nil, the steps only generate these
+// packages, and shared is reloaded for them first. It returns the shared
+// context, unless it is out of date.
+func (d *Driver) run(steps []*step, shared *generator.Context, changed []string) (*generator.Context, error) {
+	var err error
+	out := d.Output
+	if out == nil && !d.Args.VerifyOnly {
+		if out, err = d.Args.NewOutputFS(); err != nil {
+			return shared, err
+		}
+	}
+
+	var errs generator.ErrorList
+	var results []generator.VerifyResult
+	failed := map[string]bool{}
+	var parseErr error
+	// The input packages of shared to generate, all of them if nil.
+	var inputs []string
+	reloaded := false
+	for _, s := range steps {
+		if dep := s.failedDep(failed); dep != "" {
+			failed[s.name] = true
+			errs = append(errs, fmt.Errorf("%s: not run because %s failed", s.name, dep))
+			continue
+		}
+
+		only := intersect(s.generated, changed)
+		if s.execute != nil && changed != nil && len(only) == 0 {
+			// None of the changed packages is generated by it.
+			continue
+		}
+
+		log.Infof("Running generator %q", s.name)
+		var err error
+		switch {
+		case s.execute != nil:
+			stepOut := out
+			if stepOut == nil && !d.Args.VerifyOnly {
+				stepOut = generator.DiskFS{}
+			}
+			err = s.execute(stepOut, only)
+			if _, ok := stepOut.(generator.DiskFS); ok && changed == nil {
+				// The sources it rewrote are parsed again by the next
+				// generator which reads them. When watching, they are the
+				// changed packages, which are reloaded anyway.
+				shared = nil
+			}
+		case parseErr != nil:
+			err = fmt.Errorf("not run because the packages failed to parse")
+		default:
+			switch {
+			case shared == nil:
+				// All the packages are generated.
+				shared, parseErr = d.parse(steps)
+				reloaded = true
+			case changed != nil && !reloaded:
+				reloaded = true
+				if inputs, parseErr = shared.Reload(changed); inputs == nil {
+					inputs = []string{}
+				}
+			}
+			if parseErr != nil {
+				shared, err = nil, parseErr
+				break
+			}
+			if inputs != nil && len(inputs) == 0 {
+				// None of the changed packages is read by the generator.
+				continue
+			}
+			err = d.execute(shared, s, out, inputs)
+		}
+
+		var verr *generator.VerifyError
+		switch {
+		case errors.As(err, &verr):
+			results = append(results, verr.Results...)
+		case err != nil:
+			failed[s.name] = true
+			errs = append(errs, fmt.Errorf("%s: %v", s.name, err))
+		}
+	}
+
+	if d.Output == nil && out != nil {
+		if err := args.CloseOutputFS(out); err != nil {
+			errs = append(errs, fmt.Errorf("unable to write the output: %v", err))
+		}
+	}
+	if len(results) != 0 {
+		verr := &generator.VerifyError{Results: results}
+		if len(errs) == 0 {
+			return shared, verr
+		}
+		errs = append(errs, verr)
+	}
+	if len(errs) != 0 {
+		return shared, errs
+	}
+	return shared, nil
+}
+
+// intersect returns the elements of a which are in b, or nil if b is nil.
+func intersect(a, b []string) []string {
+	if b == nil {
+		return nil
+	}
+	in := map[string]bool{}
+	for _, e := range b {
+		in[e] = true
+	}
+	result := []string{}
+	for _, e := range a {
+		if in[e] {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// steps returns the steps of the generators to run, in the order of the
+// config.
+func (d *Driver) steps() ([]*step, error) {
+	if d.Config == nil {
+		return nil, fmt.Errorf("no config loaded")
+	}
+	var errs generator.ErrorList
+	only := map[string]bool{}
+	for _, name := range d.Only {
+		if !d.Config.declares(name) {
+			errs = append(errs, fmt.Errorf("unknown generator %q", name))
+		}
+		only[name] = true
+	}
+
+	var steps []*step
+	for _, gc := range d.Config.Generators {
+		if len(only) != 0 && !only[gc.Name] {
+			continue
+		}
+		s, err := generators[gc.Generator](d.Config, gc, d.Args)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", gc.Name, err))
+			continue
+		}
+		s.name, s.after = gc.Name, gc.After
+		steps = append(steps, s)
+	}
+	if len(errs) != 0 {
+		return nil, errs
+	}
+	return steps, nil
+}
+
+// orderSteps sorts steps so that they come after the steps they name. The
+// steps which rewrite their sources come first, the others keep their order.
+func orderSteps(steps []*step) ([]*step, error) {
+	selected := map[string]bool{}
+	for _, s := range steps {
+		selected[s.name] = true
+	}
+	done := map[string]bool{}
+	ready := func(s *step) bool {
+		for _, after := range s.after {
+			if selected[after] && !done[after] {
+				return false
+			}
+		}
+		return true
+	}
+
+	ordered := make([]*step, 0, len(steps))
+	for len(ordered) < len(steps) {
+		var next *step
+		for _, s := range steps {
+			if done[s.name] || !ready(s) {
+				continue
+			}
+			if next == nil || (s.execute != nil && next.execute == nil) {
+				next = s
+			}
+		}
+		if next == nil {
+			var names []string
+			for _, s := range steps {
+				if !done[s.name] {
+					names = append(names, s.name)
+				}
+			}
+			return nil, fmt.Errorf("generators %s come after each other", strings.Join(names, ", "))
+		}
+		ordered = append(ordered, next)
+		done[next.name] = true
+	}
+	return ordered, nil
+}
+
+// failedDep returns the name of a step which s comes after and which failed,
+// if any.
+func (s *step) failedDep(failed map[string]bool) string {
+	for _, after := range s.after {
+		if failed[after] {
+			return after
+		}
+	}
+	return ""
+}
+
+// parse parses the input packages of all the steps which read the shared
+// universe, and returns the context holding it.
+func (d *Driver) parse(steps []*step) (*generator.Context, error) {
+	a := *d.Args
+	a.InputDirs = nil
+	a.Markers = types.NewMarkerRegistry()
+	seen := map[string]bool{}
+	for _, s := range steps {
+		if s.args == nil {
+			continue
+		}
+		for _, dir := range s.args.InputDirs {
+			if !seen[dir] {
+				seen[dir] = true
+				a.InputDirs = append(a.InputDirs, dir)
+			}
+		}
+		if s.args.Markers != nil {
+			a.Markers.Register(s.args.Markers.Markers()...)
+		}
+	}
+
+	b, err := a.NewBuilder()
+	if err != nil {
+		return nil, err
+	}
+	c, err := generator.NewContext(b, nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed making a context: %v", err)
+	}
+	return c, nil
+}
+
+// execute runs the generator of s on the universe of shared, writing to out
+// unless it is nil. If inputs is not nil, only these input packages are
+// generated.
+func (d *Driver) execute(shared *generator.Context, s *step, out generator.OutputFS, inputs []string) error {
+	c := shared.WithNameSystems(s.nameSystems, s.defaultSystem)
+	c.Inputs = nil
+	if inputs == nil {
+		inputs = shared.Inputs
+	}
+	// The packages of the generator are given as to --input-dirs, the
+	// inputs of shared are import paths.
+	dirs, err := s.args.ResolveInputDirs(s.args.InputDirs)
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		matched := false
+		for _, pkg := range shared.Inputs {
+			if matched = includes([]string{dir}, pkg); matched {
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%q matches no package", dir)
+		}
+	}
+	for _, pkg := range intersect(shared.Inputs, inputs) {
+		if includes(dirs, pkg) {
+			c.Inputs = append(c.Inputs, pkg)
+		}
+	}
+	c.Verify = d.Args.VerifyOnly
+	c.Parallelism = s.args.Parallelism
+	if c.Parallelism == 0 {
+		c.Parallelism = runtime.NumCPU()
+	}
+	c.Owner = s.args.Owner
+	if !c.Verify {
+		c.Output = out
+	}
+	packages, err := s.packages(c, s.args)
+	if err != nil {
+		return err
+	}
+	return c.ExecutePackages(s.args.OutputBase, packages)
+}
+
+// includes returns whether the package pkgPath is one of dirs, or below one
+// ending with "/...".
+func includes(dirs []string, pkgPath string) bool {
+	for _, dir := range dirs {
+		if dir == pkgPath {
+			return true
+		}
+		if prefix := strings.TrimSuffix(dir, "/..."); prefix != dir {
+			if pkgPath == prefix || strings.HasPrefix(pkgPath, prefix+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
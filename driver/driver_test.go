@@ -0,0 +1,155 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package driver
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vine-io/gogogen/gogenerator/generator"
+)
+
+func TestLoadConfigErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gogogen.json")
+	config := `{
+  "generators": [
+    {"name": "deepcopy"},
+    {"name": "deepcopy"},
+    {"name": "foo", "generator": "bar"},
+    {"name": "set", "after": ["baz"]}
+  ]
+}`
+	if err := ioutil.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadConfig(path)
+	var errs generator.ErrorList
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("expected the 3 problems of the config, got %v", err)
+	}
+	for i, want := range []string{`"deepcopy" is declared twice`, `unknown generator "bar"`, `unknown generator "baz"`} {
+		if !strings.Contains(errs[i].Error(), want) {
+			t.Errorf("error %d: expected %q, got %v", i, want, errs[i])
+		}
+	}
+}
+
+func TestOrderSteps(t *testing.T) {
+	rewrite := func(generator.OutputFS, []string) error { return nil }
+	steps := []*step{
+		{name: "template", after: []string{"deepcopy"}},
+		{name: "deepcopy"},
+		{name: "goproto", execute: rewrite},
+		{name: "gogorm", execute: rewrite, after: []string{"plugin"}},
+		{name: "plugin"},
+	}
+	ordered, err := orderSteps(steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, s := range ordered {
+		names = append(names, s.name)
+	}
+	want := []string{"goproto", "deepcopy", "template", "plugin", "gogorm"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("expected the order %v, got %v", want, names)
+	}
+
+	steps[1].after = []string{"template"}
+	if _, err := orderSteps(steps); err == nil || !strings.Contains(err.Error(), "template, deepcopy") {
+		t.Errorf("expected a cycle between template and deepcopy, got %v", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	d := New()
+	d.ConfigPath = "testdata/gogogen.json"
+	if err := d.Load(); err != nil {
+		t.Fatal(err)
+	}
+	d.Args.GoHeaderFilePath = d.Config.Path(d.Config.GoHeaderFile)
+	output := generator.NewMemoryFS()
+	d.Output = output
+
+	err := d.Run()
+	var errs generator.ErrorList
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("expected the errors of the 2 plugins, got %v", err)
+	}
+	if !strings.Contains(errs[0].Error(), "missing: plugin gogogen-missing-plugin") {
+		t.Errorf("expected the plugin not to be found, got %v", errs[0])
+	}
+	if errs[1].Error() != "after-missing: not run because missing failed" {
+		t.Errorf("expected the dependent plugin not to run, got %v", errs[1])
+	}
+
+	// The files are generated as by the commands of their generators.
+	want := map[string]string{
+		"deepcopy_generated.go": "deepcopy-gen",
+		"names_generated.go":    "template-gen",
+	}
+	files := output.Files()
+	if len(files) != len(want) {
+		t.Errorf("expected %d files, got %d", len(want), len(files))
+	}
+	for path, data := range files {
+		cmd, ok := want[filepath.Base(path)]
+		if !ok {
+			t.Errorf("unexpected file %s", path)
+			continue
+		}
+		for _, s := range []string{"Code generated by " + cmd + ".", "func (in *Resource)"} {
+			if !strings.Contains(string(data), s) {
+				t.Errorf("%s: expected %q in:\n%s", path, s, data)
+			}
+		}
+	}
+}
+
+func TestRunRelativePackages(t *testing.T) {
+	d := New()
+	d.Config = &Config{
+		Packages: []string{"./testdata/api"},
+		Generators: []*GeneratorConfig{
+			{Name: "deepcopy", Generator: "deepcopy"},
+			{Name: "other", Generator: "deepcopy", Packages: []string{"./testdata/api", "./testdata"}},
+		},
+	}
+	d.Args.GoHeaderFilePath = "../hack/boilerplate.go.txt"
+	output := generator.NewMemoryFS()
+	d.Output = output
+
+	// The relative packages are matched with the parsed ones by import path,
+	// and an entry without packages is an error.
+	err := d.Run()
+	if err == nil || !strings.Contains(err.Error(), `other: "github.com/vine-io/gogogen/driver/testdata" matches no package`) {
+		t.Errorf("expected the empty testdata directory to be reported, got %v", err)
+	}
+	files := output.Files()
+	if len(files) != 1 {
+		t.Fatalf("expected deepcopy to generate the api package, got %d files", len(files))
+	}
+	for path := range files {
+		if want := filepath.Join("testdata", "api", "deepcopy_generated.go"); !strings.HasSuffix(path, want) {
+			t.Errorf("expected %s, got %s", want, path)
+		}
+	}
+}
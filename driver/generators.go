@@ -0,0 +1,310 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package driver
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	deepcopy_gen "github.com/vine-io/gogogen/deepcopy-gen"
+	"github.com/vine-io/gogogen/gogenerator/args"
+	"github.com/vine-io/gogogen/gogenerator/generator"
+	"github.com/vine-io/gogogen/gogenerator/namer"
+	"github.com/vine-io/gogogen/gogenerator/plugin"
+	"github.com/vine-io/gogogen/gogenerator/types"
+	gogorm_gen "github.com/vine-io/gogogen/gogorm-gen"
+	goproto_gen "github.com/vine-io/gogogen/goproto-gen"
+	set_gen "github.com/vine-io/gogogen/set-gen"
+	template_gen "github.com/vine-io/gogogen/template-gen"
+)
+
+// step is a configured generator.
+type step struct {
+	name  string
+	after []string
+
+	// Set for the generators which read the universe shared by the steps:
+	// their arguments, name systems and packages.
+	args          *args.GeneratorArgs
+	nameSystems   namer.NameSystems
+	defaultSystem string
+	packages      func(*generator.Context, *args.GeneratorArgs) (generator.Packages, error)
+	// Set for the generators which change the sources of their packages,
+	// e.g. their struct tags. They parse their packages themselves, and
+	// write to out unless it is nil in verify mode. If only is not empty,
+	// only the packages of generated among them are generated.
+	execute   func(out generator.OutputFS, only []string) error
+	generated []string
+}
+
+// generators make the steps of the generators gogogen knows, from their
+// config and the arguments common to all the generators.
+var generators = map[string]func(cfg *Config, gc *GeneratorConfig, common *args.GeneratorArgs) (*step, error){
+	"deepcopy": deepcopyStep,
+	"set":      setStep,
+	"template": templateStep,
+	"plugin":   pluginStep,
+	"gogorm":   gogormStep,
+	"goproto":  goprotoStep,
+}
+
+// deepcopyOptions are the options of deepcopy-gen.
+type deepcopyOptions struct {
+	// The import paths which bound the types deep-copies are generated for,
+	// defaults to the input packages.
+	BoundingDirs []string `json:"boundingDirs,omitempty"`
+	// The base name of the generated files.
+	OutputFileBase string `json:"outputFileBase,omitempty"`
+}
+
+func deepcopyStep(cfg *Config, gc *GeneratorConfig, common *args.GeneratorArgs) (*step, error) {
+	a, customArgs := deepcopy_gen.NewDefaults()
+	opts := &deepcopyOptions{OutputFileBase: a.OutputFileBaseName}
+	if err := decodeOptions(gc, opts); err != nil {
+		return nil, err
+	}
+	setCommon(a, common, cfg.packages(gc), "deepcopy-gen")
+	a.OutputFileBaseName = opts.OutputFileBase
+	if len(opts.BoundingDirs) != 0 {
+		customArgs.BoundingDirs = opts.BoundingDirs
+	}
+	if err := deepcopy_gen.Validate(a); err != nil {
+		return nil, err
+	}
+	return &step{
+		args:          a,
+		nameSystems:   deepcopy_gen.NameSystems(),
+		defaultSystem: deepcopy_gen.DefaultNameSystem(),
+		packages:      diagnosed(deepcopy_gen.Package),
+	}, nil
+}
+
+// setOptions are the options of set-gen.
+type setOptions struct {
+	// The import path of the package the sets are generated in.
+	OutputPackage string `json:"outputPackage"`
+}
+
+func setStep(cfg *Config, gc *GeneratorConfig, common *args.GeneratorArgs) (*step, error) {
+	opts := &setOptions{}
+	if err := decodeOptions(gc, opts); err != nil {
+		return nil, err
+	}
+	if opts.OutputPackage == "" {
+		return nil, fmt.Errorf("no output package given")
+	}
+	a := args.Default().WithoutDefaultFlagParsing()
+	a.Markers = types.NewMarkerRegistry(set_gen.Markers...)
+	a.Owner = "set-gen"
+	setCommon(a, common, cfg.packages(gc), "set-gen")
+	a.OutputPackagePath = opts.OutputPackage
+	return &step{
+		args:          a,
+		nameSystems:   set_gen.NameSystems(),
+		defaultSystem: set_gen.DefaultNameSystem(),
+		packages:      diagnosed(set_gen.Packages),
+	}, nil
+}
+
+// templateOptions are the options of template-gen.
+type templateOptions struct {
+	// The template-gen config file, relative to the gogogen one.
+	Config string `json:"config"`
+}
+
+func templateStep(cfg *Config, gc *GeneratorConfig, common *args.GeneratorArgs) (*step, error) {
+	opts := &templateOptions{}
+	if err := decodeOptions(gc, opts); err != nil {
+		return nil, err
+	}
+	a, customArgs := template_gen.NewDefaults()
+	setCommon(a, common, cfg.packages(gc), "template-gen")
+	customArgs.ConfigPath = cfg.Path(opts.Config)
+	if err := customArgs.Load(a); err != nil {
+		return nil, err
+	}
+	if err := template_gen.Validate(a); err != nil {
+		return nil, err
+	}
+	return &step{
+		args:          a,
+		nameSystems:   template_gen.NameSystems(),
+		defaultSystem: template_gen.DefaultNameSystem(),
+		packages:      diagnosed(template_gen.Packages),
+	}, nil
+}
+
+// pluginOptions are the options of plugin-gen.
+type pluginOptions struct {
+	// The plugin executable, either a path or a name to look up in $PATH.
+	Plugin string `json:"plugin"`
+	// A parameter passed as is to the plugin.
+	Parameter string `json:"parameter,omitempty"`
+}
+
+func pluginStep(cfg *Config, gc *GeneratorConfig, common *args.GeneratorArgs) (*step, error) {
+	opts := &pluginOptions{}
+	if err := decodeOptions(gc, opts); err != nil {
+		return nil, err
+	}
+	if opts.Plugin == "" {
+		return nil, fmt.Errorf("no plugin given")
+	}
+	d := &plugin.Driver{Path: opts.Plugin, Parameter: opts.Parameter}
+	a := args.Default().WithoutDefaultFlagParsing()
+	// The generated files are owned by the plugin rather than by us.
+	a.Owner = strings.TrimSuffix(filepath.Base(d.Path), ".exe")
+	setCommon(a, common, cfg.packages(gc), "plugin-gen")
+	return &step{
+		args:          a,
+		nameSystems:   namer.NameSystems{"public": namer.NewPublicNamer(0)},
+		defaultSystem: "public",
+		packages:      d.MakePackages,
+	}, nil
+}
+
+// diagnosed adapts the packages function of a generator which reports its
+// problems to the Diagnostics of the context instead of returning them;
+// ExecutePackages fails with them.
+func diagnosed(f func(*generator.Context, *args.GeneratorArgs) generator.Packages) func(*generator.Context, *args.GeneratorArgs) (generator.Packages, error) {
+	return func(c *generator.Context, a *args.GeneratorArgs) (generator.Packages, error) {
+		return f(c, a), nil
+	}
+}
+
+// setCommon sets the arguments of a generator which are common to all the
+// generators, and its input packages. The generated files get the "Code
+// generated by" comment of the command of the generator, cmd.
+func setCommon(a, common *args.GeneratorArgs, inputs []string, cmd string) {
+	a.InputDirs = inputs
+	a.GoModules = common.GoModules
+	a.OutputBase = common.OutputBase
+	a.GoHeaderFilePath = common.GoHeaderFilePath
+	a.VerifyOnly = common.VerifyOnly
+	a.DryRun = common.DryRun
+	a.OutputArchive = common.OutputArchive
+	a.IncludeTestFile = common.IncludeTestFile
+	a.Parallelism = common.Parallelism
+	a.CacheDir = common.CacheDir
+	if a.GeneratedByCommentTemplate != "" {
+		a.GeneratedByCommentTemplate = strings.Replace(common.GeneratedByCommentTemplate, "GENERATOR_NAME", cmd, -1)
+	}
+}
+
+// idlOptions are the options shared by gogorm-gen and goproto-gen.
+type idlOptions struct {
+	// Packages needed by the input packages, whose types are generated too.
+	// The entries of them and of the input packages are given like to
+	// --packages: prefixed with '-' to not generate the package, with '+' to
+	// only generate the types with explicit IDL instructions.
+	MetadataPackages []string `json:"metadataPackages,omitempty"`
+	// An optional build tag condition added to the generated go code.
+	Conditional string `json:"conditional,omitempty"`
+	// If true, only the IDL is generated.
+	OnlyIDL bool `json:"onlyIDL,omitempty"`
+	// The embedded go types to omit, of the form [GOPACKAGE.]TYPENAME.
+	DropEmbeddedFields []string `json:"dropEmbeddedFields"`
+}
+
+// goprotoOptions are the options of goproto-gen.
+type goprotoOptions struct {
+	idlOptions
+	// The name of the generated files.
+	GeneratedName string `json:"generatedName,omitempty"`
+	// The search path of protoc for the core protobuf .protos, relative to
+	// the config file.
+	ProtoImport []string `json:"protoImport,omitempty"`
+	// If true, the generated IDL keeps the gogoprotobuf extensions.
+	KeepGogoproto bool `json:"keepGogoproto,omitempty"`
+}
+
+func gogormStep(cfg *Config, gc *GeneratorConfig, common *args.GeneratorArgs) (*step, error) {
+	g := gogorm_gen.New()
+	opts := &idlOptions{DropEmbeddedFields: splitList(g.DropEmbeddedFields)}
+	if err := decodeOptions(gc, opts); err != nil {
+		return nil, err
+	}
+	pkgs, err := idlPackages(cfg.packages(gc))
+	if err != nil {
+		return nil, err
+	}
+	setCommon(&g.Common, common, nil, "")
+	g.OutputBase = common.OutputBase
+	g.Packages = pkgs
+	g.MetadataPackages = strings.Join(opts.MetadataPackages, ",")
+	g.Conditional = opts.Conditional
+	g.OnlyIDL = opts.OnlyIDL
+	g.DropEmbeddedFields = strings.Join(opts.DropEmbeddedFields, ",")
+	return &step{
+		execute: func(out generator.OutputFS, only []string) error {
+			g.Output, g.Only = out, only
+			return g.Execute()
+		},
+		generated: g.GeneratedPackages(),
+	}, nil
+}
+
+func goprotoStep(cfg *Config, gc *GeneratorConfig, common *args.GeneratorArgs) (*step, error) {
+	g := goproto_gen.New()
+	opts := &goprotoOptions{
+		idlOptions:    idlOptions{DropEmbeddedFields: splitList(g.DropEmbeddedFields)},
+		GeneratedName: g.GeneratedName,
+	}
+	if err := decodeOptions(gc, opts); err != nil {
+		return nil, err
+	}
+	pkgs, err := idlPackages(cfg.packages(gc))
+	if err != nil {
+		return nil, err
+	}
+	setCommon(&g.Common, common, nil, "")
+	g.OutputBase = common.OutputBase
+	g.Packages = pkgs
+	g.MetadataPackages = strings.Join(opts.MetadataPackages, ",")
+	g.Conditional = opts.Conditional
+	g.OnlyIDL = opts.OnlyIDL
+	g.DropEmbeddedFields = strings.Join(opts.DropEmbeddedFields, ",")
+	g.GeneratedName = opts.GeneratedName
+	g.KeepGogoproto = opts.KeepGogoproto
+	for _, dir := range opts.ProtoImport {
+		g.ProtoImport = append(g.ProtoImport, cfg.Path(dir))
+	}
+	return &step{
+		execute: func(out generator.OutputFS, only []string) error {
+			g.Output, g.Only = out, only
+			return g.Execute()
+		},
+		generated: g.GeneratedPackages(),
+	}, nil
+}
+
+// idlPackages returns the --packages of gogorm-gen and goproto-gen, which
+// don't expand "/...".
+func idlPackages(pkgs []string) (string, error) {
+	for _, p := range pkgs {
+		if strings.HasSuffix(p, "/...") {
+			return "", fmt.Errorf("package %q: packages below a directory can't be generated, list them instead", p)
+		}
+	}
+	return strings.Join(pkgs, ","), nil
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
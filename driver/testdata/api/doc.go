@@ -0,0 +1,3 @@
+// +gogo:deepcopy=package
+
+package api
@@ -0,0 +1,7 @@
+package api
+
+// +example:name=true
+type Resource struct {
+	Name   string
+	Labels map[string]string
+}
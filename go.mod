@@ -10,6 +10,7 @@ require (
 	go.uber.org/zap v1.16.0
 	golang.org/x/tools v0.3.0
 	google.golang.org/grpc v1.53.0
+	gopkg.in/yaml.v2 v2.4.0
 	gorm.io/gorm v1.24.5
 )
 
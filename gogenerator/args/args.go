@@ -21,6 +21,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 	"time"
@@ -30,15 +31,24 @@ import (
 	"github.com/vine-io/gogogen/gogenerator/namer"
 	"github.com/vine-io/gogogen/gogenerator/parser"
 	"github.com/vine-io/gogogen/gogenerator/types"
+	"github.com/vine-io/gogogen/util/log"
 )
 
 // Default returns a defaulted GeneratorArgs. You may change the defaults
 // before calling AddFlags.
 func Default() *GeneratorArgs {
+	goModules := DefaultGoModules()
+	outputBase := DefaultSourceTree()
+	if goModules {
+		// Write the generated files next to their packages.
+		outputBase = ""
+	}
 	return &GeneratorArgs{
-		OutputBase:                 DefaultSourceTree(),
-		GoHeaderFilePath:           filepath.Join(DefaultSourceTree(), "github.com/vine-io/gogogen/gogenerator/boilerplate/boilerplate.go.txt"),
+		GoModules:                  goModules,
+		OutputBase:                 outputBase,
+		GoHeaderFilePath:           DefaultGoHeaderFile(),
 		GeneratedBuildTag:          "ignore_autogenerated",
+		WatchInterval:              DefaultWatchInterval,
 		GeneratedByCommentTemplate: "// Code generated by GENERATOR_NAME. Do NOT EDIT.",
 		defaultCommandLineFlags:    true,
 	}
@@ -49,7 +59,17 @@ type GeneratorArgs struct {
 	// Which directories to parse.
 	InputDirs []string
 
-	// Source tree to write results to.
+	// If true, the generator is run by "go generate" and processes the
+	// package it is run for, see ApplyGoGenerate.
+	GoGenerate bool
+
+	// If true, packages are resolved by the go command in module mode, which
+	// honors go.mod, go.work and replace directives. Otherwise they are
+	// resolved in GOPATH mode.
+	GoModules bool
+
+	// Source tree to write results to. If empty, the results are written next
+	// to the sources of each package.
 	OutputBase string
 
 	// Package path within the source tree.
@@ -69,9 +89,53 @@ type GeneratorArgs struct {
 	// If true, only verify, don't write anything.
 	VerifyOnly bool
 
+	// If true, list the generated files instead of writing them.
+	DryRun bool
+
+	// If true, Execute doesn't return after generating: it polls the files
+	// of the input packages every WatchInterval and runs the generator again
+	// on those which change, logging its errors.
+	Watch         bool
+	WatchInterval time.Duration
+
+	// If set, the generated files are written to this archive instead of the
+	// source tree, see NewOutputFS.
+	OutputArchive string
+
 	// If true, include *_test.go files
 	IncludeTestFile bool
 
+	// The maximum number of packages parsed, type-checked or generated at the
+	// same time. If zero, the number of CPUs is used.
+	Parallelism int
+
+	// If set, type-checked packages are cached in this directory and reused
+	// by later runs as long as their sources don't change.
+	CacheDir string
+
+	// If set, the generated files are recorded in this manifest, with the
+	// files of the packages and the options they are generated from, see
+	// Manifest.
+	Manifest string
+
+	// If true, the files recorded as generated in the manifest for the input
+	// packages are removed instead of generating them, see CleanManifest.
+	Clean bool
+
+	// The options given on the command line, in the environment or in the
+	// options file, as returned by ParseOptions. They are recorded in the
+	// manifest.
+	Options *Options
+
+	// If set, the comment markers of the input packages are checked against
+	// this registry, see parser.Builder.Markers.
+	Markers *types.MarkerRegistry
+
+	// The name the generated files are marked as owned by, see
+	// generator.Context.Owner. Defaults to the name of the command in
+	// Execute.
+	Owner string
+
 	// GeneratedBuildTag is the tag used to identify code generated by execution
 	// of the type. Each generator should use a different tag, and different
 	// groups of generators (external API that depends on vine generators) should
@@ -95,17 +159,34 @@ func (g *GeneratorArgs) WithoutDefaultFlagParsing() *GeneratorArgs {
 func (g *GeneratorArgs) AddFlags(flagSet *pflag.FlagSet) {
 	flagSet.StringSliceVarP(&g.InputDirs, "input-dirs", "i", g.InputDirs,
 		"Comma-separated list of import paths to get input type from.")
+	flagSet.BoolVar(&g.GoGenerate, "go-generate", g.GoGenerate,
+		"If true, process the package go generate runs the command for, given by $GOPACKAGE and the working directory, and write the output next to it. Relative input directories and output package are relative to it.")
+	flagSet.BoolVar(&g.GoModules, "go-modules", g.GoModules,
+		"If true, resolve packages in module mode; defaults to true inside a Go module or workspace.")
 	flagSet.StringVarP(&g.OutputBase, "output-base", "o", g.OutputBase,
-		"Output base; defaults to $GOPATH/src/ or ./ if $GOPATH is not set.")
+		"Output base; if empty, output is written next to the source packages. Defaults to empty in module mode, $GOPATH/src/ or ./ otherwise.")
 	flagSet.StringVarP(&g.OutputPackagePath, "output-package", "p", g.OutputPackagePath, "Base package path.")
 	flagSet.StringVarP(&g.OutputFileBaseName, "output-file-base", "O", g.OutputFileBaseName, "Base name (without .go suffix) for output files.")
 	flagSet.StringVarP(&g.GoHeaderFilePath, "go-header-file", "H", g.GoHeaderFilePath, "File containing boilerplate header text. The string YEAR will be replace with the current 4-digit year.")
 	flagSet.BoolVarP(&g.VerifyOnly, "verify-only", "", g.VerifyOnly, "If true, only verify existing output, do not write anything.")
+	flagSet.BoolVar(&g.DryRun, "dry-run", g.DryRun, "If true, list the files which would be generated instead of writing them.")
+	flagSet.StringVar(&g.OutputArchive, "output-archive", g.OutputArchive, "If set, write the generated files to this .tar, .tar.gz, .tgz or .zip archive instead of the source tree; - writes a tar to stdout.")
 	flagSet.StringVarP(&g.GeneratedBuildTag, "build-tag", "", g.GeneratedBuildTag, "A go build tag to use to identify files generated by this command. Should be unique.")
+	flagSet.IntVar(&g.Parallelism, "parallelism", g.Parallelism, "The maximum number of packages parsed, type-checked or generated at the same time; 0 uses the number of CPUs, 1 disables concurrency.")
+	flagSet.BoolVar(&g.Watch, "watch", g.Watch, "If true, keep running and generate again for the input packages whose files change, instead of exiting.")
+	flagSet.DurationVar(&g.WatchInterval, "watch-interval", g.WatchInterval, "How often the files of the input packages are polled for changes in watch mode.")
+	flagSet.StringVar(&g.CacheDir, "cache-dir", g.CacheDir, "If set, cache type-checked packages in this directory to speed up later runs.")
+	flagSet.StringVar(&g.Manifest, "manifest", g.Manifest, "If set, record the generated files in this manifest, with the hashes of the files and the options they are generated from, for the check subcommand and --clean.")
+	flagSet.BoolVar(&g.Clean, "clean", g.Clean, "If true, remove the files recorded as generated in the manifest for the input packages, instead of generating them.")
+	MarkPathFlags(flagSet, "output-base", "go-header-file", "output-archive", "cache-dir", "manifest")
 }
 
 // LoadGoBoilerplate loads the boilerplate file passed to --go-header-file.
+// There is no boilerplate if no file is set.
 func (g *GeneratorArgs) LoadGoBoilerplate() ([]byte, error) {
+	if g.GoHeaderFilePath == "" {
+		return nil, nil
+	}
 	b, err := ioutil.ReadFile(g.GoHeaderFilePath)
 	if err != nil {
 		return nil, err
@@ -128,9 +209,15 @@ func (g *GeneratorArgs) LoadGoBoilerplate() ([]byte, error) {
 // directories.
 func (g *GeneratorArgs) NewBuilder() (*parser.Builder, error) {
 	b := parser.New()
+	if g.GoModules {
+		b = parser.NewWithModules("")
+	}
 
 	// flag for including *_test.go
 	b.IncludeTestFiles = g.IncludeTestFile
+	b.Parallelism = g.Parallelism
+	b.CacheDir = g.CacheDir
+	b.Markers = g.Markers
 
 	// Ignore all auto-generated files.
 	b.AddBuildTags(g.GeneratedBuildTag)
@@ -150,6 +237,14 @@ func (g *GeneratorArgs) NewBuilder() (*parser.Builder, error) {
 	return b, nil
 }
 
+// parallelism returns the number of packages generated at the same time.
+func (g *GeneratorArgs) parallelism() int {
+	if g.Parallelism == 0 {
+		return runtime.NumCPU()
+	}
+	return g.Parallelism
+}
+
 // InputIncludes returns true if the given package is a (sub) package of one of
 // the InputDirs.
 func (g *GeneratorArgs) InputIncludes(p *types.Package) bool {
@@ -178,6 +273,39 @@ func DefaultSourceTree() string {
 	return "./"
 }
 
+// DefaultGoModules returns whether packages should be resolved in module
+// mode: GO111MODULE doesn't turn modules off and the current directory is
+// inside a module or a workspace.
+func DefaultGoModules() bool {
+	if os.Getenv("GO111MODULE") == "off" {
+		return false
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return false
+	}
+	for {
+		for _, name := range []string{"go.mod", "go.work"} {
+			if fi, err := os.Stat(filepath.Join(dir, name)); err == nil && !fi.IsDir() {
+				return true
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
+
+// owner returns the name of the generator owning the generated files.
+func (g *GeneratorArgs) owner() string {
+	if g.Owner != "" {
+		return g.Owner
+	}
+	return path.Base(os.Args[0])
+}
+
 // Execute implements main().
 // If you don't need any non-default behavior, use as:
 // args.Default().Execute(...)
@@ -185,9 +313,17 @@ func (g *GeneratorArgs) Execute(nameSystems namer.NameSystems, defaultSystem str
 	if g.defaultCommandLineFlags {
 		flagSet := pflag.NewFlagSet("generator", pflag.ExitOnError)
 		g.AddFlags(flagSet)
-		if err := flagSet.Parse(os.Args); err != nil {
+		opts, err := ParseOptions(flagSet, g.owner(), os.Args)
+		if err != nil {
 			return err
 		}
+		if err := g.ApplyGoGenerate(opts); err != nil {
+			return err
+		}
+		g.Options = opts
+	}
+	if g.Clean {
+		return g.clean()
 	}
 
 	b, err := g.NewBuilder()
@@ -203,11 +339,68 @@ func (g *GeneratorArgs) Execute(nameSystems namer.NameSystems, defaultSystem str
 		return fmt.Errorf("failed making a context: %v", err)
 	}
 
+	err = g.execute(c, pkgs)
+	if !g.Watch {
+		return err
+	}
+	if err != nil {
+		log.Errorf("Error: %v", err)
+	}
+
+	dirs := map[string]string{}
+	for _, pkgPath := range c.Inputs {
+		dirs[pkgPath] = c.Universe[pkgPath].SourcePath
+	}
+	g.NewWatcher(dirs).Run(g.WatchInterval, func(changed []string) error {
+		reloaded, err := c.Reload(changed)
+		if err != nil {
+			return err
+		}
+		// Only the input packages reloaded are generated again.
+		rc := c.WithNameSystems(nameSystems, defaultSystem)
+		rc.Inputs = reloaded
+		return g.execute(rc, pkgs)
+	})
+	return nil
+}
+
+// execute runs the generator on the input packages of c.
+func (g *GeneratorArgs) execute(c *generator.Context, pkgs func(*generator.Context, *GeneratorArgs) generator.Packages) error {
+	var err error
 	c.Verify = g.VerifyOnly
+	c.Parallelism = g.parallelism()
+	c.Owner = g.owner()
+	if !c.Verify {
+		if c.Output, err = g.NewOutputFS(); err != nil {
+			return err
+		}
+	}
 	packages := pkgs(c, g)
 	if err := c.ExecutePackages(g.OutputBase, packages); err != nil {
-		return fmt.Errorf("failed executing generator: %v", err)
+		CloseOutputFS(c.Output)
+		return fmt.Errorf("failed executing generator: %w", err)
 	}
 
-	return nil
+	if err := CloseOutputFS(c.Output); err != nil || c.Verify {
+		return err
+	}
+	return g.recordContext(c)
+}
+
+// clean removes the files generated for the input packages, or in the
+// output package, as recorded in the manifest.
+func (g *GeneratorArgs) clean() error {
+	out, err := g.NewOutputFS()
+	if err != nil {
+		return err
+	}
+	pkgPaths := g.InputDirs
+	if len(pkgPaths) != 0 && g.OutputPackagePath != "" {
+		pkgPaths = append(append([]string{}, pkgPaths...), g.OutputPackagePath)
+	}
+	if err := g.CleanManifest(pkgPaths, out); err != nil {
+		CloseOutputFS(out)
+		return err
+	}
+	return CloseOutputFS(out)
 }
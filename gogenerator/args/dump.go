@@ -0,0 +1,390 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package args
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/vine-io/gogogen/gogenerator/types"
+	"github.com/vine-io/gogogen/util/log"
+)
+
+// DumpCommand is the name of the subcommand which runs RunDump.
+const DumpCommand = "dump"
+
+// DumpPackage is what the parser produced for a package, as dumped by
+// RunDump. Types are referred to by name, qualified with their package path
+// unless they belong to the dumped package, so that the dump doesn't depend
+// on where the sources are.
+type DumpPackage struct {
+	Path     string              `json:"path"`
+	Name     string              `json:"name"`
+	Comments []string            `json:"comments,omitempty"`
+	Markers  map[string][]string `json:"markers,omitempty"`
+	Types    []*DumpType         `json:"types"`
+}
+
+// DumpType is a type of a DumpPackage.
+type DumpType struct {
+	Name string     `json:"name"`
+	Kind types.Kind `json:"kind"`
+	// The file, relative to the directory of the package, and line.
+	Position                  string              `json:"position,omitempty"`
+	CommentLines              []string            `json:"commentLines,omitempty"`
+	SecondClosestCommentLines []string            `json:"secondClosestCommentLines,omitempty"`
+	Markers                   map[string][]string `json:"markers,omitempty"`
+	Underlying                string              `json:"underlying,omitempty"`
+	Elem                      string              `json:"elem,omitempty"`
+	Key                       string              `json:"key,omitempty"`
+	TypeParams                []string            `json:"typeParams,omitempty"`
+	Members                   []*DumpMember       `json:"members,omitempty"`
+	Methods                   []string            `json:"methods,omitempty"`
+	Enum                      []*DumpEnumValue    `json:"enum,omitempty"`
+}
+
+// DumpMember is a struct member of a DumpType.
+type DumpMember struct {
+	Name         string              `json:"name"`
+	Type         string              `json:"type"`
+	Kind         types.Kind          `json:"kind"`
+	Embedded     bool                `json:"embedded,omitempty"`
+	Tags         string              `json:"tags,omitempty"`
+	Position     string              `json:"position,omitempty"`
+	CommentLines []string            `json:"commentLines,omitempty"`
+	Markers      map[string][]string `json:"markers,omitempty"`
+}
+
+// DumpEnumValue is a constant of a DumpType.
+type DumpEnumValue struct {
+	Name         string   `json:"name"`
+	Value        string   `json:"value"`
+	CommentLines []string `json:"commentLines,omitempty"`
+}
+
+// DumpFilter selects the types to dump. A type is dumped if its name matches
+// one of Types, if any, and if it, or one of its members, has one of
+// Markers, if any.
+type DumpFilter struct {
+	// Patterns as in path.Match, matched against the name of a type and its
+	// qualified name, e.g. "Resource*" or "example.com/api.Resource".
+	Types []string
+	// Markers written "name" or "name=value", without the leading "+".
+	Markers []string
+}
+
+// RunDump implements the "dump" subcommand of the generators: it parses the
+// packages given in argv, written like InputDirs, and writes what the parser
+// produced for them to out, as JSON or as a tree. See DumpFilter for the
+// filters it accepts.
+func (g *GeneratorArgs) RunDump(argv []string, out io.Writer) error {
+	fs := pflag.NewFlagSet(DumpCommand, pflag.ContinueOnError)
+	format := fs.String("format", "tree", "The output format, json or tree.")
+	filter := DumpFilter{}
+	fs.StringSliceVar(&filter.Types, "type", nil, "Only dump the types whose name, or qualified name, matches one of these patterns.")
+	fs.StringSliceVar(&filter.Markers, "marker", nil, "Only dump the types which have, or have a member which has, one of these markers, written name or name=value.")
+	if err := fs.Parse(argv); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no package to dump")
+	}
+	if *format != "json" && *format != "tree" {
+		return fmt.Errorf("unknown format %q, want json or tree", *format)
+	}
+
+	// Keep the logs of the parser out of the dump.
+	log.DefaultOut(os.Stderr)
+
+	dump := *g
+	dump.InputDirs = fs.Args()
+	// Dumping must not stop at marker problems.
+	dump.Markers = nil
+	b, err := dump.NewBuilder()
+	if err != nil {
+		return err
+	}
+	u, err := b.FindTypes()
+	if err != nil {
+		return err
+	}
+
+	pkgs := Dump(u, b.FindPackages(), filter)
+	if *format == "json" {
+		return WriteDumpJSON(out, pkgs)
+	}
+	return WriteDumpTree(out, pkgs)
+}
+
+// Dump returns the dump of the packages pkgPaths of u, sorted by path, with
+// the types matching filter sorted by name. Packages without such types are
+// left out when filtering.
+func Dump(u types.Universe, pkgPaths []string, filter DumpFilter) []*DumpPackage {
+	paths := append([]string{}, pkgPaths...)
+	sort.Strings(paths)
+	filtering := len(filter.Types) > 0 || len(filter.Markers) > 0
+
+	var out []*DumpPackage
+	for _, pkgPath := range paths {
+		p := u[pkgPath]
+		if p == nil {
+			continue
+		}
+		dp := &DumpPackage{
+			Path:     p.Path,
+			Name:     p.Name,
+			Comments: p.Comments,
+			Markers:  markersOf(p.Comments),
+			Types:    []*DumpType{},
+		}
+		names := make([]string, 0, len(p.Types))
+		for name := range p.Types {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			t := p.Types[name]
+			if filter.match(t) {
+				dp.Types = append(dp.Types, dumpType(p, t))
+			}
+		}
+		if filtering && len(dp.Types) == 0 {
+			continue
+		}
+		out = append(out, dp)
+	}
+	return out
+}
+
+func (f DumpFilter) match(t *types.Type) bool {
+	if len(f.Types) > 0 {
+		matched := false
+		for _, pattern := range f.Types {
+			if ok, _ := path.Match(pattern, t.Name.Name); ok {
+				matched = true
+			} else if ok, _ := path.Match(pattern, t.Name.String()); ok {
+				matched = true
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(f.Markers) == 0 {
+		return true
+	}
+	groups := [][]string{append(append([]string{}, t.SecondClosestCommentLines...), t.CommentLines...)}
+	for _, m := range t.Members {
+		groups = append(groups, m.CommentLines)
+	}
+	for _, lines := range groups {
+		tags := types.ExtractCommentTags("+", lines)
+		for _, marker := range f.Markers {
+			kv := strings.SplitN(strings.TrimPrefix(marker, "+"), "=", 2)
+			for _, v := range tags[kv[0]] {
+				if len(kv) == 1 || v == kv[1] {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func dumpType(p *types.Package, t *types.Type) *DumpType {
+	name := func(t *types.Type) string { return dumpName(p.Path, t) }
+	dt := &DumpType{
+		Name:                      t.Name.Name,
+		Kind:                      t.Kind,
+		Position:                  dumpPosition(p, t.Position),
+		CommentLines:              t.CommentLines,
+		SecondClosestCommentLines: t.SecondClosestCommentLines,
+		Markers:                   markersOf(append(append([]string{}, t.SecondClosestCommentLines...), t.CommentLines...)),
+		Underlying:                name(t.Underlying),
+		Elem:                      name(t.Elem),
+		Key:                       name(t.Key),
+	}
+	for _, tp := range t.TypeParams {
+		dt.TypeParams = append(dt.TypeParams, tp.Name.Name+" "+name(tp.Constraint))
+	}
+	for _, m := range t.Members {
+		dt.Members = append(dt.Members, &DumpMember{
+			Name:         m.Name,
+			Type:         name(m.Type),
+			Kind:         m.Type.Kind,
+			Embedded:     m.Embedded,
+			Tags:         m.Tags,
+			Position:     dumpPosition(p, m.Position),
+			CommentLines: m.CommentLines,
+			Markers:      markersOf(m.CommentLines),
+		})
+	}
+	for method := range t.Methods {
+		dt.Methods = append(dt.Methods, method)
+	}
+	sort.Strings(dt.Methods)
+	for _, v := range t.Enum {
+		dt.Enum = append(dt.Enum, &DumpEnumValue{Name: v.Name, Value: v.Value, CommentLines: v.CommentLines})
+	}
+	return dt
+}
+
+// dumpName returns the name of t as written in pkg. Unlike with the raw
+// namer, named types keep their name rather than the one of their
+// underlying type.
+func dumpName(pkg string, t *types.Type) string {
+	if t == nil {
+		return ""
+	}
+	switch t.Kind {
+	case types.Pointer:
+		return "*" + dumpName(pkg, t.Elem)
+	case types.Builtin:
+		return t.Name.Name
+	}
+	if t.Name.Package == pkg {
+		return t.Name.Name
+	}
+	if t.Name.Package != "" {
+		return t.Name.String()
+	}
+	switch t.Kind {
+	case types.Slice:
+		return "[]" + dumpName(pkg, t.Elem)
+	case types.Array:
+		return "[" + strconv.FormatInt(t.Len, 10) + "]" + dumpName(pkg, t.Elem)
+	case types.Map:
+		return "map[" + dumpName(pkg, t.Key) + "]" + dumpName(pkg, t.Elem)
+	}
+	return t.Name.Name
+}
+
+// dumpPosition returns pos as file:line, the file being relative to the
+// directory of p when it is in it.
+func dumpPosition(p *types.Package, pos token.Position) string {
+	if pos.Line == 0 {
+		return ""
+	}
+	file := pos.Filename
+	if rel, err := filepath.Rel(p.SourcePath, file); err == nil && !strings.HasPrefix(rel, "..") {
+		file = filepath.ToSlash(rel)
+	}
+	return file + ":" + strconv.Itoa(pos.Line)
+}
+
+func markersOf(lines []string) map[string][]string {
+	tags := types.ExtractCommentTags("+", lines)
+	if len(tags) == 0 {
+		return nil
+	}
+	return tags
+}
+
+// WriteDumpJSON writes pkgs to w as indented JSON.
+func WriteDumpJSON(w io.Writer, pkgs []*DumpPackage) error {
+	if pkgs == nil {
+		pkgs = []*DumpPackage{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(pkgs)
+}
+
+// WriteDumpTree writes pkgs to w as an indented tree, one line per package,
+// type, member, enum value and comment line.
+func WriteDumpTree(w io.Writer, pkgs []*DumpPackage) error {
+	tw := &treeWriter{w: w}
+	for _, p := range pkgs {
+		tw.line(0, "package %s (%s)", p.Path, p.Name)
+		tw.comments(1, p.Comments)
+		for _, t := range p.Types {
+			head := fmt.Sprintf("type %s %s", t.Name, t.Kind)
+			switch {
+			case t.Underlying != "":
+				head += " " + t.Underlying
+			case t.Key != "":
+				head += fmt.Sprintf(" [%s]%s", t.Key, t.Elem)
+			case t.Elem != "":
+				head += " " + t.Elem
+			}
+			if t.Position != "" {
+				head += "  " + t.Position
+			}
+			tw.line(1, "%s", head)
+			if strings.TrimSpace(strings.Join(t.SecondClosestCommentLines, "")) != "" {
+				tw.line(2, "second closest comments:")
+				tw.comments(3, t.SecondClosestCommentLines)
+			}
+			tw.comments(2, t.CommentLines)
+			for _, tp := range t.TypeParams {
+				tw.line(2, "type param %s", tp)
+			}
+			for _, m := range t.Members {
+				head := m.Name + " " + m.Type
+				if m.Embedded {
+					head = "embedded " + head
+				}
+				if m.Tags != "" {
+					head += " `" + m.Tags + "`"
+				}
+				head += " (" + string(m.Kind) + ")"
+				if m.Position != "" {
+					head += "  " + m.Position
+				}
+				tw.line(2, "%s", head)
+				tw.comments(3, m.CommentLines)
+			}
+			for _, v := range t.Enum {
+				tw.line(2, "const %s = %s", v.Name, v.Value)
+				tw.comments(3, v.CommentLines)
+			}
+			for _, method := range t.Methods {
+				tw.line(2, "method %s", method)
+			}
+		}
+	}
+	return tw.err
+}
+
+type treeWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (tw *treeWriter) line(depth int, format string, args ...interface{}) {
+	if tw.err != nil {
+		return
+	}
+	_, tw.err = fmt.Fprintf(tw.w, strings.Repeat("  ", depth)+format+"\n", args...)
+}
+
+// comments writes the non-empty lines, the parser records an empty line
+// for a missing comment.
+func (tw *treeWriter) comments(depth int, lines []string) {
+	for _, l := range lines {
+		if strings.TrimSpace(l) != "" {
+			tw.line(depth, "// %s", l)
+		}
+	}
+}
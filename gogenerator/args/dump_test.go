@@ -0,0 +1,84 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package args
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/vine-io/gogogen/util/diff"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the tests")
+
+func TestDump(t *testing.T) {
+	src := map[string][]byte{}
+	for _, name := range []string{"doc.go", "types.go"} {
+		data, err := ioutil.ReadFile(filepath.Join("testdata/dump/api", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		src["example.com/dump/api/"+name] = data
+	}
+
+	cases := []struct {
+		golden string
+		filter DumpFilter
+		write  func(w *bytes.Buffer, pkgs []*DumpPackage) error
+	}{{
+		golden: "all.json",
+		write:  func(w *bytes.Buffer, pkgs []*DumpPackage) error { return WriteDumpJSON(w, pkgs) },
+	}, {
+		golden: "filtered.txt",
+		filter: DumpFilter{Types: []string{"Res*", "example.com/dump/api.Phase"}, Markers: []string{"primaryKey", "gogo:genproto=true"}},
+		write:  func(w *bytes.Buffer, pkgs []*DumpPackage) error { return WriteDumpTree(w, pkgs) },
+	}}
+
+	g := Default()
+	b, err := g.NewBuilder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AddOverlay(overlayRoot, src); err != nil {
+		t.Fatal(err)
+	}
+	u, err := b.FindTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cases {
+		got := &bytes.Buffer{}
+		if err := c.write(got, Dump(u, b.FindPackages(), c.filter)); err != nil {
+			t.Fatalf("%s: %v", c.golden, err)
+		}
+		path := filepath.Join("testdata/dump", c.golden)
+		if *update {
+			if err := ioutil.WriteFile(path, got.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := diff.Unified(path, "dump", want, got.Bytes()); d != "" {
+			t.Errorf("%s: dump differs (run with -update to accept it):\n%s", c.golden, d)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package args
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/vine-io/gogogen/gogenerator/generator"
+	"github.com/vine-io/gogogen/gogenerator/namer"
+)
+
+// overlayRoot is the directory the in-memory files passed to Generate are
+// given as their location. Nothing is read from or written to it.
+var overlayRoot = filepath.Join(string(filepath.Separator), "gogogen-overlay")
+
+// Run is a generator to run with Generate: its arguments and what would be
+// passed to its GeneratorArgs.Execute.
+type Run struct {
+	Args          *GeneratorArgs
+	NameSystems   namer.NameSystems
+	DefaultSystem string
+	Packages      func(*generator.Context, *GeneratorArgs) generator.Packages
+}
+
+// Generate runs generators on in-memory go files and returns the files they
+// generate, without writing to disk. The keys of src are the import path of
+// the package of a file followed by its name, e.g. "example.com/pkg/types.go",
+// and the generated files are keyed the same way. The packages of src are the
+// input packages of every run, and the InputDirs, OutputBase and VerifyOnly
+// of the runs' arguments are ignored. Imports which are not in src are
+// resolved from disk as usual.
+func Generate(src map[string][]byte, runs ...Run) (map[string][]byte, error) {
+	out := map[string][]byte{}
+	for _, r := range runs {
+		g := *r.Args
+		g.InputDirs = nil
+		g.OutputBase = overlayRoot
+		g.VerifyOnly = false
+
+		b, err := g.NewBuilder()
+		if err != nil {
+			return nil, err
+		}
+		if err := b.AddOverlay(overlayRoot, src); err != nil {
+			return nil, err
+		}
+		c, err := generator.NewContext(b, r.NameSystems, r.DefaultSystem)
+		if err != nil {
+			return nil, fmt.Errorf("failed making a context: %v", err)
+		}
+
+		c.Parallelism = g.parallelism()
+		c.Owner = g.Owner
+		output := generator.NewMemoryFS()
+		c.Output = output
+		if err := c.ExecutePackages(overlayRoot, r.Packages(c, &g)); err != nil {
+			return nil, fmt.Errorf("failed executing generator: %v", err)
+		}
+		for p, data := range output.Files() {
+			rel, err := filepath.Rel(overlayRoot, p)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				return nil, fmt.Errorf("generated file %q is outside of the output", p)
+			}
+			out[filepath.ToSlash(rel)] = data
+		}
+	}
+	return out, nil
+}
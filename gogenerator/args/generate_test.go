@@ -0,0 +1,134 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package args
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vine-io/gogogen/gogenerator/generator"
+	"github.com/vine-io/gogogen/gogenerator/namer"
+	"github.com/vine-io/gogogen/gogenerator/types"
+)
+
+// genNames writes a constant holding the name of every type of its package.
+type genNames struct {
+	generator.DefaultGen
+	prefix string
+}
+
+func (g *genNames) GenerateType(c *generator.Context, t *types.Type, w io.Writer) error {
+	_, err := fmt.Fprintf(w, "const %s%s = %q\n\n", g.prefix, t.Name.Name, t.Name.Name)
+	return err
+}
+
+func TestGenerate(t *testing.T) {
+	src := map[string][]byte{
+		"example.com/gen/a/types.go": []byte("package a\n\ntype T struct{}\n\ntype U struct{}\n"),
+		"example.com/gen/b/types.go": []byte("package b\n\nimport \"example.com/gen/a\"\n\ntype V struct {\n\tT a.T\n}\n"),
+	}
+	run := func(name, prefix string) Run {
+		g := Default()
+		g.GoHeaderFilePath = ""
+		// Ignored, the input packages are those of src.
+		g.InputDirs = []string{"example.com/missing"}
+		return Run{
+			Args:          g,
+			NameSystems:   namer.NameSystems{"public": namer.NewPublicNamer(0)},
+			DefaultSystem: "public",
+			Packages: func(c *generator.Context, a *GeneratorArgs) generator.Packages {
+				var pkgs generator.Packages
+				for _, pkgPath := range c.Inputs {
+					pkgPath := pkgPath
+					pkgs = append(pkgs, &generator.DefaultPackage{
+						PackageName:   path.Base(pkgPath),
+						PackagePath:   pkgPath,
+						GeneratorList: []generator.Generator{&genNames{DefaultGen: generator.DefaultGen{OptionalName: name}, prefix: prefix}},
+						FilterFunc: func(c *generator.Context, t *types.Type) bool {
+							return t.Name.Package == pkgPath
+						},
+					})
+				}
+				return pkgs
+			},
+		}
+	}
+
+	out, err := Generate(src, run("zz_names", "Name"), run("zz_other", "Other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for name := range out {
+		names = append(names, name)
+	}
+	want := []string{
+		"example.com/gen/a/zz_names.go",
+		"example.com/gen/a/zz_other.go",
+		"example.com/gen/b/zz_names.go",
+		"example.com/gen/b/zz_other.go",
+	}
+	if !sameStrings(names, want) {
+		t.Errorf("got the files %v, want %v", names, want)
+	}
+	got := string(out["example.com/gen/a/zz_names.go"])
+	for _, s := range []string{"package a", `NameT = "T"`, `NameU = "U"`} {
+		if !strings.Contains(got, s) {
+			t.Errorf("expected %q in:\n%s", s, got)
+		}
+	}
+	if _, err := os.Stat(overlayRoot); !os.IsNotExist(err) {
+		t.Errorf("expected nothing written to %s: %v", overlayRoot, err)
+	}
+
+	// Generating again gives the same files.
+	again, err := Generate(src, run("zz_names", "Name"), run("zz_other", "Other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again, out) {
+		t.Errorf("generating again gives other files")
+	}
+
+	// The sources must type-check.
+	src["example.com/gen/b/types.go"] = []byte("package b\n\ntype V struct {\n\tT Missing\n}\n")
+	if _, err := Generate(src, run("zz_names", "Name")); err == nil || !strings.Contains(err.Error(), "undefined: Missing") {
+		t.Errorf("expected a type error, got %v", err)
+	}
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := map[string]int{}
+	for _, s := range a {
+		seen[s]++
+	}
+	for _, s := range b {
+		seen[s]--
+	}
+	for _, n := range seen {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
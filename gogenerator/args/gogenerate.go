@@ -0,0 +1,188 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package args
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/vine-io/gogogen/gogenerator/parser"
+	"github.com/vine-io/gogogen/util/log"
+)
+
+// BoilerplateFile is the boilerplate header of the generated files, relative
+// to the root of a module, see DefaultGoHeaderFile.
+const BoilerplateFile = "hack/boilerplate.go.txt"
+
+// DefaultGoHeaderFile returns the BoilerplateFile of the working directory or
+// of its closest parent, up to the root of its module, or "" if there is
+// none.
+func DefaultGoHeaderFile() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		header := filepath.Join(dir, filepath.FromSlash(BoilerplateFile))
+		if fi, err := os.Stat(header); err == nil && !fi.IsDir() {
+			return header
+		}
+		if fi, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil && !fi.IsDir() {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// GoGenerate is the package which "go generate" runs a generator for, as told
+// by the working directory and the environment it sets.
+type GoGenerate struct {
+	// The directory of the package.
+	Dir string
+	// The import path of the package.
+	Package string
+	// The name of the package, $GOPACKAGE.
+	Name string
+	// The file holding the go:generate line, $GOFILE.
+	File string
+}
+
+// LoadGoGenerate returns the package which "go generate" runs the generator
+// for. It fails if the generator isn't run by "go generate".
+func LoadGoGenerate(goModules bool) (*GoGenerate, error) {
+	gg := &GoGenerate{Name: os.Getenv("GOPACKAGE"), File: os.Getenv("GOFILE")}
+	if gg.Name == "" {
+		return nil, fmt.Errorf("$GOPACKAGE is not set, not run by go generate")
+	}
+	if strings.HasSuffix(gg.Name, "_test") {
+		return nil, fmt.Errorf("go:generate is not supported in the test package %s", gg.Name)
+	}
+	var err error
+	if gg.Dir, err = os.Getwd(); err != nil {
+		return nil, err
+	}
+	if gg.Package, err = ImportPath(gg.Dir, goModules); err != nil {
+		return nil, err
+	}
+	return gg, nil
+}
+
+// Resolve returns the import path of the package pkg, which is relative to
+// the package of gg if it is "." or "..", or starts with "./" or "../".
+func (gg *GoGenerate) Resolve(pkg string) string {
+	if pkg == "." || pkg == ".." || strings.HasPrefix(pkg, "./") || strings.HasPrefix(pkg, "../") {
+		return path.Join(gg.Package, pkg)
+	}
+	return pkg
+}
+
+// ImportPath returns the import path of the package in dir: as the go
+// command resolves it in module mode, relative to a $GOPATH/src otherwise.
+func ImportPath(dir string, goModules bool) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	if goModules {
+		// The go command runs in dir, to find the module or the workspace
+		// it belongs to.
+		return parser.NewWithModules(dir).PackagePath(dir)
+	}
+	for _, gopath := range filepath.SplitList(os.Getenv("GOPATH")) {
+		if rel, err := filepath.Rel(filepath.Join(gopath, "src"), dir); err == nil && !strings.HasPrefix(rel, "..") && rel != "." {
+			return filepath.ToSlash(rel), nil
+		}
+	}
+	return "", fmt.Errorf("%s is not in a $GOPATH/src", dir)
+}
+
+// ResolveInputDirs returns dirs, as given to --input-dirs, with the
+// directories which are relative or absolute paths replaced by the import
+// paths of their packages, see ImportPath. A trailing "/..." is kept.
+func (g *GeneratorArgs) ResolveInputDirs(dirs []string) ([]string, error) {
+	resolve := func(dir string) (string, error) { return ImportPath(dir, false) }
+	if g.GoModules {
+		// The directories share the packages the go command loads, from
+		// the working directory.
+		b := parser.NewWithModules("")
+		resolve = func(dir string) (string, error) {
+			abs, err := filepath.Abs(dir)
+			if err != nil {
+				return "", err
+			}
+			return b.PackagePath(abs)
+		}
+	}
+	resolved := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		d, recursive := dir, ""
+		if strings.HasSuffix(d, "/...") {
+			d, recursive = strings.TrimSuffix(d, "/..."), "/..."
+		}
+		if d == "." || d == ".." || strings.HasPrefix(d, "./") || strings.HasPrefix(d, "../") || filepath.IsAbs(d) {
+			pkgPath, err := resolve(d)
+			if err != nil {
+				return nil, fmt.Errorf("unable to resolve %q: %v", dir, err)
+			}
+			d = pkgPath
+		}
+		resolved = append(resolved, d+recursive)
+	}
+	return resolved, nil
+}
+
+// ApplyGoGenerate makes the generator process the package which "go
+// generate" runs it for if GoGenerate is set: the input directories and the
+// output package default to it, and relative ones are resolved against it;
+// the files are written next to the sources unless an output base is given.
+// The options given on the command line, in the environment or in the
+// options file are told by opts.
+func (g *GeneratorArgs) ApplyGoGenerate(opts *Options) error {
+	if !g.GoGenerate {
+		return nil
+	}
+	gg, err := LoadGoGenerate(g.GoModules)
+	if err != nil {
+		return err
+	}
+	given := func(name string) bool {
+		return opts != nil && opts.Sources[name] != ""
+	}
+
+	if given("input-dirs") {
+		for i, dir := range g.InputDirs {
+			g.InputDirs[i] = gg.Resolve(dir)
+		}
+	} else {
+		g.InputDirs = []string{gg.Package}
+	}
+	if given("output-package") {
+		g.OutputPackagePath = gg.Resolve(g.OutputPackagePath)
+	} else {
+		g.OutputPackagePath = gg.Package
+	}
+	if !given("output-base") {
+		g.OutputBase = ""
+	}
+	log.Infof("Generating for package %s (go:generate in %s)", gg.Package, gg.File)
+	return nil
+}
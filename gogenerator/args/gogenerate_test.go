@@ -0,0 +1,129 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package args
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestApplyGoGenerate(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "apis", "v1")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "hack"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"go.mod":                  "module \"example.com/m\" // the module\n\ngo 1.18\n",
+		"hack/boilerplate.go.txt": "// Copyright YEAR\n",
+		"apis/v1/doc.go":          "package v1\n",
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(root, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	t.Setenv("GOPACKAGE", "v1")
+	t.Setenv("GOFILE", "doc.go")
+
+	g := Default()
+	if want := filepath.Join(root, "hack", "boilerplate.go.txt"); g.GoHeaderFilePath != want {
+		t.Errorf("expected the header of the module %s, got %s", want, g.GoHeaderFilePath)
+	}
+
+	g.GoModules = true
+	g.GoGenerate = true
+	g.InputDirs = []string{"example.com/other"}
+	g.OutputBase = "/tmp/out"
+	g.OutputPackagePath = ".."
+	opts := &Options{Sources: map[string]string{"output-package": "flag"}}
+	if err := g.ApplyGoGenerate(opts); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"example.com/m/apis/v1"}; !reflect.DeepEqual(g.InputDirs, want) {
+		t.Errorf("expected the input dirs %v, got %v", want, g.InputDirs)
+	}
+	if g.OutputPackagePath != "example.com/m/apis" {
+		t.Errorf("expected the output package relative to the package, got %s", g.OutputPackagePath)
+	}
+	if g.OutputBase != "" {
+		t.Errorf("expected the output next to the package, got %s", g.OutputBase)
+	}
+
+	t.Setenv("GOPACKAGE", "")
+	if err := g.ApplyGoGenerate(opts); err == nil {
+		t.Errorf("expected an error when not run by go generate")
+	}
+}
+
+func TestImportPath(t *testing.T) {
+	root := t.TempDir()
+	// The vendored packages have their own import path, rather than one
+	// below the module.
+	files := map[string]string{
+		"go.mod":                        "module example.com/m\n\ngo 1.18\n\nrequire example.com/dep v1.0.0\n",
+		"main.go":                       "package m\n\nimport _ \"example.com/dep/x\"\n",
+		"a/a.go":                        "package a\n",
+		"a/testdata/b/b.go":             "package b\n",
+		"vendor/modules.txt":            "# example.com/dep v1.0.0\n## explicit\nexample.com/dep/x\n",
+		"vendor/example.com/dep/x/x.go": "package x\n",
+	}
+	for name, data := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("GOFLAGS", "-mod=vendor")
+	t.Setenv("GOWORK", "off")
+
+	for dir, want := range map[string]string{
+		"a":                        "example.com/m/a",
+		"vendor/example.com/dep/x": "example.com/dep/x",
+		// The go command finds no package in a directory without Go
+		// files below testdata.
+		"a/testdata": "example.com/m/a/testdata",
+	} {
+		got, err := ImportPath(filepath.Join(root, filepath.FromSlash(dir)), true)
+		if err != nil {
+			t.Errorf("%s: %v", dir, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s: got %s, want %s", dir, got, want)
+		}
+	}
+	if _, err := ImportPath(t.TempDir(), true); err == nil {
+		t.Errorf("expected an error outside of a module")
+	}
+}
@@ -0,0 +1,638 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package args
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/vine-io/gogogen/gogenerator/generator"
+	"github.com/vine-io/gogogen/util/log"
+)
+
+// CheckCommand is the name of the subcommand which runs RunCheck.
+const CheckCommand = "check"
+
+// ManifestVersion is the version of the format of the manifest files.
+const ManifestVersion = 1
+
+// manifestIgnoredFlags are the flags which don't change the generated files,
+// and aren't recorded in the manifest.
+var manifestIgnoredFlags = map[string]bool{
+	"verify-only":    true,
+	"dry-run":        true,
+	"output-archive": true,
+	"watch":          true,
+	"watch-interval": true,
+	"parallelism":    true,
+	"cache-dir":      true,
+	"manifest":       true,
+	"clean":          true,
+}
+
+// Manifest records how the generators generated their packages: their
+// version and options, the hashes of the Go files of the packages they read
+// and of the files they wrote. It tells which packages need generating again
+// without parsing them, see RunCheck, and which files to remove, see
+// GeneratorArgs.CleanManifest.
+type Manifest struct {
+	Version int `json:"version"`
+	// The packages read or generated by each generator, by name and import
+	// path.
+	Generators map[string]map[string]*ManifestPackage `json:"generators"`
+
+	// The path of the manifest. The paths it holds are relative to its
+	// directory.
+	path string
+}
+
+// ManifestPackage is what the manifest records of a package read or
+// generated by a generator, as of its last run on it.
+type ManifestPackage struct {
+	// The version of the generator, see GeneratorVersion.
+	GeneratorVersion string `json:"generatorVersion"`
+	// The values of the options given, other than those which don't change
+	// the generated files, by flag name.
+	Options map[string]string `json:"options,omitempty"`
+	// The options given on the command line rather than in the options file
+	// or the environment.
+	Flags []string `json:"flags,omitempty"`
+	// The hashes of the files the options name, such as the boilerplate
+	// header, by path.
+	OptionFiles map[string]string `json:"optionFiles,omitempty"`
+	// The build tag of the generated files and whether the tests are read,
+	// which tell the files of the package.
+	BuildTag         string `json:"buildTag,omitempty"`
+	IncludeTestFiles bool   `json:"includeTestFiles,omitempty"`
+	// The directory of the package, if it was read, and the hashes of its Go
+	// files by name.
+	Dir   string            `json:"dir,omitempty"`
+	Files map[string]string `json:"files,omitempty"`
+	// The hashes of the files generated for the package, by path.
+	Generated map[string]string `json:"generated,omitempty"`
+}
+
+// flagSnapshot is the value of a flag when the options were parsed.
+type flagSnapshot struct {
+	// The elements of the value, the absolute paths of a flag holding paths.
+	values []string
+	isPath bool
+}
+
+// snapshotFlags returns the values of the flags of fs which change the
+// generated files, by name.
+func snapshotFlags(fs *pflag.FlagSet) map[string]flagSnapshot {
+	flags := map[string]flagSnapshot{}
+	fs.VisitAll(func(f *pflag.Flag) {
+		if manifestIgnoredFlags[f.Name] {
+			return
+		}
+		s := flagSnapshot{isPath: f.Annotations[pathAnnotation] != nil}
+		if v, ok := f.Value.(pflag.SliceValue); ok {
+			s.values = v.GetSlice()
+		} else {
+			s.values = []string{f.Value.String()}
+		}
+		if s.isPath {
+			for i, path := range s.values {
+				if abs, err := filepath.Abs(path); err == nil && path != "" {
+					s.values[i] = abs
+				}
+			}
+		}
+		flags[f.Name] = s
+	})
+	return flags
+}
+
+// GeneratorVersion returns the version of the running generator: the version
+// of its module, or the revision it was built from.
+func GeneratorVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	revision, modified := "", false
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	switch {
+	case revision == "":
+		return "(devel)"
+	case modified:
+		return revision + "+dirty"
+	}
+	return revision
+}
+
+// ReadManifest reads the manifest at path, which is empty if the file doesn't
+// exist.
+func ReadManifest(path string) (*Manifest, error) {
+	m := &Manifest{Version: ManifestVersion, Generators: map[string]map[string]*ManifestPackage{}, path: path}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if m.Version != ManifestVersion {
+		return nil, fmt.Errorf("%s: unsupported manifest version %d", path, m.Version)
+	}
+	if m.Generators == nil {
+		m.Generators = map[string]map[string]*ManifestPackage{}
+	}
+	return m, nil
+}
+
+// Write writes the manifest to its file, replacing it at once.
+func (m *Manifest) Write() error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := m.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path)
+}
+
+// rel returns path relative to the directory of the manifest, with slashes.
+func (m *Manifest) rel(path string) string {
+	base, err := filepath.Abs(filepath.Dir(m.path))
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	if rel, err := filepath.Rel(base, abs); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(abs)
+}
+
+// abs returns the path of a path relative to the directory of the manifest.
+func (m *Manifest) abs(rel string) string {
+	path := filepath.FromSlash(rel)
+	if filepath.IsAbs(path) {
+		return path
+	}
+	dir, err := filepath.Abs(filepath.Dir(m.path))
+	if err != nil {
+		dir = filepath.Dir(m.path)
+	}
+	return filepath.Join(dir, path)
+}
+
+// options returns the options of opts as the manifest records them, the
+// names of those given on the command line, and the hashes of the files
+// named by the flags holding paths, by path.
+func (m *Manifest) options(opts *Options) (values map[string]string, flags []string, files map[string]string) {
+	if opts == nil {
+		return nil, nil, nil
+	}
+	values, files = map[string]string{}, map[string]string{}
+	for name, f := range opts.flags {
+		elems := f.values
+		if f.isPath {
+			elems = make([]string, len(f.values))
+			for i, path := range f.values {
+				if path == "" {
+					continue
+				}
+				elems[i] = m.rel(path)
+				if fi, err := os.Stat(path); err == nil && fi.Mode().IsRegular() {
+					if sum, err := hashFile(path); err == nil {
+						files[elems[i]] = sum
+					}
+				}
+			}
+		}
+		source := opts.Sources[name]
+		if source == "" {
+			continue
+		}
+		values[name] = strings.Join(elems, ",")
+		if source == "flag" {
+			flags = append(flags, name)
+		}
+	}
+	sort.Strings(flags)
+	return values, flags, files
+}
+
+// hashFile returns the hex encoded SHA-256 of the file at path.
+func hashFile(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// packageFiles returns the hashes of the Go files of the package in dir, by
+// name, as told by match. The files of skip, by path, are left out.
+func packageFiles(dir string, match func(dir, name string) bool, skip map[string]bool) (map[string]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]string{}
+	for _, fi := range infos {
+		path := filepath.Join(dir, fi.Name())
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".go") || skip[path] || !match(dir, fi.Name()) {
+			continue
+		}
+		if files[fi.Name()], err = hashFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// generatedPaths returns the paths of the files the manifest records as
+// generated by the generator name.
+func (m *Manifest) generatedPaths(name string) map[string]bool {
+	paths := map[string]bool{}
+	for _, p := range m.Generators[name] {
+		for rel := range p.Generated {
+			paths[m.abs(rel)] = true
+		}
+	}
+	return paths
+}
+
+// manifestName returns the name of the generator in the manifest: the
+// command it parsed its options for, or its owner.
+func (g *GeneratorArgs) manifestName() string {
+	if g.Options != nil && g.Options.Command != "" {
+		return g.Options.Command
+	}
+	return g.owner()
+}
+
+// writesManifest returns whether the generated files are recorded in the
+// manifest: they are unless they are only verified, listed or archived.
+func (g *GeneratorArgs) writesManifest() bool {
+	return g.Manifest != "" && !g.VerifyOnly && !g.DryRun && g.OutputArchive == ""
+}
+
+// RecordManifest records in the manifest, if Manifest is set, that the
+// packages of inputs, by import path, were read from their directories and
+// those of generated were generated, with the files by path. The entries of
+// the other packages are kept, as well as the generated files of the input
+// packages which weren't generated.
+func (g *GeneratorArgs) RecordManifest(inputs map[string]string, generated map[string][]string) error {
+	if !g.writesManifest() {
+		return nil
+	}
+	m, err := ReadManifest(g.Manifest)
+	if err != nil {
+		return err
+	}
+	name := g.manifestName()
+	pkgs := m.Generators[name]
+	if pkgs == nil {
+		pkgs = map[string]*ManifestPackage{}
+		m.Generators[name] = pkgs
+	}
+
+	options, flags, files := m.options(g.Options)
+	entry := func(pkgPath string) *ManifestPackage {
+		p := &ManifestPackage{
+			GeneratorVersion: GeneratorVersion(),
+			Options:          options,
+			Flags:            flags,
+			OptionFiles:      files,
+			BuildTag:         g.GeneratedBuildTag,
+			IncludeTestFiles: g.IncludeTestFile,
+		}
+		if old := pkgs[pkgPath]; old != nil {
+			p.Dir, p.Files, p.Generated = old.Dir, old.Files, old.Generated
+		}
+		pkgs[pkgPath] = p
+		return p
+	}
+	skip := map[string]bool{}
+	for pkgPath, paths := range generated {
+		p := entry(pkgPath)
+		p.Generated = map[string]string{}
+		for _, path := range paths {
+			sum, err := hashFile(path)
+			if err != nil {
+				return err
+			}
+			p.Generated[m.rel(path)] = sum
+			skip[path] = true
+		}
+	}
+	for path := range m.generatedPaths(name) {
+		skip[path] = true
+	}
+	match := packageFileMatcher(g.GeneratedBuildTag, g.IncludeTestFile)
+	for pkgPath, dir := range inputs {
+		p := entry(pkgPath)
+		if p.Files, err = packageFiles(dir, match, skip); err != nil {
+			return err
+		}
+		p.Dir = m.rel(dir)
+	}
+	return m.Write()
+}
+
+// recordContext records in the manifest the input packages of c and the
+// files it generated, see RecordManifest.
+func (g *GeneratorArgs) recordContext(c *generator.Context) error {
+	inputs := map[string]string{}
+	generated := map[string][]string{}
+	for _, pkgPath := range c.Inputs {
+		if p := c.Universe[pkgPath]; p != nil && p.SourcePath != "" {
+			inputs[pkgPath] = p.SourcePath
+		}
+		// The input packages whose files are no longer generated have
+		// none.
+		generated[pkgPath] = nil
+	}
+	for path, pkgPath := range c.Generated() {
+		generated[pkgPath] = append(generated[pkgPath], path)
+	}
+	return g.RecordManifest(inputs, generated)
+}
+
+// CleanManifest removes the files generated for the packages pkgPaths, as
+// recorded in the manifest, from out, or the disk if it is nil. Packages
+// ending with "/..." include those below them, relative directories are
+// resolved like the input dirs, see ResolveInputDirs, and all the packages are
+// cleaned if pkgPaths is empty. The files changed since they were generated
+// are kept. Unless they are only listed or archived, the packages whose files
+// are all removed are dropped from the manifest.
+func (g *GeneratorArgs) CleanManifest(pkgPaths []string, out generator.OutputFS) error {
+	if g.Manifest == "" {
+		return fmt.Errorf("--clean needs the manifest of the generated files, see --manifest")
+	}
+	pkgPaths, err := g.ResolveInputDirs(pkgPaths)
+	if err != nil {
+		return err
+	}
+	m, err := ReadManifest(g.Manifest)
+	if err != nil {
+		return err
+	}
+	name := g.manifestName()
+	pkgs := m.Generators[name]
+	for pkgPath, p := range pkgs {
+		if len(pkgPaths) != 0 && !matchPackage(pkgPaths, pkgPath) {
+			continue
+		}
+		for rel, sum := range p.Generated {
+			path := m.abs(rel)
+			current, err := hashFile(path)
+			switch {
+			case os.IsNotExist(err):
+			case err != nil:
+				return err
+			case current != sum:
+				log.Warnf("Keeping %q, changed since it was generated", path)
+				continue
+			default:
+				log.Infof("Removing generated file %q", path)
+				if out != nil {
+					err = out.Remove(path)
+				} else {
+					err = os.Remove(path)
+				}
+				if err != nil && !os.IsNotExist(err) {
+					return err
+				}
+			}
+			delete(p.Generated, rel)
+		}
+		if len(p.Generated) == 0 {
+			delete(pkgs, pkgPath)
+		}
+	}
+	if !g.writesManifest() {
+		return nil
+	}
+	if len(pkgs) == 0 {
+		delete(m.Generators, name)
+	}
+	return m.Write()
+}
+
+// matchPackage returns whether the package pkgPath is one of pkgPaths, or
+// below one ending with "/...".
+func matchPackage(pkgPaths []string, pkgPath string) bool {
+	for _, p := range pkgPaths {
+		if p == pkgPath {
+			return true
+		}
+		if prefix := strings.TrimSuffix(p, "/..."); prefix != p && (pkgPath == prefix || strings.HasPrefix(pkgPath, prefix+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckError is returned by RunCheck if some packages need generating again.
+type CheckError struct {
+	// The path of the manifest.
+	Manifest string
+	// The packages to generate again, sorted.
+	Stale []StalePackage
+}
+
+// StalePackage is a package which needs generating again, and why.
+type StalePackage struct {
+	Generator string
+	Package   string
+	Reasons   []string
+}
+
+func (e *CheckError) Error() string {
+	return fmt.Sprintf("%d packages need generating again, according to %s", len(e.Stale), e.Manifest)
+}
+
+// Report writes the packages which need generating again to w, with why.
+func (e *CheckError) Report(w io.Writer) {
+	for _, s := range e.Stale {
+		fmt.Fprintf(w, "%s: %s: %s\n", s.Generator, s.Package, strings.Join(s.Reasons, ", "))
+	}
+}
+
+// RunCheck implements the "check" subcommand of the generators: it parses
+// argv, the arguments of command, into fs like ParseOptions, and compares
+// the manifest given by the "manifest" flag with the current Go files of
+// the packages, generated files and options, without parsing anything. If
+// packages of command need generating again, it returns a *CheckError.
+//
+// The options given on the command line when generating, e.g. by a
+// go:generate line, are only compared with those given to check.
+func RunCheck(fs *pflag.FlagSet, command string, argv []string) error {
+	opts, err := ParseOptions(fs, command, argv)
+	if err != nil {
+		return err
+	}
+	f := fs.Lookup("manifest")
+	if f == nil || f.Value.String() == "" {
+		return fmt.Errorf("no manifest of the generated files, see --manifest")
+	}
+	path := f.Value.String()
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	m, err := ReadManifest(path)
+	if err != nil {
+		return err
+	}
+	if len(m.Generators[command]) == 0 {
+		return fmt.Errorf("%s: no package generated by %s", path, command)
+	}
+	if stale := m.Check(command, opts); len(stale) != 0 {
+		return &CheckError{Manifest: path, Stale: stale}
+	}
+	log.Infof("Generated files of %d packages are up to date.", len(m.Generators[command]))
+	return nil
+}
+
+// Check returns the packages of the generator name which need generating
+// again: those whose Go files, generated files, generator version or the
+// files named by their options changed, and if opts is not nil, those whose
+// options differ, see RunCheck.
+func (m *Manifest) Check(name string, opts *Options) []StalePackage {
+	version := GeneratorVersion()
+	options, flags, _ := m.options(opts)
+	generated := m.generatedPaths(name)
+
+	var stale []StalePackage
+	for pkgPath, p := range m.Generators[name] {
+		var reasons []string
+		if p.GeneratorVersion != version {
+			reasons = append(reasons, fmt.Sprintf("generated by version %s, not %s", p.GeneratorVersion, version))
+		}
+		if opts != nil {
+			reasons = append(reasons, optionChanges(p, options, flags)...)
+		}
+		reasons = append(reasons, fileChanges("option file ", m, sortedKeys(p.OptionFiles), p.OptionFiles)...)
+		if p.Dir != "" {
+			files, err := packageFiles(m.abs(p.Dir), packageFileMatcher(p.BuildTag, p.IncludeTestFiles), generated)
+			if err != nil {
+				reasons = append(reasons, fmt.Sprintf("unable to read %s: %v", p.Dir, err))
+			}
+			for _, file := range sortedKeys(files) {
+				if _, ok := p.Files[file]; !ok {
+					reasons = append(reasons, file+" added")
+				}
+			}
+			for _, file := range sortedKeys(p.Files) {
+				switch sum, ok := files[file]; {
+				case !ok:
+					reasons = append(reasons, file+" removed")
+				case sum != p.Files[file]:
+					reasons = append(reasons, file+" changed")
+				}
+			}
+		}
+		reasons = append(reasons, fileChanges("generated ", m, sortedKeys(p.Generated), p.Generated)...)
+		if len(reasons) != 0 {
+			stale = append(stale, StalePackage{Generator: name, Package: pkgPath, Reasons: reasons})
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i].Package < stale[j].Package })
+	return stale
+}
+
+// optionChanges returns how the options recorded for p differ from the
+// current ones. Those given on the command line when generating are only
+// compared if they are given on the command line now.
+func optionChanges(p *ManifestPackage, options map[string]string, flags []string) []string {
+	given := func(flags []string, name string) bool {
+		for _, f := range flags {
+			if f == name {
+				return true
+			}
+		}
+		return false
+	}
+	var changes []string
+	for _, name := range sortedKeys(p.Options) {
+		value, ok := options[name]
+		switch {
+		case given(p.Flags, name) && !given(flags, name):
+		case !ok:
+			changes = append(changes, fmt.Sprintf("--%s no longer given", name))
+		case value != p.Options[name]:
+			changes = append(changes, fmt.Sprintf("--%s changed", name))
+		}
+	}
+	for _, name := range sortedKeys(options) {
+		if _, ok := p.Options[name]; !ok {
+			changes = append(changes, fmt.Sprintf("--%s given", name))
+		}
+	}
+	return changes
+}
+
+// fileChanges returns which of the files paths, relative to the manifest,
+// were removed or changed since sums.
+func fileChanges(kind string, m *Manifest, paths []string, sums map[string]string) []string {
+	var changes []string
+	for _, rel := range paths {
+		switch sum, err := hashFile(m.abs(rel)); {
+		case os.IsNotExist(err):
+			changes = append(changes, kind+rel+" removed")
+		case err != nil:
+			changes = append(changes, fmt.Sprintf("unable to read %s%s: %v", kind, rel, err))
+		case sum != sums[rel]:
+			changes = append(changes, kind+rel+" changed")
+		}
+	}
+	return changes
+}
+
+// sortedKeys returns the keys of m, sorted.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,132 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package args
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestManifest(t *testing.T) {
+	root := t.TempDir()
+	path := func(name string) string { return filepath.Join(root, filepath.FromSlash(name)) }
+	write := func(name, data string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path(name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path(name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a/a.go", "package a\n")
+	write("a/zz_generated.go", "// +build !ignore_autogenerated\n\npackage a\n")
+	write("b/b.go", "package b\n")
+	write("header.txt", "// header\n")
+	write("go.mod", "module example.com\n")
+
+	t.Setenv(OptionsFileEnv, "")
+	parse := func(argv ...string) *Options {
+		t.Helper()
+		g := Default()
+		fs := pflag.NewFlagSet("test-gen", pflag.ContinueOnError)
+		g.AddFlags(fs)
+		opts, err := ParseOptions(fs, "test-gen", argv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return opts
+	}
+
+	g := Default()
+	g.Manifest = path("manifest.json")
+	g.Options = parse("--go-header-file", path("header.txt"), "--output-file-base", "zz_generated", "--parallelism", "2")
+	inputs := map[string]string{"example.com/a": path("a"), "example.com/b": path("b")}
+	generated := map[string][]string{"example.com/a": {path("a/zz_generated.go")}, "example.com/b": nil}
+	if err := g.RecordManifest(inputs, generated); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(name string, opts *Options, want []StalePackage) {
+		t.Helper()
+		m, err := ReadManifest(path("manifest.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.Check("test-gen", opts); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", name, got, want)
+		}
+	}
+	check("up to date", parse(), nil)
+	check("ignored option", parse("--parallelism", "4"), nil)
+	check("option given to check", parse("--output-file-base", "other"), []StalePackage{
+		{Generator: "test-gen", Package: "example.com/a", Reasons: []string{"--output-file-base changed"}},
+		{Generator: "test-gen", Package: "example.com/b", Reasons: []string{"--output-file-base changed"}},
+	})
+
+	write("b/b_test.go", "package b\n")
+	write("a/zz_generated.go", "// +build !ignore_autogenerated\n\npackage a\n\ntype T struct{}\n")
+	write("b/c.go", "package b\n")
+	write("header.txt", "// new header\n")
+	check("changed", nil, []StalePackage{
+		{Generator: "test-gen", Package: "example.com/a", Reasons: []string{"option file header.txt changed", "generated a/zz_generated.go changed"}},
+		{Generator: "test-gen", Package: "example.com/b", Reasons: []string{"option file header.txt changed", "c.go added"}},
+	})
+
+	// Changed files are kept.
+	if err := g.CleanManifest([]string{"example.com/..."}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path("a/zz_generated.go")); err != nil {
+		t.Errorf("changed generated file removed: %v", err)
+	}
+
+	write("header.txt", "// header\n")
+	if err := g.RecordManifest(inputs, generated); err != nil {
+		t.Fatal(err)
+	}
+	check("generated again", nil, nil)
+
+	// Relative directories are resolved to the packages in them.
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := g.CleanManifest([]string{"./a"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path("a/zz_generated.go")); !os.IsNotExist(err) {
+		t.Errorf("generated file not removed: %v", err)
+	}
+	m, err := ReadManifest(path("manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Generators["test-gen"]["example.com/a"]; ok {
+		t.Errorf("cleaned package still in the manifest")
+	}
+	if _, ok := m.Generators["test-gen"]["example.com/b"]; !ok {
+		t.Errorf("package b dropped from the manifest")
+	}
+}
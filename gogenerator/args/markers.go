@@ -0,0 +1,79 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package args
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/vine-io/gogogen/gogenerator/types"
+)
+
+// MarkersCommand is the name of the subcommand which runs RunMarkers.
+const MarkersCommand = "markers"
+
+// RunMarkers implements the "markers" subcommand of the generators. Without
+// arguments it lists the markers of g.Markers; otherwise it checks the
+// comment markers of the packages in dirs, written like InputDirs, prints
+// the problems it finds and returns an error if there are any.
+func (g *GeneratorArgs) RunMarkers(dirs []string, out io.Writer) error {
+	if g.Markers == nil {
+		return fmt.Errorf("the generator declares no markers")
+	}
+	if len(dirs) == 0 {
+		return PrintMarkers(g.Markers, out)
+	}
+
+	lint := *g
+	lint.InputDirs = dirs
+	// Problems are printed below rather than logged by the builder.
+	lint.Markers = nil
+	b, err := lint.NewBuilder()
+	if err != nil {
+		return err
+	}
+	u, err := b.FindTypes()
+	if err != nil {
+		return err
+	}
+
+	n := 0
+	for _, pkgPath := range b.FindPackages() {
+		for _, p := range g.Markers.CheckPackage(u[pkgPath]) {
+			fmt.Fprintln(out, p)
+			n++
+		}
+	}
+	if n > 0 {
+		return fmt.Errorf("found %d marker problem(s)", n)
+	}
+	return nil
+}
+
+// PrintMarkers writes a table of the markers of r to out.
+func PrintMarkers(r *types.MarkerRegistry, out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "MARKER\tSCOPE\tVALUE\tGENERATOR\tDESCRIPTION")
+	for _, m := range r.Markers() {
+		value := m.Value.String()
+		if len(m.Allowed) > 0 {
+			value = strings.Join(m.Allowed, "|")
+		}
+		fmt.Fprintf(w, "+%s\t%s\t%s\t%s\t%s\n", m.Name, m.Scope, value, m.Generator, m.Description)
+	}
+	return w.Flush()
+}
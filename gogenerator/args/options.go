@@ -0,0 +1,314 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package args
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+// OptionsCommand is the name of the subcommand which prints the options of a
+// command, see Options.Print.
+const OptionsCommand = "options"
+
+// OptionsFileNames are the names of the options file, which is looked up in
+// the working directory and its parents.
+var OptionsFileNames = []string{".gogogen.yaml", ".gogogen.yml", ".gogogen.json"}
+
+// OptionsFileEnv names the environment variable giving the options file to
+// read instead of looking it up.
+const OptionsFileEnv = "GOGOGEN_OPTIONS_FILE"
+
+// OptionsEnvPrefix prefixes the environment variables which set the flags:
+// GOGOGEN_GO_HEADER_FILE sets --go-header-file, for instance.
+const OptionsEnvPrefix = "GOGOGEN_"
+
+// pathAnnotation marks the flags holding paths, see MarkPathFlags.
+const pathAnnotation = "gogogen_path"
+
+// MarkPathFlags marks the flags of fs which hold paths, or lists of paths.
+// Their relative values in an options file are relative to its directory.
+func MarkPathFlags(fs *pflag.FlagSet, names ...string) {
+	for _, name := range names {
+		if err := fs.SetAnnotation(name, pathAnnotation, []string{"true"}); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Options tells where the values of the flags of a command come from.
+type Options struct {
+	// The path of the options file read, if any.
+	File string
+	// The source of the value of each flag which isn't the default: "flag",
+	// the environment variable, or the options file.
+	Sources map[string]string
+	// The command the options are for.
+	Command string
+
+	// The values of the flags, as recorded in the manifest, see
+	// snapshotFlags.
+	flags map[string]flagSnapshot
+}
+
+// ParseOptions parses the arguments of command, argv, into fs like
+// fs.Parse. The flags which aren't given are then set from their
+// environment variable, see OptionsEnvPrefix, or else from the options file.
+//
+// The options file is a YAML, or JSON, map from flag names to values, which
+// are either scalars or lists. The flags at the top level apply to every
+// command which has them; those under the name of a command, e.g.
+// "goproto-gen", only apply to it and take precedence:
+//
+//	go-header-file: hack/boilerplate.go.txt
+//	goproto-gen:
+//	  proto-import: [third_party]
+func ParseOptions(fs *pflag.FlagSet, command string, argv []string) (*Options, error) {
+	if err := fs.Parse(argv); err != nil {
+		return nil, err
+	}
+	opts := &Options{Sources: map[string]string{}}
+	fs.Visit(func(f *pflag.Flag) {
+		opts.Sources[f.Name] = "flag"
+	})
+
+	var err error
+	if opts.File, err = optionsFile(); err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	if opts.File != "" {
+		top, section, err := loadOptionsFile(opts.File, command)
+		if err != nil {
+			return nil, err
+		}
+		// The top level options of the other commands are ignored, while
+		// those of the section of command must be its flags.
+		for name, v := range top {
+			if fs.Lookup(name) != nil {
+				values[name] = v
+			}
+		}
+		for name, v := range section {
+			if fs.Lookup(name) == nil {
+				return nil, fmt.Errorf("%s: unknown option %q of %s", opts.File, name, command)
+			}
+			values[name] = v
+		}
+	}
+
+	fs.VisitAll(func(f *pflag.Flag) {
+		if err != nil || opts.Sources[f.Name] != "" {
+			return
+		}
+		env := OptionsEnvPrefix + strings.ToUpper(strings.Replace(f.Name, "-", "_", -1))
+		if value, ok := os.LookupEnv(env); ok {
+			if err = fs.Set(f.Name, value); err != nil {
+				err = fmt.Errorf("$%s: %v", env, err)
+			}
+			opts.Sources[f.Name] = "$" + env
+			return
+		}
+		v, ok := values[f.Name]
+		if !ok {
+			return
+		}
+		var value string
+		if value, err = optionValue(v, opts.File, f.Annotations[pathAnnotation] != nil); err == nil {
+			err = fs.Set(f.Name, value)
+		}
+		if err != nil {
+			err = fmt.Errorf("%s: option %q: %v", opts.File, f.Name, err)
+		}
+		opts.Sources[f.Name] = opts.File
+	})
+	if err != nil {
+		return nil, err
+	}
+	opts.Command = command
+	opts.flags = snapshotFlags(fs)
+	return opts, nil
+}
+
+// RunOptions implements the "options" subcommand of the generators: it
+// parses argv, the arguments of command, into fs like ParseOptions and
+// prints the resulting options to out.
+func RunOptions(fs *pflag.FlagSet, command string, argv []string, out io.Writer) error {
+	opts, err := ParseOptions(fs, command, argv)
+	if err != nil {
+		return err
+	}
+	return opts.Print(fs, out)
+}
+
+// optionsFile returns the path of the options file, or "" if there is none.
+func optionsFile() (string, error) {
+	if path, ok := os.LookupEnv(OptionsFileEnv); ok {
+		return path, nil
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return FindOptionsFile(dir), nil
+}
+
+// FindOptionsFile returns the path of the options file in dir or its
+// closest parent, or "" if there is none.
+func FindOptionsFile(dir string) string {
+	for {
+		for _, name := range OptionsFileNames {
+			path := filepath.Join(dir, name)
+			if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+				return path
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// loadOptionsFile returns the top level options of the options file at path,
+// and those of the section of command.
+func loadOptionsFile(path, command string) (top, section map[string]interface{}, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	file := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	top, section = map[string]interface{}{}, map[string]interface{}{}
+	for name, v := range file {
+		m, ok := v.(map[interface{}]interface{})
+		switch {
+		case !ok:
+			top[name] = v
+		case name == command:
+			for k, v := range m {
+				section[fmt.Sprint(k)] = v
+			}
+		}
+	}
+	return top, section, nil
+}
+
+// optionValue returns the value to set a flag to from its value v in the
+// options file at path. The elements of lists are separated by commas.
+// Relative paths are made relative to the directory of the file, except for
+// "-" which stands for the standard output.
+func optionValue(v interface{}, path string, isPath bool) (string, error) {
+	scalar := func(v interface{}) (string, error) {
+		switch v.(type) {
+		case nil:
+			return "", nil
+		case []interface{}, map[interface{}]interface{}:
+			return "", fmt.Errorf("expected a value or a list of values")
+		}
+		s := fmt.Sprint(v)
+		if isPath && s != "" && s != "-" && !filepath.IsAbs(s) {
+			s = filepath.Join(filepath.Dir(path), s)
+		}
+		return s, nil
+	}
+
+	list, ok := v.([]interface{})
+	if !ok {
+		return scalar(v)
+	}
+	values := make([]string, 0, len(list))
+	for _, e := range list {
+		s, err := scalar(e)
+		if err != nil {
+			return "", err
+		}
+		values = append(values, s)
+	}
+	return strings.Join(values, ","), nil
+}
+
+// Print writes the values of the flags of fs as an options file to out, with
+// where they come from as comments.
+func (o *Options) Print(fs *pflag.FlagSet, out io.Writer) error {
+	if o.File != "" {
+		fmt.Fprintf(out, "# options file: %s\n", o.File)
+	} else {
+		fmt.Fprintln(out, "# no options file")
+	}
+
+	var lines [][2]string
+	width := 0
+	fs.VisitAll(func(f *pflag.Flag) {
+		line := f.Name + ": " + printedValue(fs, f)
+		if len(line) > width {
+			width = len(line)
+		}
+		source := o.Sources[f.Name]
+		if source == "" {
+			source = "default"
+		}
+		lines = append(lines, [2]string{line, source})
+	})
+	sort.Slice(lines, func(i, j int) bool { return lines[i][0] < lines[j][0] })
+	for _, l := range lines {
+		if _, err := fmt.Fprintf(out, "%-*s  # %s\n", width, l[0], l[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printedValue returns the value of f as YAML.
+func printedValue(fs *pflag.FlagSet, f *pflag.Flag) string {
+	var v interface{} = f.Value.String()
+	switch f.Value.Type() {
+	case "bool":
+		v, _ = fs.GetBool(f.Name)
+	case "int":
+		v, _ = fs.GetInt(f.Name)
+	case "stringSlice":
+		list, _ := fs.GetStringSlice(f.Name)
+		values := make([]string, 0, len(list))
+		for _, e := range list {
+			values = append(values, printedValue(fs, &pflag.Flag{Value: stringValue(e)}))
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	}
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return f.Value.String()
+	}
+	return strings.TrimSuffix(string(data), "\n")
+}
+
+// stringValue is a string flag value.
+type stringValue string
+
+func (s stringValue) String() string   { return string(s) }
+func (s stringValue) Set(string) error { return nil }
+func (s stringValue) Type() string     { return "string" }
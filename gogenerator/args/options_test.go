@@ -0,0 +1,98 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package args
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestParseOptions(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".gogogen.yaml")
+	file := `go-header-file: hack/boilerplate.go.txt
+output-package: example.com/top
+dry-run: true
+output-archive: "-"
+unknown-elsewhere: 1
+test-gen:
+  output-package: example.com/section
+  input-dirs: [example.com/a, example.com/b]
+other-gen:
+  unknown: 1
+`
+	if err := ioutil.WriteFile(path, []byte(file), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(OptionsFileEnv, path)
+	t.Setenv(OptionsEnvPrefix+"PARALLELISM", "3")
+	t.Setenv(OptionsEnvPrefix+"DRY_RUN", "false")
+
+	g := Default().WithoutDefaultFlagParsing()
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	g.AddFlags(fs)
+	opts, err := ParseOptions(fs, "test-gen", []string{"test-gen", "-O", "zz_generated"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := filepath.Join(dir, "hack/boilerplate.go.txt"); g.GoHeaderFilePath != want {
+		t.Errorf("expected the header %s relative to the file, got %s", want, g.GoHeaderFilePath)
+	}
+	if g.OutputArchive != "-" {
+		t.Errorf("expected the standard output to be kept as -, got %s", g.OutputArchive)
+	}
+	if g.OutputPackagePath != "example.com/section" {
+		t.Errorf("expected the output package of the section, got %s", g.OutputPackagePath)
+	}
+	if want := []string{"example.com/a", "example.com/b"}; !reflect.DeepEqual(g.InputDirs, want) {
+		t.Errorf("expected the input dirs %v, got %v", want, g.InputDirs)
+	}
+	if g.Parallelism != 3 || g.DryRun {
+		t.Errorf("expected the environment to override the file, got parallelism %d and dry run %v", g.Parallelism, g.DryRun)
+	}
+	if g.OutputFileBaseName != "zz_generated" {
+		t.Errorf("expected the flag to be kept, got %s", g.OutputFileBaseName)
+	}
+
+	var out bytes.Buffer
+	if err := opts.Print(fs, &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"# options file: " + path,
+		"input-dirs: [example.com/a, example.com/b]",
+		"output-file-base: zz_generated",
+		"# $" + OptionsEnvPrefix + "PARALLELISM",
+		"verify-only: false",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("expected %q in:\n%s", line, out.String())
+		}
+	}
+
+	// The options of the section of a command must be its flags.
+	fs = pflag.NewFlagSet("other", pflag.ContinueOnError)
+	Default().WithoutDefaultFlagParsing().AddFlags(fs)
+	if _, err := ParseOptions(fs, "other-gen", nil); err == nil || !strings.Contains(err.Error(), `unknown option "unknown"`) {
+		t.Errorf("expected an unknown option error, got %v", err)
+	}
+}
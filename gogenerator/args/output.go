@@ -0,0 +1,98 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package args
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/vine-io/gogogen/gogenerator/generator"
+	"github.com/vine-io/gogogen/util/log"
+)
+
+// NewOutputFS returns the filesystem the generated files are written to, as
+// picked by DryRun and OutputArchive, or nil if they are written to disk.
+//
+// Archive entries are named relative to OutputBase, or to the current
+// directory if it is empty. The format of the archive follows its extension.
+// When the listing of a dry run or an archive goes to stdout, the logs are
+// moved to stderr.
+func (g *GeneratorArgs) NewOutputFS() (generator.OutputFS, error) {
+	if g.DryRun && g.OutputArchive != "" {
+		return nil, fmt.Errorf("--dry-run and --output-archive are mutually exclusive")
+	}
+	if g.DryRun {
+		log.DefaultOut(os.Stderr)
+		return &generator.DryRunFS{W: os.Stdout}, nil
+	}
+	if g.OutputArchive == "" {
+		return nil, nil
+	}
+
+	root := g.OutputBase
+	if root == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		root = wd
+	}
+	if g.OutputArchive == "-" {
+		log.DefaultOut(os.Stderr)
+		return generator.NewTarFS(root, os.Stdout, false), nil
+	}
+
+	name := g.OutputArchive
+	var newFS func(w io.Writer) *generator.ArchiveFS
+	switch {
+	case strings.HasSuffix(name, ".tar"):
+		newFS = func(w io.Writer) *generator.ArchiveFS { return generator.NewTarFS(root, w, false) }
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		newFS = func(w io.Writer) *generator.ArchiveFS { return generator.NewTarFS(root, w, true) }
+	case strings.HasSuffix(name, ".zip"):
+		newFS = func(w io.Writer) *generator.ArchiveFS { return generator.NewZipFS(root, w) }
+	default:
+		return nil, fmt.Errorf("unknown archive format of %q, expected .tar, .tar.gz, .tgz or .zip", name)
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return &fileArchive{ArchiveFS: newFS(f), f: f}, nil
+}
+
+// CloseOutputFS flushes an output returned by NewOutputFS.
+func CloseOutputFS(out generator.OutputFS) error {
+	if c, ok := out.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// fileArchive is an archive written to a file.
+type fileArchive struct {
+	*generator.ArchiveFS
+	f *os.File
+}
+
+func (a *fileArchive) Close() error {
+	err := a.ArchiveFS.Close()
+	if err2 := a.f.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
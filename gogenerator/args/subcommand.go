@@ -0,0 +1,43 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package args
+
+import (
+	"io"
+
+	"github.com/spf13/pflag"
+)
+
+// RunSubcommand runs the subcommand of the generator command named by
+// argv[0], argv being the arguments of command: DumpCommand and
+// MarkersCommand on g, and OptionsCommand and CheckCommand with the flags fs
+// of command. It returns whether argv starts with one of them, and its
+// error; the failures of CheckCommand are told apart by VerifyExitCode.
+func (g *GeneratorArgs) RunSubcommand(fs *pflag.FlagSet, command string, argv []string, out io.Writer) (handled bool, err error) {
+	if len(argv) == 0 {
+		return false, nil
+	}
+	switch argv[0] {
+	case DumpCommand:
+		return true, g.RunDump(argv[1:], out)
+	case MarkersCommand:
+		return true, g.RunMarkers(argv[1:], out)
+	case OptionsCommand:
+		return true, RunOptions(fs, command, argv[1:], out)
+	case CheckCommand:
+		return true, RunCheck(fs, command, argv[1:])
+	}
+	return false, nil
+}
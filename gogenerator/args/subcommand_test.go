@@ -0,0 +1,54 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package args
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestRunSubcommand(t *testing.T) {
+	t.Setenv(OptionsFileEnv, "")
+	for _, argv := range [][]string{nil, {"--output-package", "example.com/out"}, {"example.com/in"}} {
+		g := Default().WithoutDefaultFlagParsing()
+		fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		g.AddFlags(fs)
+		if handled, err := g.RunSubcommand(fs, "test-gen", argv, &bytes.Buffer{}); handled || err != nil {
+			t.Errorf("%q: got handled %v, %v, want the generator to run", argv, handled, err)
+		}
+	}
+
+	g := Default().WithoutDefaultFlagParsing()
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	g.AddFlags(fs)
+	out := &bytes.Buffer{}
+	handled, err := g.RunSubcommand(fs, "test-gen", []string{OptionsCommand, "--output-package", "example.com/out"}, out)
+	if !handled || err != nil {
+		t.Fatalf("got handled %v, %v, want the options to be printed", handled, err)
+	}
+	if !strings.Contains(out.String(), "output-package: example.com/out") {
+		t.Errorf("the options lack the output package:\n%s", out.String())
+	}
+
+	// The markers need a registry, and check a manifest.
+	for _, command := range []string{MarkersCommand, CheckCommand} {
+		if handled, err := g.RunSubcommand(fs, "test-gen", []string{command}, &bytes.Buffer{}); !handled || err == nil {
+			t.Errorf("%s: got handled %v, %v, want an error", command, handled, err)
+		}
+	}
+}
@@ -0,0 +1,2 @@
+// +gogo:deepcopy-gen=package
+package api
@@ -0,0 +1,35 @@
+package api
+
+import "time"
+
+// +gogo:genproto=true
+
+// Resource is a resource.
+// +gogo:gengorm=true
+type Resource struct {
+	// +primaryKey
+	Name    string `json:"name"`
+	Created time.Time
+	Labels  map[string]string
+	Owner   *Owner
+	Phase   Phase
+	Meta
+}
+
+type Owner struct {
+	ID int64
+}
+
+type Meta struct {
+	UID string
+}
+
+type Phase string
+
+const (
+	// Pending is the first phase.
+	Pending Phase = "Pending"
+	Running Phase = "Running"
+)
+
+func (r *Resource) GetName() string { return r.Name }
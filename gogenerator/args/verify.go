@@ -0,0 +1,44 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package args
+
+import (
+	"errors"
+	"io"
+
+	"github.com/vine-io/gogogen/gogenerator/generator"
+)
+
+// VerifyFailedCode is the exit code of the generators when --verify-only, or
+// the check subcommand, finds generated files which aren't up to date, as
+// opposed to 1 for other errors.
+const VerifyFailedCode = 3
+
+// VerifyExitCode prints the report of err to w and returns VerifyFailedCode
+// and true if err is, or wraps, a *generator.VerifyError or a *CheckError.
+// Otherwise it returns false, and the caller reports err as usual.
+func VerifyExitCode(err error, w io.Writer) (int, bool) {
+	var verr *generator.VerifyError
+	if errors.As(err, &verr) {
+		verr.Report(w)
+		return VerifyFailedCode, true
+	}
+	var cerr *CheckError
+	if errors.As(err, &cerr) {
+		cerr.Report(w)
+		return VerifyFailedCode, true
+	}
+	return 0, false
+}
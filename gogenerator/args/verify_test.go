@@ -0,0 +1,46 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package args
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/vine-io/gogogen/gogenerator/generator"
+)
+
+func TestVerifyExitCode(t *testing.T) {
+	stale := &CheckError{Manifest: "manifest", Stale: []StalePackage{{Generator: "deepcopy-gen", Package: "example.com/a", Reasons: []string{"a.go changed"}}}}
+	for _, tc := range []struct {
+		err    error
+		ok     bool
+		report string
+	}{
+		{err: fmt.Errorf("executing: %w", stale), ok: true, report: "deepcopy-gen: example.com/a: a.go changed\n"},
+		{err: &generator.VerifyError{}, ok: true, report: "PACKAGE  FILE  STATUS\n0 of 0 generated files are out of date\n"},
+		{err: errors.New("failed"), ok: false},
+	} {
+		out := &bytes.Buffer{}
+		code, ok := VerifyExitCode(tc.err, out)
+		if ok != tc.ok || (ok && code != VerifyFailedCode) || (!ok && code != 0) {
+			t.Errorf("%v: got %d, %v, want ok %v", tc.err, code, ok, tc.ok)
+		}
+		if out.String() != tc.report {
+			t.Errorf("%v: got report %q, want %q", tc.err, out.String(), tc.report)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package args
+
+import (
+	"crypto/sha256"
+	"go/build"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vine-io/gogogen/gogenerator/parser"
+	"github.com/vine-io/gogogen/util/log"
+)
+
+// DefaultWatchInterval is how often the files are polled in watch mode.
+const DefaultWatchInterval = time.Second
+
+// Watcher polls the Go files of packages for changes, see
+// GeneratorArgs.Watch.
+type Watcher struct {
+	// The directories of the packages watched, by import path.
+	dirs map[string]string
+	// Tells whether a Go file belongs to its package.
+	match func(dir, name string) bool
+	// The Go files of the directories, by path.
+	files map[string]watchedFile
+}
+
+// watchedFile is the state of a file when it was last polled.
+type watchedFile struct {
+	pkgPath string
+	modTime time.Time
+	size    int64
+	sum     [sha256.Size]byte
+	// Whether the file belongs to its package, generated files don't.
+	matched bool
+}
+
+// NewWatcher returns a watcher of the Go files of the packages in dirs, by
+// import path. The files match tells not to belong to their package are
+// ignored, all of them are watched if it is nil.
+func NewWatcher(dirs map[string]string, match func(dir, name string) bool) *Watcher {
+	w := &Watcher{dirs: dirs, match: match, files: map[string]watchedFile{}}
+	w.Changed()
+	return w
+}
+
+// NewWatcher returns a watcher of the Go files of the packages in dirs, by
+// import path. Like the parser, it ignores the files generated with
+// GeneratedBuildTag and, unless IncludeTestFile is set, the tests.
+func (g *GeneratorArgs) NewWatcher(dirs map[string]string) *Watcher {
+	return NewWatcher(dirs, packageFileMatcher(g.GeneratedBuildTag, g.IncludeTestFile))
+}
+
+// packageFileMatcher returns whether the Go files belong to their package as
+// the parser sees it: those generated with buildTag don't, nor the tests
+// unless includeTests is set.
+func packageFileMatcher(buildTag string, includeTests bool) func(dir, name string) bool {
+	ctxt := build.Default
+	ctxt.CgoEnabled = false
+	ctxt.BuildTags = []string{buildTag}
+	return func(dir, name string) bool {
+		if !includeTests && strings.HasSuffix(name, "_test.go") {
+			return false
+		}
+		ok, err := ctxt.MatchFile(dir, name)
+		return err == nil && ok
+	}
+}
+
+// Changed returns the packages whose files were added, removed or changed
+// since the last call, sorted. The files whose contents are the same are not
+// changed, even if they were written again.
+func (w *Watcher) Changed() []string {
+	changed := map[string]bool{}
+	seen := map[string]bool{}
+	for pkgPath, dir := range w.dirs {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			log.Debugf("watch %s: %v", dir, err)
+		}
+		for _, fi := range infos {
+			if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".go") {
+				continue
+			}
+			path := filepath.Join(dir, fi.Name())
+			prev, known := w.files[path]
+			if known && prev.modTime.Equal(fi.ModTime()) && prev.size == fi.Size() {
+				seen[path] = true
+				continue
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				// It is being replaced, or was removed.
+				continue
+			}
+			seen[path] = true
+			f := watchedFile{
+				pkgPath: pkgPath,
+				modTime: fi.ModTime(),
+				size:    fi.Size(),
+				sum:     sha256.Sum256(data),
+				matched: w.match == nil || w.match(dir, fi.Name()),
+			}
+			if f.matched != prev.matched || (f.matched && f.sum != prev.sum) {
+				changed[pkgPath] = true
+			}
+			w.files[path] = f
+		}
+	}
+	for path, f := range w.files {
+		if !seen[path] {
+			delete(w.files, path)
+			if f.matched {
+				changed[f.pkgPath] = true
+			}
+		}
+	}
+
+	pkgPaths := make([]string, 0, len(changed))
+	for pkgPath := range changed {
+		pkgPaths = append(pkgPaths, pkgPath)
+	}
+	sort.Strings(pkgPaths)
+	return pkgPaths
+}
+
+// Run calls run with the packages whose files changed, polling them every
+// interval, each time some do. The errors of run are logged, and the
+// packages of a failed call are passed again to the next one. It never
+// returns.
+func (w *Watcher) Run(interval time.Duration, run func(pkgPaths []string) error) {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	log.Infof("Watching %d packages for changes every %v", len(w.dirs), interval)
+	var failed []string
+	for {
+		time.Sleep(interval)
+		changed := w.Changed()
+		if len(changed) == 0 {
+			continue
+		}
+		log.Infof("Changed packages: %s", strings.Join(changed, ", "))
+		pkgPaths := mergePaths(failed, changed)
+		failed = nil
+		if err := run(pkgPaths); err != nil {
+			log.Errorf("Error: %v", err)
+			failed = pkgPaths
+			continue
+		}
+		log.Infof("Completed successfully, watching for changes.")
+	}
+}
+
+// mergePaths returns the sorted union of a and b.
+func mergePaths(a, b []string) []string {
+	set := map[string]bool{}
+	for _, p := range append(append([]string{}, a...), b...) {
+		set[p] = true
+	}
+	merged := make([]string, 0, len(set))
+	for p := range set {
+		merged = append(merged, p)
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+// PackageDirs returns the directories of the packages pkgPaths, by import
+// path, e.g. to watch them.
+func (g *GeneratorArgs) PackageDirs(pkgPaths []string) (map[string]string, error) {
+	b := parser.New()
+	if g.GoModules {
+		b = parser.NewWithModules("")
+	}
+	dirs := map[string]string{}
+	for _, pkgPath := range pkgPaths {
+		dir, err := b.PackageDir(pkgPath)
+		if err != nil {
+			return nil, err
+		}
+		dirs[pkgPath] = dir
+	}
+	return dirs, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package args
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatcherChanged(t *testing.T) {
+	root := t.TempDir()
+	dirs := map[string]string{
+		"example.com/a": filepath.Join(root, "a"),
+		"example.com/b": filepath.Join(root, "b"),
+	}
+	write := func(name, data string) {
+		t.Helper()
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		// Make the change visible even if the clock is coarse.
+		later := time.Now().Add(time.Duration(len(data)+1) * time.Second)
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a/a.go", "package a\n")
+	write("b/b.go", "package b\n")
+	write("b/zz_generated.go", "// +build !ignore_autogenerated\n\npackage b\n")
+
+	g := Default()
+	w := g.NewWatcher(dirs)
+	check := func(name string, want []string) {
+		t.Helper()
+		if got := w.Changed(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", name, got, want)
+		}
+	}
+
+	check("unchanged", []string{})
+	write("a/a.go", "package a\n")
+	check("same contents", []string{})
+	write("a/a.go", "package a\n\ntype T struct{}\n")
+	check("edited", []string{"example.com/a"})
+	write("b/zz_generated.go", "// +build !ignore_autogenerated\n\npackage b\n\ntype T struct{}\n")
+	check("generated", []string{})
+	write("b/b_test.go", "package b\n")
+	check("test", []string{})
+	write("b/c.go", "package b\n")
+	check("added", []string{"example.com/b"})
+	if err := os.Remove(filepath.Join(root, "a", "a.go")); err != nil {
+		t.Fatal(err)
+	}
+	check("removed", []string{"example.com/a"})
+}
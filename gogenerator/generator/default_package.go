@@ -39,6 +39,10 @@ type DefaultPackage struct {
 
 	// Optional; filters the types exposed to the generators.
 	FilterFunc func(*Context, *types.Type) bool
+	// Optional; the import paths of the packages the types exposed to the
+	// generators come from. Unlike FilterFunc, it spares looking at the
+	// types of the other packages.
+	TypesFrom []string
 }
 
 func (d *DefaultPackage) Name() string       { return d.PackageName }
@@ -52,6 +56,10 @@ func (d *DefaultPackage) Filter(c *Context, t *types.Type) bool {
 	return true
 }
 
+func (d *DefaultPackage) TypePackages() []string {
+	return d.TypesFrom
+}
+
 func (d *DefaultPackage) Generators(c *Context) []Generator {
 	if d.GeneratorFunc != nil {
 		return d.GeneratorFunc(c)
@@ -67,5 +75,5 @@ func (d *DefaultPackage) Header(filename string) []byte {
 }
 
 var (
-	_ = Package(&DefaultPackage{})
+	_ = ScopedPackage(&DefaultPackage{})
 )
@@ -0,0 +1,139 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"strings"
+	"sync"
+
+	"github.com/vine-io/gogogen/util/log"
+)
+
+// Diagnostic is an error about a position in the parsed sources, e.g. the
+// Position of a types.Type or types.Member. It prints as "file:line:col: msg",
+// the format editors and IDEs know how to jump to.
+type Diagnostic struct {
+	Position token.Position
+	Err      error
+}
+
+// Errorf returns a Diagnostic at pos, formatting the message like fmt.Errorf.
+func Errorf(pos token.Position, format string, args ...interface{}) error {
+	return &Diagnostic{Position: pos, Err: fmt.Errorf(format, args...)}
+}
+
+// Wrapf returns a Diagnostic at pos which prefixes err with the formatted
+// message. If err is itself a Diagnostic with a known position, that position
+// is kept instead of pos since it points closer to the problem.
+func Wrapf(pos token.Position, err error, format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	var d *Diagnostic
+	if errors.As(err, &d) && d.Position.IsValid() {
+		return &Diagnostic{Position: d.Position, Err: fmt.Errorf("%s: %w", msg, d.Err)}
+	}
+	return &Diagnostic{Position: pos, Err: fmt.Errorf("%s: %w", msg, err)}
+}
+
+func (d *Diagnostic) Error() string {
+	if !d.Position.IsValid() {
+		return d.Err.Error()
+	}
+	return d.Position.String() + ": " + d.Err.Error()
+}
+
+func (d *Diagnostic) Unwrap() error {
+	return d.Err
+}
+
+// ErrorList is a list of errors, e.g. the problems reported to Diagnostics.
+// It prints one error per line.
+type ErrorList []error
+
+func (l ErrorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, err := range l {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Diagnostics collects the problems found while generating, e.g. by the
+// Filter methods or the name systems, which can't return errors. They are
+// reported all at once instead of stopping at the first one. It is safe for
+// concurrent use; a nil *Diagnostics logs the problems as errors.
+type Diagnostics struct {
+	// The diagnostics the problems are reported to too, if any.
+	parent *Diagnostics
+
+	mu   sync.Mutex
+	errs []error
+	// The messages of errs, as the same problem may be found several times.
+	seen map[string]bool
+}
+
+// Report records the problem err, unless it was already reported.
+func (d *Diagnostics) Report(err error) {
+	if d == nil {
+		log.Errorf("%v", err)
+		return
+	}
+	d.mu.Lock()
+	if d.seen == nil {
+		d.seen = map[string]bool{}
+	}
+	msg := err.Error()
+	dup := d.seen[msg]
+	if !dup {
+		d.seen[msg] = true
+		d.errs = append(d.errs, err)
+	}
+	d.mu.Unlock()
+	if !dup && d.parent != nil {
+		d.parent.Report(err)
+	}
+}
+
+// Errorf reports a Diagnostic at pos, see Errorf.
+func (d *Diagnostics) Errorf(pos token.Position, format string, args ...interface{}) {
+	d.Report(Errorf(pos, format, args...))
+}
+
+// Errors returns the problems reported so far, in order.
+func (d *Diagnostics) Errors() []error {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]error{}, d.errs...)
+}
+
+// Err returns the problems reported so far as an ErrorList, or nil if there
+// are none.
+func (d *Diagnostics) Err() error {
+	if errs := d.Errors(); len(errs) != 0 {
+		return ErrorList(errs)
+	}
+	return nil
+}
+
+// scope returns new diagnostics whose problems are reported to d too, e.g.
+// to tell those of a package.
+func (d *Diagnostics) scope() *Diagnostics {
+	return &Diagnostics{parent: d}
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"errors"
+	"go/token"
+	"io"
+	"testing"
+)
+
+func TestDiagnostic(t *testing.T) {
+	typePos := token.Position{Filename: "api/types.go", Line: 3, Column: 6}
+	memberPos := token.Position{Filename: "api/types.go", Line: 4, Column: 2}
+
+	err := Errorf(memberPos, "unsupported type %s", "chan int")
+	if got, want := err.Error(), "api/types.go:4:2: unsupported type chan int"; got != want {
+		t.Errorf("Errorf: got %q, want %q", got, want)
+	}
+	if got, want := Errorf(token.Position{}, "no position").Error(), "no position"; got != want {
+		t.Errorf("Errorf without a position: got %q, want %q", got, want)
+	}
+
+	// The position of the member is closer to the problem than that of its
+	// type.
+	wrapped := Wrapf(typePos, err, "field %s", "Ch")
+	if got, want := wrapped.Error(), "api/types.go:4:2: field Ch: unsupported type chan int"; got != want {
+		t.Errorf("Wrapf of a diagnostic: got %q, want %q", got, want)
+	}
+	wrapped = Wrapf(typePos, io.EOF, "reading")
+	if got, want := wrapped.Error(), "api/types.go:3:6: reading: EOF"; got != want {
+		t.Errorf("Wrapf of an error: got %q, want %q", got, want)
+	}
+	if !errors.Is(wrapped, io.EOF) {
+		t.Errorf("Wrapf doesn't wrap the error")
+	}
+
+	var d Diagnostics
+	pkg := d.scope()
+	pkg.Report(err)
+	pkg.Errorf(memberPos, "unsupported type %s", "chan int")
+	d.Errorf(typePos, "other")
+	if got := len(pkg.Errors()); got != 1 {
+		t.Errorf("got %d problems in the package, want the duplicate to be dropped", got)
+	}
+	want := "api/types.go:4:2: unsupported type chan int\napi/types.go:3:6: other"
+	if err := d.Err(); err == nil || err.Error() != want {
+		t.Errorf("Err: got %v, want %q", err, want)
+	}
+	if err := (&Diagnostics{}).Err(); err != nil {
+		t.Errorf("Err without problems: got %v", err)
+	}
+}
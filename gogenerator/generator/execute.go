@@ -16,12 +16,15 @@ package generator
 
 import (
 	"bytes"
+	goerrors "errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/vine-io/gogogen/gogenerator/namer"
 	"github.com/vine-io/gogogen/gogenerator/types"
@@ -42,16 +45,73 @@ func errs2strings(errors []error) []string {
 // should be a physical path on disk, not an import path. e.g.:
 // /path/to/home/path/to/gopath/src
 // Each package has its import path already, this will be appended to 'outDir'.
+//
+// Up to c.Parallelism packages are executed at the same time. Errors and
+// verify results are reported in the order of the packages either way.
+//
+// The problems reported to c.Diagnostics before, e.g. while listing the
+// packages, fail it too, after the packages are executed.
+//
+// In verify mode, if no other error occurs but some files on disk aren't up to
+// date, the returned error is a *VerifyError. Otherwise, if c.Owner is set,
+// the files it owns which are no longer generated are removed.
 func (c *Context) ExecutePackages(outDir string, packages Packages) error {
-	var errors []error
-	for _, p := range packages {
-		if err := c.ExecutePackage(outDir, p); err != nil {
-			errors = append(errors, err)
+	reported := c.Diagnostics.Errors()
+	c.indexOrder()
+	errs := make([]error, len(packages))
+	generated := make([][]string, len(packages))
+	verified := make([][]VerifyResult, len(packages))
+	workers := c.Parallelism
+	if workers > len(packages) {
+		workers = len(packages)
+	}
+	if workers < 2 {
+		for i, p := range packages {
+			generated[i], verified[i], errs[i] = c.executePackage(outDir, p)
+		}
+	} else {
+		if c.lock == nil {
+			c.lock = &sync.Mutex{}
+		}
+		next := make(chan int)
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range next {
+					generated[i], verified[i], errs[i] = c.executePackage(outDir, packages[i])
+				}
+			}()
 		}
+		for i := range packages {
+			next <- i
+		}
+		close(next)
+		wg.Wait()
 	}
+
+	var results []VerifyResult
+	errors := reported
+	for i := range packages {
+		c.addGenerated(packages[i].Path(), generated[i])
+		results = append(results, verified[i]...)
+		if errs[i] != nil {
+			errors = append(errors, errs[i])
+		}
+	}
+	c.verified = append(c.verified, results...)
 	if len(errors) > 0 {
 		return fmt.Errorf("some packages had errors:\n%v\n", strings.Join(errs2strings(errors), "\n"))
 	}
+	if c.Verify {
+		results = append(results, c.extraFiles(outDir, results)...)
+		if verr := (&VerifyError{Results: results}); verr.Stale() {
+			return verr
+		}
+	} else if c.Owner != "" {
+		return c.removeStale(outDir)
+	}
 	return nil
 }
 
@@ -60,65 +120,65 @@ type DefaultFileType struct {
 	Assemble func(io.Writer, *File)
 }
 
+// AssembleFile writes f to pathname, unless the file there is already up to
+// date, in which case it is left untouched so that its modification time is
+// kept.
 func (ft DefaultFileType) AssembleFile(f *File, pathname string) error {
 	log.Infof("Assembling file %q", pathname)
-	destFile, err := os.Create(pathname)
-	if err != nil {
+	data, err := ft.RenderFile(f)
+	if data == nil {
 		return err
 	}
+	if err != nil {
+		err = fmt.Errorf("unable to format file %q (%v)", pathname, err)
+	} else if existing, rerr := ioutil.ReadFile(pathname); rerr == nil && bytes.Equal(existing, data) {
+		log.Infof("File %q is up to date", pathname)
+		return nil
+	}
+
+	destFile, cerr := os.Create(pathname)
+	if cerr != nil {
+		return cerr
+	}
 	defer destFile.Close()
+	// If formatting failed, write the file anyway, so they can see what's
+	// going wrong and fix the generator.
+	if _, err2 := destFile.Write(data); err2 != nil && err == nil {
+		return err2
+	}
+	return err
+}
 
+// RenderFile assembles and formats f. If formatting fails, it returns the
+// unformatted file along with the error.
+func (ft DefaultFileType) RenderFile(f *File) ([]byte, error) {
 	b := &bytes.Buffer{}
 	et := NewErrorTracker(b)
 	ft.Assemble(et, f)
 	if et.Error() != nil {
-		return et.Error()
+		return nil, et.Error()
 	}
-	if formatted, err := ft.Format(b.Bytes()); err != nil {
-		err = fmt.Errorf("unable to format file %q (%v)", pathname, err)
-		// Write the file anyway, so they can see what's going wrong and fix the generator.
-		if _, err2 := destFile.Write(b.Bytes()); err2 != nil {
-			return err
-		}
-		return err
-	} else {
-		_, err = destFile.Write(formatted)
-		return err
+	formatted, err := ft.Format(b.Bytes())
+	if err != nil {
+		return b.Bytes(), err
 	}
+	return formatted, nil
 }
 
 func (ft DefaultFileType) VerifyFile(f *File, pathname string) error {
 	log.Infof("Verifying file %q", pathname)
 	friendlyName := filepath.Join(f.PackageName, f.Name)
-	b := &bytes.Buffer{}
-	et := NewErrorTracker(b)
-	ft.Assemble(et, f)
-	if et.Error() != nil {
-		return et.Error()
+	formatted, err := ft.RenderFile(f)
+	if formatted == nil {
+		return err
 	}
-	formatted, err := ft.Format(b.Bytes())
 	if err != nil {
 		return fmt.Errorf("unable to format the output for %q: %v", friendlyName, err)
 	}
-	existing, err := ioutil.ReadFile(pathname)
-	if err != nil {
-		return fmt.Errorf("unable to read file %q for comparison: %q", friendlyName, err)
+	if stale := VerifyData(pathname, formatted); stale != nil {
+		return stale
 	}
-	if bytes.Compare(formatted, existing) == 0 {
-		return nil
-	}
-	i := 0
-	for i < len(formatted) && i < len(existing) && formatted[i] == existing[i] {
-		i++
-	}
-	eDiff, fDiff := existing[i:], formatted[i:]
-	if len(eDiff) > 100 {
-		eDiff = eDiff[:100]
-	}
-	if len(fDiff) > 100 {
-		fDiff = fDiff[:100]
-	}
-	return fmt.Errorf("output for %q differs; first existing/expected diff: \n	%q\n	%q", friendlyName, string(eDiff), string(fDiff))
+	return nil
 }
 
 func assembleGolangFile(w io.Writer, f *File) {
@@ -174,9 +234,39 @@ func addIndentHeaderComment(b *bytes.Buffer, format string, args ...interface{})
 	}
 }
 
+// indexOrder indexes the types of c.Order by package. It is called again by
+// every Execute* call, as c.Order may have changed in between.
+func (c *Context) indexOrder() {
+	c.orderIndex = map[string][]int{}
+	for i, t := range c.Order {
+		c.orderIndex[t.Name.Package] = append(c.orderIndex[t.Name.Package], i)
+	}
+}
+
+// typesOf returns the types of c.Order which belong to pkgs, in order.
+func (c *Context) typesOf(pkgs []string) []*types.Type {
+	var indexes []int
+	seen := map[string]bool{}
+	for _, pkg := range pkgs {
+		if !seen[pkg] {
+			seen[pkg] = true
+			indexes = append(indexes, c.orderIndex[pkg]...)
+		}
+	}
+	if len(seen) > 1 {
+		sort.Ints(indexes)
+	}
+	order := make([]*types.Type, len(indexes))
+	for i, index := range indexes {
+		order[i] = c.Order[index]
+	}
+	return order
+}
+
 func (c *Context) filteredBy(f func(*Context, *types.Type) bool) *Context {
 	c2 := *c
 	c2.Order = []*types.Type{}
+	c2.orderIndex = nil
 	for _, t := range c.Order {
 		if f(c, t) {
 			c2.Order = append(c2.Order, t)
@@ -205,16 +295,63 @@ func (c *Context) addNameSystems(namers namer.NameSystems) *Context {
 	return &c2
 }
 
+// OutputDir returns the directory in which the files of the package are
+// placed. The import path of the package is appended to 'outDir'; if 'outDir'
+// is empty, the package is placed next to its sources instead, wherever its
+// module or GOPATH entry lives.
+func (c *Context) OutputDir(outDir string, p Package) (string, error) {
+	if outDir != "" {
+		return filepath.Join(outDir, p.Path()), nil
+	}
+	if c.builder != nil {
+		return c.builder.PackageDir(p.Path())
+	}
+	if p.SourcePath() != "" {
+		return p.SourcePath(), nil
+	}
+	return "", fmt.Errorf("unable to find the directory of package %q", p.Path())
+}
+
 // ExecutePackage executes a single package. 'outDir' is the base directory in
 // which to place the package; it should be a physical path on disk, not an
 // import path. e.g.: '/path/to/name/path/to/gopath/src/' The package knowns its
-// import path already, this will be appended to 'outDir'.
+// import path already, this will be appended to 'outDir'. An empty 'outDir'
+// places the package next to its sources, see OutputDir.
 func (c *Context) ExecutePackage(outDir string, p Package) error {
-	path := filepath.Join(outDir, p.Path())
+	c.indexOrder()
+	generated, verified, err := c.executePackage(outDir, p)
+	c.addGenerated(p.Path(), generated)
+	c.verified = append(c.verified, verified...)
+	return err
+}
+
+// executePackage executes p and returns the paths of the files it generated,
+// or verified, and the results of the files it verified.
+func (c *Context) executePackage(outDir string, p Package) ([]string, []VerifyResult, error) {
+	path, err := c.OutputDir(outDir, p)
+	if err != nil {
+		return nil, nil, err
+	}
 	log.Infof("Processing package %q, disk location %q", p.Name(), path)
+	// The problems reported while executing p are told apart from those of
+	// the other packages.
+	scoped := *c
+	scoped.Diagnostics = c.Diagnostics.scope()
 	// Filter out any types the *package* doesn't care about.
-	packageContext := c.filteredBy(p.Filter)
-	os.MkdirAll(path, 0755)
+	if sp, ok := p.(ScopedPackage); ok && sp.TypePackages() != nil {
+		scoped.Order = c.typesOf(sp.TypePackages())
+	}
+	packageContext := scoped.filteredBy(p.Filter)
+	switch {
+	case c.Verify:
+		// Nothing is written in verify mode.
+	case c.Output != nil:
+		if err := c.Output.MkdirAll(path, 0755); err != nil {
+			return nil, nil, err
+		}
+	default:
+		os.MkdirAll(path, 0755)
+	}
 	files := map[string]*File{}
 	for _, g := range p.Generators(packageContext) {
 		// Filter out types the *generator* doesn't care about.
@@ -224,7 +361,7 @@ func (c *Context) ExecutePackage(outDir string, p Package) error {
 
 		fileType := g.FileType()
 		if len(fileType) == 0 {
-			return fmt.Errorf("generator %q must specify a file type", g.Name())
+			return nil, nil, fmt.Errorf("generator %q must specify a file type", g.Name())
 		}
 		f := files[g.Filename()]
 		if f == nil {
@@ -235,13 +372,13 @@ func (c *Context) ExecutePackage(outDir string, p Package) error {
 				PackageName:       p.Name(),
 				PackagePath:       p.Path(),
 				PackageSourcePath: p.SourcePath(),
-				Header:            p.Header(g.Filename()),
+				Header:            c.header(p, g.Filename()),
 				Imports:           map[string]string{},
 			}
 			files[f.Name] = f
 		} else {
 			if f.FileType != g.FileType() {
-				return fmt.Errorf("file %q already has type %q, but generator %q wants to use type %q", f.Name, f.FileType, g.Name(), g.FileType())
+				return nil, nil, fmt.Errorf("file %q already has type %q, but generator %q wants to use type %q", f.Name, f.FileType, g.Name(), g.FileType())
 			}
 		}
 
@@ -249,7 +386,7 @@ func (c *Context) ExecutePackage(outDir string, p Package) error {
 			addIndentHeaderComment(&f.Vars, "Package-wide variables from generator %q.", g.Name())
 			for _, v := range vars {
 				if _, err := fmt.Fprintf(&f.Vars, "%s\n", v); err != nil {
-					return err
+					return nil, nil, err
 				}
 			}
 		}
@@ -257,12 +394,12 @@ func (c *Context) ExecutePackage(outDir string, p Package) error {
 			addIndentHeaderComment(&f.Consts, "Package-wide consts from generator %q.", g.Name())
 			for _, v := range consts {
 				if _, err := fmt.Fprintf(&f.Consts, "%s\n", v); err != nil {
-					return err
+					return nil, nil, err
 				}
 			}
 		}
 		if err := genContext.executeBody(&f.Body, g); err != nil {
-			return err
+			return nil, nil, err
 		}
 		if importLines := g.Imports(genContext); len(importLines) > 0 {
 			for k, v := range importLines {
@@ -270,17 +407,30 @@ func (c *Context) ExecutePackage(outDir string, p Package) error {
 			}
 		}
 	}
+	if err := scoped.Diagnostics.Err(); err != nil {
+		return nil, nil, fmt.Errorf("errors in package %q:\n%v\n", p.Path(), err)
+	}
 
+	var generated []string
+	var verified []VerifyResult
 	var errors []error
 	for _, f := range files {
 		finalPath := filepath.Join(path, f.Name)
+		generated = append(generated, finalPath)
 		assembler, ok := c.FileTypes[f.FileType]
 		if !ok {
-			return fmt.Errorf("the file type %q registered for file %q does not exist in the context", f.FileType, f.Name)
+			return nil, nil, fmt.Errorf("the file type %q registered for file %q does not exist in the context", f.FileType, f.Name)
 		}
 		var err error
 		if c.Verify {
 			err = assembler.VerifyFile(f, finalPath)
+			var stale *StaleFileError
+			if err == nil || goerrors.As(err, &stale) {
+				verified = append(verified, verifyResult(p.Path(), finalPath, stale))
+				err = nil
+			}
+		} else if c.Output != nil {
+			err = c.render(assembler, f, finalPath)
 		} else {
 			err = assembler.AssembleFile(f, finalPath)
 		}
@@ -289,9 +439,30 @@ func (c *Context) ExecutePackage(outDir string, p Package) error {
 		}
 	}
 	if len(errors) > 0 {
-		return fmt.Errorf("errors in package %q:\n%v\n", p.Path(), strings.Join(errs2strings(errors), "\n"))
+		return generated, verified, fmt.Errorf("errors in package %q:\n%v\n", p.Path(), strings.Join(errs2strings(errors), "\n"))
 	}
-	return nil
+	return generated, verified, nil
+}
+
+// render renders f and writes it to c.Output at finalPath.
+func (c *Context) render(assembler FileType, f *File, finalPath string) error {
+	renderer, ok := assembler.(FileRenderer)
+	if !ok {
+		return fmt.Errorf("the file type %q of file %q can't be rendered in memory", f.FileType, f.Name)
+	}
+	log.Infof("Assembling file %q", finalPath)
+	data, err := renderer.RenderFile(f)
+	if data == nil {
+		return err
+	}
+	if err != nil {
+		err = fmt.Errorf("unable to format file %q (%v)", finalPath, err)
+	}
+	// As with AssembleFile, the file is written even if formatting failed.
+	if err2 := c.Output.WriteFile(finalPath, data, 0644); err2 != nil && err == nil {
+		return err2
+	}
+	return err
 }
 
 func (c *Context) executeBody(w io.Writer, generator Generator) error {
@@ -300,8 +471,9 @@ func (c *Context) executeBody(w io.Writer, generator Generator) error {
 		return err
 	}
 	for _, t := range c.Order {
+		// The other types are still generated, to find all the problems.
 		if err := generator.GenerateType(c, t, et); err != nil {
-			return err
+			c.Diagnostics.Report(err)
 		}
 	}
 	if err := generator.Finalize(c, et); err != nil {
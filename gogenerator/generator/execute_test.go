@@ -0,0 +1,172 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator_test
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/vine-io/gogogen/gogenerator/args"
+	"github.com/vine-io/gogogen/gogenerator/generator"
+	"github.com/vine-io/gogogen/gogenerator/namer"
+	"github.com/vine-io/gogogen/gogenerator/types"
+)
+
+// genRefs writes a variable referring to every type of its package, and to
+// the types their fields refer to, which makes it import the other packages
+// and look up the universe.
+type genRefs struct {
+	generator.DefaultGen
+	pkg     string
+	imports namer.ImportTracker
+}
+
+func (g *genRefs) Namers(c *generator.Context) namer.NameSystems {
+	return namer.NameSystems{"raw": namer.NewRawNamer(g.pkg, g.imports)}
+}
+
+func (g *genRefs) Imports(c *generator.Context) map[string]string {
+	lines := map[string]string{}
+	for path, name := range g.imports.ImportLines() {
+		if path != g.pkg {
+			lines[path] = name
+		}
+	}
+	return lines
+}
+
+func (g *genRefs) GenerateType(c *generator.Context, t *types.Type, w io.Writer) error {
+	// Adding the packages already in the universe leaves their types, which
+	// the other generators read, alone.
+	if p, err := c.AddDirectory(g.pkg); err != nil || p != c.Universe.Package(g.pkg) {
+		return fmt.Errorf("%v: adding its own package again: %v", t, err)
+	}
+	sw := generator.NewSnippetWriter(w, c, "$", "$")
+	for _, line := range t.CommentLines {
+		sw.Do("// "+line+"\n", nil)
+	}
+	sw.Do("var _ $.|raw$\n", t)
+	for _, m := range t.Members {
+		if m.Type.Kind == types.Pointer {
+			if err := c.AddDir(m.Type.Elem.Name.Package); err != nil {
+				return err
+			}
+		}
+		// The universe returns the same type when looked up concurrently.
+		ref := c.Universe.Type(m.Type.Name)
+		if ref != m.Type {
+			return fmt.Errorf("%v: the universe has another %v", t, m.Type.Name)
+		}
+		sw.Do("var _ $.|raw$\n", ref)
+	}
+	// Markers of missing types are added to the package concurrently.
+	c.Universe.Type(types.Name{Package: "example.com/exec/p0", Name: "Missing" + t.Name.Name})
+	// And markers of missing packages to the universe.
+	c.Universe.Type(types.Name{Package: g.pkg + "/missing", Name: t.Name.Name})
+	// While the universe is ranged over.
+	importers := append([]string{}, c.IncomingImports()[g.pkg]...)
+	sort.Strings(importers)
+	for _, p := range importers {
+		sw.Do("// imported by "+p+"\n", nil)
+	}
+	return sw.Error()
+}
+
+func TestExecutePackagesParallel(t *testing.T) {
+	const n = 12
+	src := map[string][]byte{}
+	for i := 0; i < n; i++ {
+		var b strings.Builder
+		fmt.Fprintf(&b, "package p%d\n\n", i)
+		if i > 0 {
+			fmt.Fprintf(&b, "import prev \"example.com/exec/p%d\"\n\n", i-1)
+		}
+		for j := 0; j < 5; j++ {
+			fmt.Fprintf(&b, "// T%d is a type.\ntype T%d struct {\n\tName string\n", j, j)
+			if i > 0 {
+				fmt.Fprintf(&b, "\tPrev *prev.T%d\n", j)
+			}
+			b.WriteString("}\n\n")
+		}
+		src[fmt.Sprintf("example.com/exec/p%d/types.go", i)] = []byte(b.String())
+	}
+
+	run := func(parallelism int) map[string][]byte {
+		t.Helper()
+		g := args.Default()
+		g.GoHeaderFilePath = ""
+		g.Parallelism = parallelism
+		out, err := args.Generate(src, args.Run{
+			Args:          g,
+			NameSystems:   namer.NameSystems{"public": namer.NewPublicNamer(0)},
+			DefaultSystem: "public",
+			Packages: func(c *generator.Context, a *args.GeneratorArgs) generator.Packages {
+				var pkgs generator.Packages
+				for i := 0; i < n; i++ {
+					path := fmt.Sprintf("example.com/exec/p%d", i)
+					p := &generator.DefaultPackage{
+						PackageName: fmt.Sprintf("p%d", i),
+						PackagePath: path,
+						GeneratorList: []generator.Generator{&genRefs{
+							DefaultGen: generator.DefaultGen{OptionalName: "refs_generated"},
+							pkg:        path,
+							imports:    generator.NewImportTracker(),
+						}},
+						FilterFunc: func(c *generator.Context, t *types.Type) bool {
+							return t.Name.Package == path && t.Kind == types.Struct
+						},
+					}
+					// Half of the packages only look at their own types,
+					// the others filter the whole ordering.
+					if i%2 == 0 {
+						p.TypesFrom = []string{path}
+					}
+					pkgs = append(pkgs, p)
+				}
+				return pkgs
+			},
+		})
+		if err != nil {
+			t.Fatalf("parallelism %d: %v", parallelism, err)
+		}
+		return out
+	}
+
+	serial := run(1)
+	if len(serial) != n {
+		t.Fatalf("got %d generated files, want %d", len(serial), n)
+	}
+	if got := string(serial["example.com/exec/p1/refs_generated.go"]); !strings.Contains(got, "var _ *p0.T4") {
+		t.Errorf("p1 doesn't refer to p0.T4:\n%s", got)
+	}
+	if got := string(serial["example.com/exec/p1/refs_generated.go"]); !strings.Contains(got, "// T4 is a type.") || !strings.Contains(got, "// imported by example.com/exec/p2") {
+		t.Errorf("p1 lacks the comments of T4 or its importers:\n%s", got)
+	}
+	for _, parallelism := range []int{4, n} {
+		parallel := run(parallelism)
+		if len(parallel) != len(serial) {
+			t.Errorf("parallelism %d: got %d generated files, want %d", parallelism, len(parallel), len(serial))
+		}
+		for name := range serial {
+			if !reflect.DeepEqual(parallel[name], serial[name]) {
+				t.Errorf("parallelism %d: %s differs:\n%s\nwant:\n%s", parallelism, name, parallel[name], serial[name])
+			}
+		}
+	}
+}
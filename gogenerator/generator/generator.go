@@ -17,6 +17,7 @@ package generator
 import (
 	"bytes"
 	"io"
+	"sync"
 
 	"github.com/vine-io/gogogen/gogenerator/namer"
 	"github.com/vine-io/gogogen/gogenerator/parser"
@@ -49,6 +50,17 @@ type Package interface {
 	Generators(*Context) []Generator
 }
 
+// ScopedPackage is a Package which only cares about the types of a few
+// packages of the universe. Executing it only runs its Filter on their types,
+// instead of on the whole ordering of the context.
+type ScopedPackage interface {
+	Package
+
+	// TypePackages returns the import paths of the packages whose types
+	// the package cares about, or nil if they can come from any package.
+	TypePackages() []string
+}
+
 type File struct {
 	Name              string
 	FileType          string
@@ -67,6 +79,12 @@ type FileType interface {
 	VerifyFile(f *File, path string) error
 }
 
+// FileRenderer is implemented by the file types which can render a file in
+// memory, see Context.Output.
+type FileRenderer interface {
+	RenderFile(f *File) ([]byte, error)
+}
+
 // Packages is a list of package to generate.
 type Packages []Package
 
@@ -168,6 +186,8 @@ type Context struct {
 	// The canonical ordering of the types (will be filtered by both the
 	// Package's and Generator's Filter methods).
 	Order []*types.Type
+	// The indexes in Order of the types of each package, see indexOrder.
+	orderIndex map[string][]int
 
 	// A set of types this context can process. If this is empty or nil
 	// the default "golang" filetype will be provide
@@ -177,8 +197,43 @@ type Context struct {
 	// correct. (You may set after calling NewContext.)
 	Verify bool
 
+	// If set, Execute* calls write the files to this filesystem instead of
+	// letting their file types write them to disk, in which case the file
+	// types must implement FileRenderer. It is ignored in verify mode.
+	Output OutputFS
+
+	// The maximum number of packages ExecutePackages executes at the same
+	// time. If it is less than 2, packages are executed one at a time.
+	// Otherwise the generators of different packages run concurrently: they
+	// must not share mutable state other than through the namers and the
+	// output, which are safe for concurrent use. They may look up types in
+	// the universe, but the packages they need must be added before, see
+	// AddDir, and it must be ranged over through Universe.Snapshot.
+	Parallelism int
+
+	// If set, the generated files are marked as owned by this generator, see
+	// FileOwner. Execute* calls then remove the files it owns in the
+	// directories they generate to, or of the input packages, which are no
+	// longer generated, and report them in verify mode.
+	Owner string
+
+	// The problems found while generating, which the generators report
+	// instead of returning them, e.g. from their Filter methods. Execute*
+	// calls fail with the problems of the packages, which aren't written.
+	Diagnostics *Diagnostics
+
+	// The results of the files verified so far.
+	verified []VerifyResult
+	// The paths of the files generated, or verified, so far, mapped to the
+	// import paths of their packages.
+	generated map[string]string
+
 	// Allows generators to add packages at runtime.
 	builder *parser.Builder
+	// Serializes the uses of builder, and of the imports it adds to the
+	// universe, by concurrent generators. It is shared by the contexts
+	// derived from this one.
+	lock *sync.Mutex
 }
 
 // NewContext generates a context from the given builder, naming systems, and
@@ -196,7 +251,9 @@ func NewContext(b *parser.Builder, nameSystems namer.NameSystems, canonicalOrder
 		FileTypes: map[string]FileType{
 			GolangFileType: NewGolangFile(),
 		},
-		builder: b,
+		Diagnostics: &Diagnostics{},
+		builder:     b,
+		lock:        &sync.Mutex{},
 	}
 
 	for name, systemNamer := range nameSystems {
@@ -209,11 +266,43 @@ func NewContext(b *parser.Builder, nameSystems namer.NameSystems, canonicalOrder
 	return c, nil
 }
 
+// WithNameSystems returns a new context over the universe and the inputs of
+// ctxt, e.g. to run another generator without parsing the packages again. It
+// has the given naming systems and the default file types, and none of the
+// settings or results of ctxt.
+func (ctxt *Context) WithNameSystems(nameSystems namer.NameSystems, canonicalOrderName string) *Context {
+	c := &Context{
+		Namers:   namer.NameSystems{},
+		Universe: ctxt.Universe,
+		Inputs:   append([]string{}, ctxt.Inputs...),
+		FileTypes: map[string]FileType{
+			GolangFileType: NewGolangFile(),
+		},
+		Diagnostics: &Diagnostics{},
+		builder:     ctxt.builder,
+		lock:        ctxt.lock,
+	}
+	for name, systemNamer := range nameSystems {
+		c.Namers[name] = systemNamer
+		if name == canonicalOrderName {
+			orderer := namer.Orderer{Namer: systemNamer}
+			c.Order = orderer.OrderUniverse(c.Universe)
+		}
+	}
+	return c
+}
+
 // IncomingImports returns the incoming imports for each package. The map is lazily computed.
 func (ctxt *Context) IncomingImports() map[string][]string {
+	// The imports are only added by the builder, see AddDir.
+	defer ctxt.lockBuilder()()
+	return ctxt.incomingImportsLocked()
+}
+
+func (ctxt *Context) incomingImportsLocked() map[string][]string {
 	if ctxt.incomingImports == nil {
 		incoming := map[string][]string{}
-		for _, pkg := range ctxt.Universe {
+		for _, pkg := range ctxt.Universe.Snapshot() {
 			for imp := range pkg.Imports {
 				incoming[imp] = append(incoming[imp], pkg.Path)
 			}
@@ -226,17 +315,23 @@ func (ctxt *Context) IncomingImports() map[string][]string {
 // TransitiveIncomingImports returns the transitive closure of the incoming imports for each package.
 // The map is lazily computed.
 func (ctxt *Context) TransitiveIncomingImports() map[string][]string {
+	defer ctxt.lockBuilder()()
 	if ctxt.incomingTransitiveImports == nil {
-		ctxt.incomingTransitiveImports = transitiveClosure(ctxt.IncomingImports())
+		ctxt.incomingTransitiveImports = transitiveClosure(ctxt.incomingImportsLocked())
 	}
 	return ctxt.incomingTransitiveImports
 }
 
 // AddDir adds a Go package to the context. The specified path must be a single
 // go package import path.  GOPATH, GOROOT, and the location of your go binary
-// (`which go`) will all be searched, in the normal Go fashion.
+// (`which go`) will all be searched, in the normal Go fashion. Packages
+// already in the universe are left as they are.
 // Deprecated: Please use AddDirectory.
 func (ctxt *Context) AddDir(path string) error {
+	defer ctxt.lockBuilder()()
+	if ctxt.added(path) != nil {
+		return nil
+	}
 	ctxt.incomingImports = nil
 	ctxt.incomingTransitiveImports = nil
 	return ctxt.builder.AddDirTo(path, &ctxt.Universe)
@@ -244,9 +339,45 @@ func (ctxt *Context) AddDir(path string) error {
 
 // AddDirectory adds a Go Package to the context. The specified path must be a
 // single go package import path.  GOPATH, GOROOT, and the location of your go
-// binary (`which go`) will all be searched, in the normal Go fashion.
+// binary (`which go`) will all be searched, in the normal Go fashion. Packages
+// already in the universe are returned as they are.
 func (ctxt *Context) AddDirectory(path string) (*types.Package, error) {
+	defer ctxt.lockBuilder()()
+	if p := ctxt.added(path); p != nil {
+		return p, nil
+	}
 	ctxt.incomingImports = nil
 	ctxt.incomingTransitiveImports = nil
 	return ctxt.builder.AddDirectoryTo(path, &ctxt.Universe)
 }
+
+// Reload parses again the packages pkgPaths, whose files changed on disk, and
+// the packages importing them, and rebuilds their entries in the universe,
+// see parser.Builder.Reload. It returns the input packages reloaded. The
+// contexts sharing the universe, see WithNameSystems, must be made again to
+// order its new types.
+func (ctxt *Context) Reload(pkgPaths []string) ([]string, error) {
+	defer ctxt.lockBuilder()()
+	ctxt.incomingImports = nil
+	ctxt.incomingTransitiveImports = nil
+	return ctxt.builder.Reload(ctxt.Universe, pkgPaths...)
+}
+
+// added returns the package path if it was already added to the universe.
+// Adding it again would rewrite its types while concurrent generators may be
+// reading them.
+func (ctxt *Context) added(path string) *types.Package {
+	if p := ctxt.Universe.Lookup(path); p != nil && p.SourcePath != "" {
+		return p
+	}
+	return nil
+}
+
+// lockBuilder locks the builder and returns the function unlocking it.
+func (ctxt *Context) lockBuilder() func() {
+	if ctxt.lock == nil {
+		return func() {}
+	}
+	ctxt.lock.Lock()
+	return ctxt.lock.Unlock
+}
@@ -0,0 +1,251 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vine-io/gogogen/util/log"
+)
+
+// OutputFS is where the Execute* calls of a context write the generated
+// files, see Context.Output. Paths are the paths the files would have on disk.
+type OutputFS interface {
+	MkdirAll(path string, perm os.FileMode) error
+	WriteFile(path string, data []byte, perm os.FileMode) error
+	// Remove removes a stale file, see Context.Owner.
+	Remove(path string) error
+}
+
+// DiskFS writes the files to disk. Files which are already up to date are
+// left untouched, so that their modification time is kept.
+type DiskFS struct{}
+
+func (DiskFS) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (DiskFS) WriteFile(path string, data []byte, perm os.FileMode) error {
+	if existing, err := ioutil.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+		log.Infof("File %q is up to date", path)
+		return nil
+	}
+	return ioutil.WriteFile(path, data, perm)
+}
+
+func (DiskFS) Remove(path string) error {
+	return os.Remove(path)
+}
+
+// MemoryFS keeps the files in memory.
+type MemoryFS struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func NewMemoryFS() *MemoryFS {
+	return &MemoryFS{files: map[string][]byte{}}
+}
+
+func (m *MemoryFS) MkdirAll(path string, perm os.FileMode) error {
+	return nil
+}
+
+func (m *MemoryFS) WriteFile(path string, data []byte, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[path] = append([]byte{}, data...)
+	return nil
+}
+
+func (m *MemoryFS) Remove(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.files, path)
+	return nil
+}
+
+// Files returns the files written so far, keyed by path.
+func (m *MemoryFS) Files() map[string][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	files := make(map[string][]byte, len(m.files))
+	for path, data := range m.files {
+		files[path] = data
+	}
+	return files
+}
+
+// DryRunFS writes nothing, but lists the sorted paths of the files to W, one
+// per line, when it is closed. Removed files are suffixed with " (removed)".
+type DryRunFS struct {
+	W     io.Writer
+	mu    sync.Mutex
+	paths []string
+}
+
+func (d *DryRunFS) MkdirAll(path string, perm os.FileMode) error {
+	return nil
+}
+
+func (d *DryRunFS) WriteFile(path string, data []byte, perm os.FileMode) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.paths = append(d.paths, path)
+	return nil
+}
+
+func (d *DryRunFS) Remove(path string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.paths = append(d.paths, path+" (removed)")
+	return nil
+}
+
+// Close writes the list of the files.
+func (d *DryRunFS) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sort.Strings(d.paths)
+	for _, path := range d.paths {
+		if _, err := fmt.Fprintln(d.W, path); err != nil {
+			return err
+		}
+	}
+	d.paths = nil
+	return nil
+}
+
+// ArchiveFS collects the files and writes them as a tar or zip archive when
+// it is closed. The archive entries are named after the paths of the files
+// relative to its root. Directories get no entry of their own, and removed
+// files are only left out.
+type ArchiveFS struct {
+	// The modification time of the entries. If zero, ArchiveEpoch is used,
+	// so that the same files always make the same archive.
+	ModTime time.Time
+
+	root  string
+	w     io.Writer
+	write func(w io.Writer, names []string, files map[string][]byte, modes map[string]os.FileMode, modTime time.Time) error
+	mem   *MemoryFS
+	modes map[string]os.FileMode
+}
+
+// ArchiveEpoch is the default modification time of the entries of an
+// ArchiveFS, the earliest time a zip archive can hold.
+var ArchiveEpoch = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// NewTarFS returns an ArchiveFS which writes a tar archive to w, compressed
+// with gzip if compress is true.
+func NewTarFS(root string, w io.Writer, compress bool) *ArchiveFS {
+	return newArchiveFS(root, w, func(w io.Writer, names []string, files map[string][]byte, modes map[string]os.FileMode, modTime time.Time) error {
+		if compress {
+			zw := gzip.NewWriter(w)
+			defer zw.Close()
+			w = zw
+		}
+		tw := tar.NewWriter(w)
+		for _, name := range names {
+			hdr := &tar.Header{
+				Name:    name,
+				Mode:    int64(modes[name].Perm()),
+				Size:    int64(len(files[name])),
+				ModTime: modTime,
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if _, err := tw.Write(files[name]); err != nil {
+				return err
+			}
+		}
+		return tw.Close()
+	})
+}
+
+// NewZipFS returns an ArchiveFS which writes a zip archive to w.
+func NewZipFS(root string, w io.Writer) *ArchiveFS {
+	return newArchiveFS(root, w, func(w io.Writer, names []string, files map[string][]byte, modes map[string]os.FileMode, modTime time.Time) error {
+		zw := zip.NewWriter(w)
+		for _, name := range names {
+			hdr := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime}
+			hdr.SetMode(modes[name])
+			fw, err := zw.CreateHeader(hdr)
+			if err != nil {
+				return err
+			}
+			if _, err := fw.Write(files[name]); err != nil {
+				return err
+			}
+		}
+		return zw.Close()
+	})
+}
+
+func newArchiveFS(root string, w io.Writer, write func(io.Writer, []string, map[string][]byte, map[string]os.FileMode, time.Time) error) *ArchiveFS {
+	return &ArchiveFS{root: root, w: w, write: write, mem: NewMemoryFS(), modes: map[string]os.FileMode{}}
+}
+
+func (a *ArchiveFS) MkdirAll(path string, perm os.FileMode) error {
+	return nil
+}
+
+func (a *ArchiveFS) WriteFile(path string, data []byte, perm os.FileMode) error {
+	rel, err := filepath.Rel(a.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("file %q is outside of the archive root %q", path, a.root)
+	}
+	name := filepath.ToSlash(rel)
+	a.mem.mu.Lock()
+	a.modes[name] = perm
+	a.mem.mu.Unlock()
+	return a.mem.WriteFile(name, data, perm)
+}
+
+func (a *ArchiveFS) Remove(path string) error {
+	rel, err := filepath.Rel(a.root, path)
+	if err != nil {
+		return nil
+	}
+	return a.mem.Remove(filepath.ToSlash(rel))
+}
+
+// Close writes the archive. It doesn't close the underlying writer.
+func (a *ArchiveFS) Close() error {
+	files := a.mem.Files()
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	modTime := a.ModTime
+	if modTime.IsZero() {
+		modTime = ArchiveEpoch
+	}
+	return a.write(a.w, names, files, a.modes, modTime)
+}
@@ -0,0 +1,158 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDryRunFS(t *testing.T) {
+	var b bytes.Buffer
+	d := &DryRunFS{W: &b}
+	d.WriteFile("/out/b/b.go", []byte("package b\n"), 0644)
+	d.Remove("/out/c/stale.go")
+	d.WriteFile("/out/a/a.go", []byte("package a\n"), 0644)
+	if b.Len() != 0 {
+		t.Errorf("listed before Close: %q", b.String())
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "/out/a/a.go\n/out/b/b.go\n/out/c/stale.go (removed)\n"
+	if got := b.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// archiveEntry is an entry of an archive written by an ArchiveFS.
+type archiveEntry struct {
+	Data    string
+	Mode    os.FileMode
+	ModTime time.Time
+}
+
+// writeArchive writes files with a, and drops the file "drop" again.
+func writeArchive(t *testing.T, a *ArchiveFS, root string) {
+	t.Helper()
+	for name, data := range map[string]string{"b/b.go": "package b\n", "a/a.go": "package a\n", "drop.go": "package drop\n"} {
+		if err := a.WriteFile(filepath.Join(root, filepath.FromSlash(name)), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Remove(filepath.Join(root, "drop.go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.WriteFile(filepath.Join(filepath.Dir(root), "outside.go"), nil, 0644); err == nil {
+		t.Errorf("writing outside of the archive root: no error")
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchiveFS(t *testing.T) {
+	root := filepath.Join(os.TempDir(), "archive-root")
+	wantEntries := func(modTime time.Time) map[string]archiveEntry {
+		return map[string]archiveEntry{
+			"a/a.go": {Data: "package a\n", Mode: 0644, ModTime: modTime},
+			"b/b.go": {Data: "package b\n", Mode: 0644, ModTime: modTime},
+		}
+	}
+	readTar := func(r io.Reader) (names []string, entries map[string]archiveEntry) {
+		t.Helper()
+		entries = map[string]archiveEntry{}
+		tr := tar.NewReader(r)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				return names, entries
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, hdr.Name)
+			entries[hdr.Name] = archiveEntry{Data: string(data), Mode: os.FileMode(hdr.Mode), ModTime: hdr.ModTime.UTC()}
+		}
+	}
+	check := func(name string, names []string, got, want map[string]archiveEntry) {
+		t.Helper()
+		if !reflect.DeepEqual(names, []string{"a/a.go", "b/b.go"}) {
+			t.Errorf("%s: got entries %v, want them sorted", name, names)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, want %+v", name, got, want)
+		}
+	}
+
+	var b bytes.Buffer
+	writeArchive(t, NewTarFS(root, &b, false), root)
+	names, entries := readTar(&b)
+	check("tar", names, entries, wantEntries(ArchiveEpoch))
+
+	b.Reset()
+	modTime := time.Date(2020, time.March, 4, 5, 6, 7, 0, time.UTC)
+	a := NewTarFS(root, &b, true)
+	a.ModTime = modTime
+	writeArchive(t, a, root)
+	zr, err := gzip.NewReader(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, entries = readTar(zr)
+	check("tar.gz", names, entries, wantEntries(modTime))
+
+	b.Reset()
+	writeArchive(t, NewZipFS(root, &b), root)
+	r, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, entries = nil, map[string]archiveEntry{}
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, f.Name)
+		entries[f.Name] = archiveEntry{Data: string(data), Mode: f.Mode(), ModTime: f.Modified.UTC()}
+	}
+	check("zip", names, entries, wantEntries(ArchiveEpoch))
+
+	// The same files make the same archive.
+	var again bytes.Buffer
+	writeArchive(t, NewZipFS(root, &again), root)
+	if !bytes.Equal(b.Bytes(), again.Bytes()) {
+		t.Errorf("zip archives of the same files differ")
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vine-io/gogogen/util/log"
+)
+
+// ownerPrefix starts the first line of the files owned by a generator, which
+// is followed by the name of the generator, see Context.Owner.
+const ownerPrefix = "// gogogen:owner="
+
+// FileOwner returns the generator owning the file at path, or "" if the file
+// doesn't exist or isn't owned.
+func FileOwner(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, ownerPrefix) {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(line, ownerPrefix))
+}
+
+// header returns the header of the file named filename of p, marked as owned
+// by c.Owner if it is set.
+func (c *Context) header(p Package, filename string) []byte {
+	header := p.Header(filename)
+	if c.Owner == "" {
+		return header
+	}
+	return append([]byte(ownerPrefix+c.Owner+"\n\n"), header...)
+}
+
+// addGenerated records the paths of the files c generated for the package
+// pkg.
+func (c *Context) addGenerated(pkg string, paths []string) {
+	if c.generated == nil {
+		c.generated = map[string]string{}
+	}
+	for _, path := range paths {
+		c.generated[path] = pkg
+	}
+}
+
+// Generated returns the paths of the files generated, or verified, so far,
+// mapped to the import paths of their packages.
+func (c *Context) Generated() map[string]string {
+	generated := make(map[string]string, len(c.generated))
+	for path, pkg := range c.generated {
+		generated[path] = pkg
+	}
+	return generated
+}
+
+// staleFiles returns the files owned by c.Owner which c didn't generate, in
+// the directories of the input packages and of the generated files.
+func (c *Context) staleFiles(outDir string) []VerifyResult {
+	dirs := c.inputDirs(outDir)
+	for path, pkg := range c.generated {
+		dirs[filepath.Dir(path)] = pkg
+	}
+
+	var stale []VerifyResult
+	for dir, pkg := range dirs {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			path := filepath.Join(dir, e.Name())
+			if _, ok := c.generated[path]; ok || e.IsDir() || FileOwner(path) != c.Owner {
+				continue
+			}
+			stale = append(stale, VerifyResult{Package: pkg, Path: path, Status: VerifyExtra})
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i].Path < stale[j].Path })
+	return stale
+}
+
+// removeStale removes the stale files of c, see staleFiles.
+func (c *Context) removeStale(outDir string) error {
+	for _, r := range c.staleFiles(outDir) {
+		log.Infof("Removing stale file %q", r.Path)
+		var err error
+		if c.Output != nil {
+			err = c.Output.Remove(r.Path)
+		} else {
+			err = os.Remove(r.Path)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// inputDirs returns the directories of the input packages, keyed by
+// directory.
+func (c *Context) inputDirs(outDir string) map[string]string {
+	dirs := map[string]string{}
+	for _, pkg := range c.Inputs {
+		dir := ""
+		if outDir != "" {
+			dir = filepath.Join(outDir, pkg)
+		} else if p := c.Universe[pkg]; p != nil {
+			dir = p.SourcePath
+		}
+		if dir != "" {
+			dirs[dir] = pkg
+		}
+	}
+	return dirs
+}
@@ -0,0 +1,209 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/vine-io/gogogen/util/log"
+)
+
+// Shadow is a temporary copy of the directories of the packages a generator
+// writes to. Generators which post-process their output, or change the
+// sources it is generated from, run in it, and its changes are then verified
+// against the source tree or exported to it, so that unchanged files are left
+// untouched and the files which aren't generated anymore are removed.
+type Shadow struct {
+	// The directory of the copy, which holds the copy of each package at its
+	// import path.
+	Root string
+
+	match     func(name string) bool
+	generated func(pkg string) []string
+	pkgs      []string
+	dirs      map[string]string
+}
+
+// NewShadow creates an empty shadow copy in a new temporary directory named
+// after prefix. Only the files for which match returns true are copied and
+// compared, and generated returns the names of the files generated in a
+// package, which are reported even when they are up to date.
+func NewShadow(prefix string, match func(name string) bool, generated func(pkg string) []string) (*Shadow, error) {
+	root, err := ioutil.TempDir("", prefix)
+	if err != nil {
+		return nil, err
+	}
+	return &Shadow{Root: root, match: match, generated: generated, dirs: map[string]string{}}, nil
+}
+
+// Add copies the package pkg from dir, which needn't exist.
+func (s *Shadow) Add(pkg, dir string) error {
+	dst := s.Dir(pkg)
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !s.match(e.Name()) {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dst, e.Name()), data, 0644); err != nil {
+			return err
+		}
+	}
+	if _, ok := s.dirs[pkg]; !ok {
+		s.pkgs = append(s.pkgs, pkg)
+	}
+	s.dirs[pkg] = dir
+	return nil
+}
+
+// Has returns whether the package pkg is copied.
+func (s *Shadow) Has(pkg string) bool {
+	_, ok := s.dirs[pkg]
+	return ok
+}
+
+// Dir returns the directory of the copy of the package pkg.
+func (s *Shadow) Dir(pkg string) string {
+	return filepath.Join(s.Root, pkg)
+}
+
+// ReadGoFiles adds the go files of the copy of the package pkg, other than
+// its tests, to src, keyed as parser.Builder.AddOverlay wants them with
+// s.Root as the root.
+func (s *Shadow) ReadGoFiles(pkg string, src map[string][]byte) error {
+	dir := s.Dir(pkg)
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		src[path.Join(pkg, name)] = data
+	}
+	return nil
+}
+
+// Results compares the copy with the source tree. It returns the results of
+// the generated files, and of the other files which differ.
+func (s *Shadow) Results() ([]VerifyResult, error) {
+	var results []VerifyResult
+	for _, pkg := range s.pkgs {
+		rs, err := VerifyDir(pkg, s.Dir(pkg), s.dirs[pkg], s.match)
+		if err != nil {
+			return nil, fmt.Errorf("package %s: %v", pkg, err)
+		}
+		generated := map[string]bool{}
+		for _, name := range s.generated(pkg) {
+			generated[name] = true
+		}
+		for _, r := range rs {
+			if r.Status != VerifyOK || generated[filepath.Base(r.Path)] {
+				results = append(results, r)
+			}
+		}
+	}
+	return results, nil
+}
+
+// Verify compares the copy with the source tree, and returns a *VerifyError
+// reporting the differences if it isn't up to date.
+func (s *Shadow) Verify() error {
+	results, err := s.Results()
+	if err != nil {
+		return fmt.Errorf("unable to verify the generated files: %v", err)
+	}
+	verr := &VerifyError{Results: results}
+	if verr.Stale() {
+		return verr
+	}
+	log.Infof("Generated files are up to date.")
+	return nil
+}
+
+// Export writes the generated files of the copy, and the other files which
+// differ, to out at the paths they have in the source tree, and removes the
+// files which are gone from the copy.
+func (s *Shadow) Export(out OutputFS) error {
+	results, err := s.Results()
+	if err != nil {
+		return fmt.Errorf("unable to collect the generated files: %v", err)
+	}
+	for _, r := range results {
+		if r.Status == VerifyExtra {
+			log.Infof("Removing stale file %q", r.Path)
+			if err := out.Remove(r.Path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("unable to remove %s: %v", r.Path, err)
+			}
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(s.Dir(r.Package), filepath.Base(r.Path)))
+		if err != nil {
+			return fmt.Errorf("unable to collect %s: %v", r.Path, err)
+		}
+		if err := out.MkdirAll(filepath.Dir(r.Path), 0755); err != nil {
+			return fmt.Errorf("unable to write %s: %v", r.Path, err)
+		}
+		if err := out.WriteFile(r.Path, data, 0644); err != nil {
+			return fmt.Errorf("unable to write %s: %v", r.Path, err)
+		}
+	}
+	return nil
+}
+
+// Manifest returns the directories of the copied packages and the generated
+// files which exist in them, by import path, as GeneratorArgs.RecordManifest
+// wants them.
+func (s *Shadow) Manifest() (inputs map[string]string, generated map[string][]string) {
+	inputs = map[string]string{}
+	generated = map[string][]string{}
+	for _, pkg := range s.pkgs {
+		dir := s.dirs[pkg]
+		inputs[pkg] = dir
+		generated[pkg] = nil
+		for _, name := range s.generated(pkg) {
+			p := filepath.Join(dir, name)
+			if _, err := os.Stat(p); err == nil {
+				generated[pkg] = append(generated[pkg], p)
+			}
+		}
+	}
+	return inputs, generated
+}
+
+// Remove removes the copy.
+func (s *Shadow) Remove() error {
+	return os.RemoveAll(s.Root)
+}
@@ -0,0 +1,149 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestShadow(t *testing.T) {
+	src := t.TempDir()
+	write := func(name, data string) {
+		t.Helper()
+		p := filepath.Join(src, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a/a.go", "package a\n")
+	write("a/a_test.go", "package a\n")
+	write("a/gen.go", "package a\n\n// old\n")
+	write("a/stale.go", "package a\n")
+	write("a/notes.txt", "notes\n")
+	write("b/b.go", "package b\n")
+	write("b/gen.go", "package b\n")
+
+	match := func(name string) bool {
+		return strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go")
+	}
+	s, err := NewShadow("shadow-test", match, func(pkg string) []string { return []string{"gen.go"} })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Remove()
+	for _, pkg := range []string{"a", "b", "c"} {
+		if err := s.Add("example.com/"+pkg, filepath.Join(src, pkg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !s.Has("example.com/a") || s.Has("example.com/d") {
+		t.Errorf("Has: got %v and %v, want true and false", s.Has("example.com/a"), s.Has("example.com/d"))
+	}
+
+	shadowed := map[string][]byte{}
+	if err := s.ReadGoFiles("example.com/a", shadowed); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for name := range shadowed {
+		names = append(names, name)
+	}
+	if want := []string{"example.com/a/a.go", "example.com/a/gen.go", "example.com/a/stale.go"}; !sameStrings(names, want) {
+		t.Errorf("ReadGoFiles: got %v, want %v", names, want)
+	}
+
+	// Regenerate a and c, and drop the stale file of a.
+	if err := ioutil.WriteFile(filepath.Join(s.Dir("example.com/a"), "gen.go"), []byte("package a\n\n// new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(s.Dir("example.com/a"), "stale.go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(s.Dir("example.com/c"), "gen.go"), []byte("package c\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := s.Results()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]VerifyStatus{}
+	for _, r := range results {
+		rel, _ := filepath.Rel(src, r.Path)
+		got[filepath.ToSlash(rel)] = r.Status
+	}
+	want := map[string]VerifyStatus{
+		"a/gen.go":   VerifyDiffers,
+		"a/stale.go": VerifyExtra,
+		"b/gen.go":   VerifyOK,
+		"c/gen.go":   VerifyMissing,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Results: got %v, want %v", got, want)
+	}
+	if _, ok := s.Verify().(*VerifyError); !ok {
+		t.Errorf("Verify: got %v, want a *VerifyError", s.Verify())
+	}
+
+	if err := s.Export(DiskFS{}); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{"a/gen.go": "package a\n\n// new\n", "c/gen.go": "package c\n", "a/notes.txt": "notes\n"} {
+		if got, err := ioutil.ReadFile(filepath.Join(src, name)); err != nil || string(got) != data {
+			t.Errorf("%s: got %q, %v after the export, want %q", name, got, err, data)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(src, "a/stale.go")); !os.IsNotExist(err) {
+		t.Errorf("stale file not removed: %v", err)
+	}
+	if err := s.Verify(); err != nil {
+		t.Errorf("Verify after the export: %v", err)
+	}
+
+	inputs, generated := s.Manifest()
+	if inputs["example.com/c"] != filepath.Join(src, "c") {
+		t.Errorf("Manifest inputs: got %v", inputs)
+	}
+	if want := []string{filepath.Join(src, "c", "gen.go")}; !reflect.DeepEqual(generated["example.com/c"], want) {
+		t.Errorf("Manifest generated files of c: got %v, want %v", generated["example.com/c"], want)
+	}
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := map[string]int{}
+	for _, s := range a {
+		seen[s]++
+	}
+	for _, s := range b {
+		seen[s]--
+	}
+	for _, n := range seen {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,215 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/vine-io/gogogen/util/diff"
+)
+
+// VerifyStatus is the outcome of verifying a generated file.
+type VerifyStatus string
+
+const (
+	// The file on disk is up to date.
+	VerifyOK VerifyStatus = "ok"
+	// The file on disk differs from the output.
+	VerifyDiffers VerifyStatus = "differs"
+	// The file is generated, but doesn't exist on disk.
+	VerifyMissing VerifyStatus = "missing"
+	// The file exists on disk, but isn't generated anymore.
+	VerifyExtra VerifyStatus = "extra"
+)
+
+// VerifyResult is the outcome of verifying one generated file.
+type VerifyResult struct {
+	// The import path of the package of the file.
+	Package string
+	// The path of the file on disk.
+	Path   string
+	Status VerifyStatus
+	// The unified diff from the file on disk to the output, if they differ.
+	Diff string
+}
+
+// StaleFileError is returned by FileType.VerifyFile when the file on disk
+// isn't up to date.
+type StaleFileError struct {
+	Path   string
+	Status VerifyStatus
+	Diff   string
+}
+
+func (e *StaleFileError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Status)
+}
+
+// VerifyError is returned by the Execute* calls of a context in verify mode
+// when some generated files on disk aren't up to date. It holds the results
+// of all the verified files.
+type VerifyError struct {
+	Results []VerifyResult
+}
+
+func (e *VerifyError) Error() string {
+	n := 0
+	for _, r := range e.Results {
+		if r.Status != VerifyOK {
+			n++
+		}
+	}
+	return fmt.Sprintf("%d of %d generated files are out of date", n, len(e.Results))
+}
+
+// Report writes the diffs of the files which aren't up to date to w,
+// followed by a table of the results.
+func (e *VerifyError) Report(w io.Writer) {
+	results := append([]VerifyResult{}, e.Results...)
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Package != results[j].Package {
+			return results[i].Package < results[j].Package
+		}
+		return results[i].Path < results[j].Path
+	})
+
+	for _, r := range results {
+		if r.Diff != "" {
+			fmt.Fprintln(w, r.Diff)
+		}
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tFILE\tSTATUS")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Package, r.Path, r.Status)
+	}
+	tw.Flush()
+	fmt.Fprintln(w, e.Error())
+}
+
+// Stale returns whether some of the results aren't ok.
+func (e *VerifyError) Stale() bool {
+	for _, r := range e.Results {
+		if r.Status != VerifyOK {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyData compares the output for a file with the file at path on disk.
+func VerifyData(path string, data []byte) *StaleFileError {
+	existing, err := ioutil.ReadFile(path)
+	if err != nil {
+		return &StaleFileError{Path: path, Status: VerifyMissing, Diff: diff.Unified("/dev/null", path+" (generated)", nil, data)}
+	}
+	if bytes.Equal(existing, data) {
+		return nil
+	}
+	return &StaleFileError{Path: path, Status: VerifyDiffers, Diff: diff.Unified(path, path+" (generated)", existing, data)}
+}
+
+// VerifyDir compares the files of dir with those of generated, which holds
+// the output for dir, and returns the results for the files for which match
+// returns true. Files of dir missing from generated are reported as extra.
+func VerifyDir(pkg, generated, dir string, match func(name string) bool) ([]VerifyResult, error) {
+	names := map[string]bool{}
+	for _, d := range []string{generated, dir} {
+		entries, err := ioutil.ReadDir(d)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, e := range entries {
+			if !e.IsDir() && match(e.Name()) {
+				names[e.Name()] = true
+			}
+		}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var results []VerifyResult
+	for _, name := range sorted {
+		path := filepath.Join(dir, name)
+		data, err := ioutil.ReadFile(filepath.Join(generated, name))
+		if os.IsNotExist(err) {
+			results = append(results, VerifyResult{Package: pkg, Path: path, Status: VerifyExtra})
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		results = append(results, verifyResult(pkg, path, VerifyData(path, data)))
+	}
+	return results, nil
+}
+
+// extraFiles returns the generated files left in the directories of the input
+// packages which weren't verified, e.g. because their package doesn't need
+// generation anymore. These are the files owned by c.Owner if it is set, and
+// otherwise the generated files named like the files of results.
+func (c *Context) extraFiles(outDir string, results []VerifyResult) []VerifyResult {
+	if c.Owner != "" {
+		return c.staleFiles(outDir)
+	}
+	names := map[string]bool{}
+	paths := map[string]bool{}
+	for _, r := range results {
+		names[filepath.Base(r.Path)] = true
+		paths[r.Path] = true
+	}
+
+	var extra []VerifyResult
+	for dir, pkg := range c.inputDirs(outDir) {
+		for name := range names {
+			path := filepath.Join(dir, name)
+			if !paths[path] && isGenerated(path) {
+				extra = append(extra, VerifyResult{Package: pkg, Path: path, Status: VerifyExtra})
+			}
+		}
+	}
+	sort.Slice(extra, func(i, j int) bool { return extra[i].Path < extra[j].Path })
+	return extra
+}
+
+// isGenerated returns whether the file at path exists and has a "Code
+// generated ... DO NOT EDIT." comment in its header.
+func isGenerated(path string) bool {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	if len(data) > 4096 {
+		data = data[:4096]
+	}
+	data = bytes.ToLower(data)
+	return bytes.Contains(data, []byte("code generated")) && bytes.Contains(data, []byte("do not edit"))
+}
+
+func verifyResult(pkg, path string, stale *StaleFileError) VerifyResult {
+	if stale == nil {
+		return VerifyResult{Package: pkg, Path: path, Status: VerifyOK}
+	}
+	return VerifyResult{Package: pkg, Path: path, Status: stale.Status, Diff: stale.Diff}
+}
@@ -16,7 +16,9 @@ package namer
 
 import (
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/vine-io/gogogen/gogenerator/types"
 )
@@ -140,6 +142,7 @@ type NameStrategy struct {
 
 	// A cache of names thus far assigned by this namer.
 	Names
+	lock sync.Mutex
 }
 
 // IC ensure the first character is uppercase.
@@ -205,13 +208,20 @@ func (ns *NameStrategy) filterDirs(path string) []string {
 
 // See the comment on NameStrategy
 func (ns *NameStrategy) Name(t *types.Type) string {
-	if ns.Names == nil {
-		ns.Names = Names{}
-	}
-	if s, ok := ns.Names[t]; ok {
+	if s, ok := ns.cached(t); ok {
 		return s
 	}
 
+	if t.Origin != nil {
+		// e.g. Array[string] becomes ArrayOfString.
+		names := []string{ns.removePrefixAndSuffix(ns.Name(t.Origin)), "Of"}
+		for _, arg := range t.TypeArgs {
+			names = append(names, ns.removePrefixAndSuffix(ns.Name(arg)))
+		}
+		name := ns.Join(ns.Prefix, names, ns.Suffix)
+		return ns.cache(t, name)
+	}
+
 	if t.Name.Package != "" {
 		dirs := append(ns.filterDirs(t.Name.Package), t.Name.Name)
 		i := ns.PrependPackageNames + 1
@@ -220,14 +230,13 @@ func (ns *NameStrategy) Name(t *types.Type) string {
 			i = dn
 		}
 		name := ns.Join(ns.Prefix, dirs[dn-i:], ns.Suffix)
-		ns.Names[t] = name
-		return name
+		return ns.cache(t, name)
 	}
 
 	// Only anonymous types remain.
 	var name string
 	switch t.Kind {
-	case types.Builtin:
+	case types.Builtin, types.TypeParam:
 		name = ns.Join(ns.Prefix, []string{t.Name.Name}, ns.Suffix)
 	case types.Map:
 		name = ns.Join(ns.Prefix, []string{
@@ -241,6 +250,12 @@ func (ns *NameStrategy) Name(t *types.Type) string {
 			"Slice",
 			ns.removePrefixAndSuffix(ns.Name(t.Elem)),
 		}, ns.Suffix)
+	case types.Array:
+		name = ns.Join(ns.Prefix, []string{
+			"Array",
+			strconv.FormatInt(t.Len, 10),
+			ns.removePrefixAndSuffix(ns.Name(t.Elem)),
+		}, ns.Suffix)
 	case types.Pointer:
 		name = ns.Join(ns.Prefix, []string{
 			"Pointer",
@@ -253,8 +268,15 @@ func (ns *NameStrategy) Name(t *types.Type) string {
 		}
 		name = ns.Join(ns.Prefix, names, ns.Suffix)
 	case types.Chan:
+		dir := "Chan"
+		switch t.ChanDir {
+		case types.SendOnly:
+			dir = "SendChan"
+		case types.RecvOnly:
+			dir = "ReceiveChan"
+		}
 		name = ns.Join(ns.Prefix, []string{
-			"Chan",
+			dir,
 			ns.removePrefixAndSuffix(ns.Name(t.Elem)),
 		}, ns.Suffix)
 	case types.Interface:
@@ -279,6 +301,26 @@ func (ns *NameStrategy) Name(t *types.Type) string {
 	default:
 		name = "unnameable_" + string(t.Kind)
 	}
+	return ns.cache(t, name)
+}
+
+// cached returns the cached name of t. The cache is locked, as namers may be
+// shared by generators running concurrently; names are computed outside of
+// the lock, so the same name is at worst computed twice.
+func (ns *NameStrategy) cached(t *types.Type) (string, bool) {
+	ns.lock.Lock()
+	defer ns.lock.Unlock()
+	name, ok := ns.Names[t]
+	return name, ok
+}
+
+// cache caches and returns the name of t.
+func (ns *NameStrategy) cache(t *types.Type, name string) string {
+	ns.lock.Lock()
+	defer ns.lock.Unlock()
+	if ns.Names == nil {
+		ns.Names = Names{}
+	}
 	ns.Names[t] = name
 	return name
 }
@@ -296,49 +338,48 @@ type rawNamer struct {
 	pkg     string
 	tracker ImportTracker
 	Names
+	lock sync.Mutex
 }
 
 // Name makes a name the way you'd write it to literally refer to type t,
 // making ordinary assumptions about how you've imported t's package (or using
 // r.tracker to specifically track the package imports).
 func (r *rawNamer) Name(t *types.Type) string {
-	if r.Names == nil {
-		r.Names = Names{}
-	}
-	if name, ok := r.Names[t]; ok {
+	if name, ok := r.cached(t); ok {
 		return name
 	}
+	if t.Origin != nil {
+		// Instantiated generic types are written with their type
+		// arguments, each of which may need an import of its own.
+		args := make([]string, 0, len(t.TypeArgs))
+		for _, arg := range t.TypeArgs {
+			args = append(args, r.Name(arg))
+		}
+		name := r.qualifiedName(t.Origin) + "[" + strings.Join(args, ", ") + "]"
+		return r.cache(t, name)
+	}
 	switch t.Kind {
 	case types.Alias:
 		return r.Name(t.Underlying)
+	case types.Pointer:
+		// Pointers are named after their element, package included, which
+		// must be qualified on its own.
+		return r.cache(t, "*"+r.Name(t.Elem))
 	}
 	if t.Name.Package != "" {
-		var name string
-		if r.tracker != nil {
-			r.tracker.AddType(t)
-			if t.Name.Package == r.pkg {
-				name = t.Name.Name
-			} else {
-				name = r.tracker.LocalNameOf(t.Name.Package) + "." + t.Name.Name
-			}
-		} else {
-			if t.Name.Package == r.pkg {
-				name = t.Name.Name
-			} else {
-				name = filepath.Join(t.Name.Package) + "." + t.Name.Name
-			}
-		}
-		r.Names[t] = name
-		return name
+		name := r.qualifiedName(t)
+		return r.cache(t, name)
 	}
 	var name string
 	switch t.Kind {
-	case types.Builtin:
+	case types.Builtin, types.TypeParam:
 		name = t.Name.Name
 	case types.Map:
 		name = "map[" + r.Name(t.Key) + "]" + r.Name(t.Elem)
 	case types.Slice:
 		name = "[]" + r.Name(t.Elem)
+	case types.Array:
+		name = "[" + strconv.FormatInt(t.Len, 10) + "]" + r.Name(t.Elem)
 	case types.Pointer:
 		name = "*" + r.Name(t.Elem)
 	case types.Struct:
@@ -348,8 +389,19 @@ func (r *rawNamer) Name(t *types.Type) string {
 		}
 		name = "struct{" + strings.Join(elems, "; ") + "}"
 	case types.Chan:
-		// TODO: include directionality
-		name = "chan " + r.Name(t.Elem)
+		elem := r.Name(t.Elem)
+		switch t.ChanDir {
+		case types.SendOnly:
+			name = "chan<- " + elem
+		case types.RecvOnly:
+			name = "<-chan " + elem
+		default:
+			// chan (<-chan T) must not be read as chan<- (chan T).
+			if t.Elem.Kind == types.Chan && t.Elem.ChanDir == types.RecvOnly && t.Elem.Name.Package == "" {
+				elem = "(" + elem + ")"
+			}
+			name = "chan " + elem
+		}
 	case types.Interface:
 		// TODO: add to name set
 		elems := []string{}
@@ -377,6 +429,40 @@ func (r *rawNamer) Name(t *types.Type) string {
 	default:
 		name = "unnameable_" + string(t.Kind)
 	}
+	return r.cache(t, name)
+}
+
+// qualifiedName returns the name of a named type, prefixed by the local name
+// of its package when it lives outside of r.pkg.
+func (r *rawNamer) qualifiedName(t *types.Type) string {
+	if r.tracker != nil {
+		r.tracker.AddType(t)
+		if t.Name.Package == r.pkg {
+			return t.Name.Name
+		}
+		return r.tracker.LocalNameOf(t.Name.Package) + "." + t.Name.Name
+	}
+	if t.Name.Package == r.pkg {
+		return t.Name.Name
+	}
+	return filepath.Join(t.Name.Package) + "." + t.Name.Name
+}
+
+// cached returns the cached name of t, see NameStrategy.cached.
+func (r *rawNamer) cached(t *types.Type) (string, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	name, ok := r.Names[t]
+	return name, ok
+}
+
+// cache caches and returns the name of t.
+func (r *rawNamer) cache(t *types.Type, name string) string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.Names == nil {
+		r.Names = Names{}
+	}
 	r.Names[t] = name
 	return name
 }
@@ -0,0 +1,66 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namer
+
+import (
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/vine-io/gogogen/gogenerator/types"
+)
+
+func TestRawNamerPointers(t *testing.T) {
+	local := &types.Type{Name: types.Name{Package: "example.com/api", Name: "Local"}, Kind: types.Struct}
+	other := &types.Type{Name: types.Name{Package: "example.com/other", Name: "T"}, Kind: types.Struct}
+	// The parser names the pointers to named types like go/types prints
+	// them, which puts the star in front of the package path.
+	pointer := func(elem *types.Type) *types.Type {
+		name := types.Name{Name: "*" + elem.Name.Name}
+		if elem.Name.Package != "" {
+			name = types.Name{Package: "*" + elem.Name.Package, Name: elem.Name.Name}
+		}
+		return &types.Type{Name: name, Kind: types.Pointer, Elem: elem}
+	}
+	str := types.String
+
+	tracker := NewDefaultImportTracker(types.Name{Package: "example.com/api"})
+	tracker.IsInvalidType = func(*types.Type) bool { return false }
+	tracker.LocalName = func(n types.Name) string { return path.Base(n.Package) }
+	r := NewRawNamer("example.com/api", &tracker)
+
+	for _, tc := range []struct {
+		t    *types.Type
+		want string
+	}{
+		{pointer(local), "*Local"},
+		{pointer(other), "*other.T"},
+		{pointer(pointer(other)), "**other.T"},
+		{pointer(str), "*string"},
+		{&types.Type{Name: types.Name{Name: "[]*example.com/other.T"}, Kind: types.Slice, Elem: pointer(other)}, "[]*other.T"},
+	} {
+		if got := r.Name(tc.t); got != tc.want {
+			t.Errorf("%v: got %q, want %q", tc.t.Name, got, tc.want)
+		}
+	}
+	if got, want := tracker.ImportLines(), map[string]string{"example.com/other": "other"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got imports %v, want %v", got, want)
+	}
+
+	// Without a tracker, packages are named after their path.
+	if got, want := NewRawNamer("example.com/api", nil).Name(pointer(other)), "*example.com/other.T"; got != want {
+		t.Errorf("without a tracker: got %q, want %q", got, want)
+	}
+}
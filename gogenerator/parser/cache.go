@@ -0,0 +1,466 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/token"
+	tc "go/types"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vine-io/gogogen/util/log"
+	forkedexport "github.com/vine-io/gogogen/util/third_party/forked/golang/gcexportdata"
+	"golang.org/x/tools/go/gcexportdata"
+)
+
+// cacheVersion is part of every cache key, change it whenever the contents of
+// cachedPackage change.
+const cacheVersion = "gogogen-cache-2"
+
+// cachedPackage is what the cache stores for a type-checked package: its
+// export data and, since export data doesn't keep comments, the comments of
+// its files. Export data is read back with the line of each declaration only,
+// so the files also keep the full positions of the declared names. Aliases are
+// replaced by the types they denote.
+type cachedPackage struct {
+	ExportData []byte
+	Files      []cachedFile
+}
+
+type cachedFile struct {
+	Name    string
+	Imports []string
+	// The index of the package doc comment in Comments, or -1.
+	Doc      int
+	Comments []cachedCommentGroup
+	Decls    []cachedDecl
+}
+
+// cachedDecl is the position of a name declared in a file: types, functions,
+// variables, constants, struct fields and interface methods.
+type cachedDecl struct {
+	Name                 string
+	Offset, Line, Column int
+}
+
+// declKey identifies a declared name in the fake positions of packages read
+// from the cache.
+type declKey struct {
+	fileLine
+	name string
+}
+
+type cachedCommentGroup struct {
+	EndLine int
+	// The line and text of each comment in the group.
+	Lines []int
+	Texts []string
+}
+
+// packageCache holds the state of the cache in a Builder, see CacheDir.
+type packageCache struct {
+	// Map of the user-provided names of packages to their cache keys. An
+	// empty key means that the package can't be cached.
+	keys map[string]string
+
+	// Map of packages which were parsed from source to their cache keys.
+	pending map[importPathString]string
+
+	// Map of packages found in the cache to their entry.
+	loaded map[importPathString]*cachedPackage
+
+	// Map of the names declared by the packages found in the cache to their
+	// positions, see objectPosition.
+	positions map[declKey]token.Position
+
+	// The version of the Go toolchain whose standard library is parsed, see
+	// gorootVersion.
+	gorootVersion string
+}
+
+// cacheKey returns the key of the package in dir. It covers the Go version,
+// the build configuration, the files of the package and, recursively, the keys
+// of the packages it imports. It returns "" if the package can't be cached.
+func (b *Builder) cacheKey(dir string) string {
+	b.cacheMu.Lock()
+	defer b.cacheMu.Unlock()
+	return b.cacheKeyLocked(dir, map[string]bool{})
+}
+
+func (b *Builder) cacheKeyLocked(dir string, visiting map[string]bool) string {
+	if key, ok := b.cache.keys[dir]; ok {
+		return key
+	}
+	if visiting[dir] {
+		// Import cycles don't type-check, there is nothing to cache.
+		return ""
+	}
+	visiting[dir] = true
+	defer delete(visiting, dir)
+
+	key, err := b.computeCacheKey(dir, visiting)
+	if err != nil {
+		log.Debugf("not caching %s: %v", dir, err)
+	}
+	b.cache.keys[dir] = key
+	return key
+}
+
+func (b *Builder) computeCacheKey(dir string, visiting map[string]bool) (string, error) {
+	buildPkg, err := b.findBuildPackage(dir)
+	if err != nil {
+		return "", err
+	}
+
+	// The version of the type checker is part of the key, export data of
+	// one version doesn't have to type-check the same with another.
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s/%s\n", cacheVersion, runtime.Version(), b.context.GOOS, b.context.GOARCH)
+	fmt.Fprintf(h, "tags=%s\ntests=%t\n", strings.Join(b.context.BuildTags, ","), b.IncludeTestFiles)
+	fmt.Fprintf(h, "%s\n%s\n", buildPkg.ImportPath, buildPkg.Dir)
+
+	// The standard library and the module cache are read-only, the version
+	// of the toolchain and the paths in the module cache identify their
+	// contents.
+	if buildPkg.Goroot {
+		version, err := b.gorootVersion()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "goroot %s\n", version)
+	} else if !inModuleCache(buildPkg.Dir) {
+		for _, path := range b.packageFiles(buildPkg) {
+			f, err := os.Open(path)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(h, "file %s\n", filepath.Base(path))
+			_, err = io.Copy(h, f)
+			f.Close()
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	imports := append([]string{}, buildPkg.Imports...)
+	if b.IncludeTestFiles {
+		imports = append(imports, buildPkg.TestImports...)
+	}
+	sort.Strings(imports)
+	for _, imp := range imports {
+		if imp == "C" || imp == "unsafe" {
+			continue
+		}
+		depKey := b.cacheKeyLocked(imp, visiting)
+		if depKey == "" {
+			return "", fmt.Errorf("import %q can't be cached", imp)
+		}
+		fmt.Fprintf(h, "import %s %s\n", imp, depKey)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// gorootVersion returns the version of the Go toolchain in $GOROOT, which
+// isn't the one the generator was built with when another toolchain is
+// installed. It is read from $GOROOT/VERSION, or asked to the go command for
+// toolchains built from source which have no such file.
+func (b *Builder) gorootVersion() (string, error) {
+	if b.cache.gorootVersion != "" {
+		return b.cache.gorootVersion, nil
+	}
+	version := ""
+	if data, err := ioutil.ReadFile(filepath.Join(b.context.GOROOT, "VERSION")); err == nil {
+		version = strings.TrimSpace(strings.SplitN(string(data), "\n", 2)[0])
+	} else {
+		cmd := exec.Command(filepath.Join(b.context.GOROOT, "bin", "go"), "env", "GOVERSION")
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("unable to find the version of the Go toolchain in %s: %v", b.context.GOROOT, err)
+		}
+		version = strings.TrimSpace(string(out))
+	}
+	if version == "" {
+		return "", fmt.Errorf("unable to find the version of the Go toolchain in %s", b.context.GOROOT)
+	}
+	b.cache.gorootVersion = version
+	return version, nil
+}
+
+// inModuleCache returns whether dir is in the read-only module cache.
+func inModuleCache(dir string) bool {
+	modCache := os.Getenv("GOMODCACHE")
+	if modCache == "" {
+		gopath := filepath.SplitList(build.Default.GOPATH)
+		if len(gopath) == 0 {
+			return false
+		}
+		modCache = filepath.Join(gopath[0], "pkg", "mod")
+	}
+	return strings.HasPrefix(dir, modCache+string(filepath.Separator))
+}
+
+// loadCached returns the cache entry of the package in dir, or nil if there
+// is none. Otherwise the package is remembered to be stored once it has been
+// type-checked.
+func (b *Builder) loadCached(dir string, pkgPath importPathString) *cachedPackage {
+	if b.CacheDir == "" {
+		return nil
+	}
+	key := b.cacheKey(dir)
+	if key == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(b.CacheDir, key))
+	if err == nil {
+		cp := &cachedPackage{}
+		if err = gob.NewDecoder(bytes.NewReader(data)).Decode(cp); err == nil {
+			log.Debugf("loaded %s from cache", pkgPath)
+			return cp
+		}
+	}
+	if !os.IsNotExist(err) {
+		log.Warnf("Ignoring cache entry of %s: %v", pkgPath, err)
+	}
+
+	b.cacheMu.Lock()
+	b.cache.pending[pkgPath] = key
+	b.cacheMu.Unlock()
+	return nil
+}
+
+// recordCachedPackage is the counterpart of recordFile for packages loaded
+// from the cache. The comments of their files are given fake positions which
+// resolve to the original file names and lines.
+func (b *Builder) recordCachedPackage(pkgPath importPathString, cp *cachedPackage, userRequested bool) {
+	b.userRequested[pkgPath] = userRequested || b.userRequested[pkgPath]
+	if b.importGraph[pkgPath] == nil {
+		b.importGraph[pkgPath] = map[string]struct{}{}
+	}
+	b.cache.loaded[pkgPath] = cp
+
+	for _, cf := range cp.Files {
+		maxLine := 1
+		for _, cg := range cf.Comments {
+			if cg.EndLine > maxLine {
+				maxLine = cg.EndLine
+			}
+		}
+		// Each line of the fake file is one byte long.
+		tf := b.fset.AddFile(cf.Name, -1, maxLine)
+		lines := make([]int, maxLine)
+		for i := range lines {
+			lines[i] = i
+		}
+		tf.SetLines(lines)
+
+		file := &ast.File{Name: ast.NewIdent(""), Package: token.Pos(tf.Base())}
+		for i, cg := range cf.Comments {
+			group := &ast.CommentGroup{}
+			for j := range cg.Lines {
+				group.List = append(group.List, &ast.Comment{Slash: tf.LineStart(cg.Lines[j]), Text: cg.Texts[j]})
+			}
+			file.Comments = append(file.Comments, group)
+			if i == cf.Doc {
+				file.Doc = group
+			}
+			b.endLineToCommentGroup[fileLine{cf.Name, cg.EndLine}] = group
+		}
+		for _, d := range cf.Decls {
+			key := declKey{fileLine{cf.Name, d.Line}, d.Name}
+			if _, ok := b.cache.positions[key]; !ok {
+				b.cache.positions[key] = token.Position{Filename: cf.Name, Offset: d.Offset, Line: d.Line, Column: d.Column}
+			}
+		}
+		for _, imp := range cf.Imports {
+			file.Imports = append(file.Imports, &ast.ImportSpec{Path: &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(imp)}})
+			b.importGraph[pkgPath][imp] = struct{}{}
+		}
+		b.parsed[pkgPath] = append(b.parsed[pkgPath], parsedFile{cf.Name, file})
+	}
+}
+
+// readCached creates the package from the export data of its cache entry. All
+// the packages it imports are loaded first, so that the types it refers to
+// are shared with them.
+func (b *Builder) readCached(pkgPath importPathString, cp *cachedPackage, importer tc.Importer) (*tc.Package, error) {
+	imports := map[string]*tc.Package{}
+	var add func(pkg *tc.Package)
+	add = func(pkg *tc.Package) {
+		if _, ok := imports[pkg.Path()]; ok {
+			return
+		}
+		imports[pkg.Path()] = pkg
+		for _, imp := range pkg.Imports() {
+			add(imp)
+		}
+	}
+	for _, cf := range cp.Files {
+		for _, imp := range cf.Imports {
+			if imp == "C" || imp == "unsafe" {
+				continue
+			}
+			if pkg, err := importer.Import(imp); err == nil && pkg != nil {
+				add(pkg)
+			}
+		}
+	}
+
+	pkg, err := gcexportdata.Read(bytes.NewReader(cp.ExportData), b.fset, imports, string(pkgPath))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read cached %q, consider removing %s: %v", pkgPath, b.CacheDir, err)
+	}
+	return pkg, nil
+}
+
+// storeCached writes the cache entry of a package which was type-checked
+// without errors.
+func (b *Builder) storeCached(pkgPath importPathString, files []parsedFile, pkg *tc.Package) {
+	b.cacheMu.Lock()
+	key, ok := b.cache.pending[pkgPath]
+	delete(b.cache.pending, pkgPath)
+	b.cacheMu.Unlock()
+	if !ok {
+		return
+	}
+
+	cp := &cachedPackage{}
+	var buf bytes.Buffer
+	if err := forkedexport.Write(&buf, b.fset, pkg); err != nil {
+		// Not every package can be represented in export data.
+		log.Debugf("not caching %s: %v", pkgPath, err)
+		return
+	}
+	cp.ExportData = buf.Bytes()
+	for _, f := range files {
+		cf := cachedFile{Name: f.name, Doc: -1}
+		for _, im := range f.file.Imports {
+			cf.Imports = append(cf.Imports, strings.Trim(im.Path.Value, `"`))
+		}
+		for i, c := range f.file.Comments {
+			if c == f.file.Doc {
+				cf.Doc = i
+			}
+			cg := cachedCommentGroup{EndLine: b.fset.Position(c.End()).Line}
+			for _, comment := range c.List {
+				cg.Lines = append(cg.Lines, b.fset.Position(comment.Slash).Line)
+				cg.Texts = append(cg.Texts, comment.Text)
+			}
+			cf.Comments = append(cf.Comments, cg)
+		}
+		for _, ident := range declaredIdents(f.file) {
+			p := b.fset.Position(ident.Pos())
+			cf.Decls = append(cf.Decls, cachedDecl{Name: ident.Name, Offset: p.Offset, Line: p.Line, Column: p.Column})
+		}
+		cp.Files = append(cp.Files, cf)
+	}
+
+	if err := writeCacheFile(filepath.Join(b.CacheDir, key), cp); err != nil {
+		log.Warnf("Unable to cache %s: %v", pkgPath, err)
+	}
+}
+
+// declaredIdents returns the identifiers which declare the names of a file,
+// those are where go/types puts the positions of its objects. An embedded
+// field is declared by the name of its type.
+func declaredIdents(file *ast.File) []*ast.Ident {
+	var idents []*ast.Ident
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.TypeSpec:
+			idents = append(idents, n.Name)
+		case *ast.FuncDecl:
+			// Nothing in the bodies is part of the package.
+			idents = append(idents, n.Name)
+			return false
+		case *ast.ValueSpec:
+			idents = append(idents, n.Names...)
+		case *ast.Field:
+			if len(n.Names) != 0 {
+				idents = append(idents, n.Names...)
+			} else if ident := embeddedIdent(n.Type); ident != nil {
+				idents = append(idents, ident)
+			}
+		}
+		return true
+	})
+	return idents
+}
+
+func embeddedIdent(x ast.Expr) *ast.Ident {
+	switch x := x.(type) {
+	case *ast.Ident:
+		return x
+	case *ast.StarExpr:
+		return embeddedIdent(x.X)
+	case *ast.SelectorExpr:
+		return x.Sel
+	case *ast.IndexExpr:
+		return embeddedIdent(x.X)
+	case *ast.IndexListExpr:
+		return embeddedIdent(x.X)
+	case *ast.ParenExpr:
+		return embeddedIdent(x.X)
+	}
+	return nil
+}
+
+// objectPosition returns the position of an object. The objects of packages
+// read from the cache only have the line of their position, the rest is
+// looked up in the declarations of their files.
+func (b *Builder) objectPosition(obj tc.Object) token.Position {
+	p := b.fset.Position(obj.Pos())
+	if full, ok := b.cache.positions[declKey{fileLine{p.Filename, p.Line}, obj.Name()}]; ok {
+		return full
+	}
+	return p
+}
+
+// writeCacheFile writes the entry to a temporary file first, so concurrent
+// runs never read partial entries.
+func writeCacheFile(path string, cp *cachedPackage) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(tmp).Encode(cp); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
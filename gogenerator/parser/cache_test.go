@@ -0,0 +1,187 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/vine-io/gogogen/gogenerator/types"
+)
+
+func TestCache(t *testing.T) {
+	root := t.TempDir()
+	for name, data := range map[string]string{
+		"go.mod": "module example.com/cm\n\ngo 1.18\n",
+		"a/a.go": `// Package a is cached.
+package a
+
+// Phase is a phase.
+// +enum
+type Phase string
+
+const (
+	// Pending is pending.
+	Pending Phase = "Pending"
+	Done    Phase = "Done"
+)
+
+// A is a struct.
+type A struct {
+	// Name is a name.
+	Name string ` + "`json:\"name\"`" + `
+	Phase Phase
+}
+
+// Get returns the name.
+func (a *A) Get() string { return a.Name }
+`,
+		"b/b.go": `package b
+
+import (
+	"strings"
+
+	"example.com/cm/a"
+)
+
+// +marker
+type B struct {
+	A     *a.A
+	Names []string
+}
+
+func Upper(b B) string { return strings.ToUpper(b.A.Name) }
+
+type Pair struct{ X, Y int }
+`,
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cacheDir := t.TempDir()
+
+	// describe lists what the generators see of the packages.
+	describe := func(u types.Universe) []string {
+		var lines []string
+		add := func(format string, args ...interface{}) { lines = append(lines, fmt.Sprintf(format, args...)) }
+		for _, path := range []string{"example.com/cm/a", "example.com/cm/b"} {
+			pkg := u.Package(path)
+			add("package %s %q %q", path, pkg.Comments, pkg.DocComments)
+			for _, name := range sortedKeys(pkg.Types) {
+				typ := pkg.Types[name]
+				add("type %v %v %v %q %q", typ.Name, typ.Kind, typ.Position, typ.CommentLines, typ.SecondClosestCommentLines)
+				for _, m := range typ.Members {
+					add("  member %s %v %q %q %v", m.Name, m.Type.Name, m.Tags, m.CommentLines, m.Position)
+				}
+				for _, name := range sortedKeys(typ.Methods) {
+					add("  method %s %v %q %v", name, typ.Methods[name].Name, typ.Methods[name].CommentLines, typ.Methods[name].Position)
+				}
+				for _, v := range typ.Enum {
+					add("  value %s %s %q %v", v.Name, v.Value, v.CommentLines, v.Position)
+				}
+			}
+			for _, name := range sortedKeys(pkg.Functions) {
+				add("func %s %v", name, pkg.Functions[name].Position)
+			}
+		}
+		return lines
+	}
+	parse := func() (types.Universe, *Builder) {
+		t.Helper()
+		b := NewWithModules(root)
+		b.CacheDir = cacheDir
+		if err := b.AddDir("example.com/cm/b"); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.AddDir("example.com/cm/a"); err != nil {
+			t.Fatal(err)
+		}
+		u, err := b.FindTypes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u, b
+	}
+
+	parsed, b := parse()
+	if len(b.cache.loaded) != 0 {
+		t.Errorf("got %d packages from the empty cache", len(b.cache.loaded))
+	}
+	if entries, err := os.ReadDir(cacheDir); err != nil || len(entries) == 0 {
+		t.Fatalf("nothing cached: %v", err)
+	}
+	cached, b := parse()
+	for _, path := range []importPathString{"example.com/cm/a", "example.com/cm/b"} {
+		if b.cache.loaded[path] == nil {
+			t.Errorf("%s not loaded from the cache", path)
+		}
+	}
+	if want := `  member Name string "json:\"name\"" ["Name is a name."] ` + filepath.Join(root, "a", "a.go") + ":17:2"; !strings.Contains(strings.Join(describe(parsed), "\n"), want) {
+		t.Errorf("expected %q in the description of the parsed packages:\n%s", want, strings.Join(describe(parsed), "\n"))
+	}
+	if got, want := describe(cached), describe(parsed); !reflect.DeepEqual(got, want) {
+		t.Errorf("the cached packages differ:\n%s\nparsed:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Changing a file invalidates its package and those importing it.
+	if err := os.WriteFile(filepath.Join(root, "a", "a.go"), []byte("package a\n\ntype A struct{ Name string }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	u, b := parse()
+	for _, path := range []importPathString{"example.com/cm/a", "example.com/cm/b"} {
+		if b.cache.loaded[path] != nil {
+			t.Errorf("%s loaded from the cache after a change", path)
+		}
+	}
+	if members := u.Type(types.Name{Package: "example.com/cm/a", Name: "A"}).Members; len(members) != 1 {
+		t.Errorf("got the members %v of the changed A", members)
+	}
+}
+
+func TestGorootVersion(t *testing.T) {
+	goroot := t.TempDir()
+	if err := os.WriteFile(filepath.Join(goroot, "VERSION"), []byte("go1.99.1\ntime 2030-01-01T00:00:00Z\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b := New()
+	b.context.GOROOT = goroot
+	if v, err := b.gorootVersion(); err != nil || v != "go1.99.1" {
+		t.Errorf("got %q, %v; want go1.99.1", v, err)
+	}
+
+	b = New()
+	if v, err := b.gorootVersion(); err != nil || !strings.HasPrefix(v, "go") {
+		t.Errorf("got %q, %v for the installed toolchain", v, err)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
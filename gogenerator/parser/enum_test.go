@@ -0,0 +1,88 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vine-io/gogogen/gogenerator/types"
+)
+
+func TestEnums(t *testing.T) {
+	root := writeModule(t, map[string]string{
+		"enum/types.go": `package enum
+
+type Phase string
+
+const (
+	// Pending is the first phase.
+	Pending Phase = "Pending"
+	Running Phase = "Running"
+	Failed  Phase = "Failed"
+)
+
+type Level int
+
+const (
+	Low Level = iota + 1
+	High
+)
+
+const Untyped = 1
+
+type Plain string
+`,
+		"other/other.go": `package other
+
+import "example.com/enum"
+
+const Unknown enum.Phase = "Unknown"
+`,
+	})
+	pkg := parseModule(t, root, "example.com/enum", "example.com/other").Package("example.com/enum")
+
+	values := func(t *types.Type) (names, literals []string) {
+		for _, v := range t.Enum {
+			names = append(names, v.Name)
+			literals = append(literals, v.Value)
+		}
+		return names, literals
+	}
+	phase := pkg.Type("Phase")
+	// The values are in declaration order, and the constants of the other
+	// package aren't values of the type.
+	names, literals := values(phase)
+	if want := []string{"Pending", "Running", "Failed"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got the values %v of Phase, want %v", names, want)
+	}
+	if want := []string{`"Pending"`, `"Running"`, `"Failed"`}; !reflect.DeepEqual(literals, want) {
+		t.Errorf("got the literals %v of Phase, want %v", literals, want)
+	}
+	pending := phase.Enum[0]
+	if want := []string{"Pending is the first phase."}; !reflect.DeepEqual(pending.CommentLines, want) {
+		t.Errorf("got the comment %q of Pending, want %q", pending.CommentLines, want)
+	}
+	if pending.Constant != pkg.Constant("Pending") || pending.Position != pending.Constant.Position {
+		t.Errorf("Pending isn't linked to its declaration")
+	}
+
+	if _, literals := values(pkg.Type("Level")); !reflect.DeepEqual(literals, []string{"1", "2"}) {
+		t.Errorf("got the literals %v of Level, want [1 2]", literals)
+	}
+	if !phase.IsEnum() || pkg.Type("Plain").IsEnum() {
+		t.Errorf("IsEnum: got %v for Phase and %v for Plain, want true and false", phase.IsEnum(), pkg.Type("Plain").IsEnum())
+	}
+}
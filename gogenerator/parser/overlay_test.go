@@ -0,0 +1,90 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/vine-io/gogogen/gogenerator/types"
+)
+
+func TestAddOverlay(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "gogogen-overlay-test")
+	src := map[string]string{
+		// The packages of the overlay import each other in either order,
+		// and packages from disk.
+		"example.com/ov/a/a.go": `package a
+
+import "example.com/ov/b"
+
+type A struct {
+	B b.B
+}
+`,
+		"example.com/ov/b/b.go": `package b
+
+import "github.com/vine-io/gogogen/runtime/meta"
+
+type B struct {
+	Meta meta.Meta
+}
+`,
+		"example.com/ov/b/ignored.go": `//go:build ignore
+
+package b
+
+type Ignored struct{}
+`,
+		"example.com/ov/b/b_test.go": `package b
+
+type Test struct{}
+`,
+		"example.com/ov/b/README.md": "not go\n",
+	}
+	files := map[string][]byte{}
+	for name, data := range src {
+		files[name] = []byte(data)
+	}
+	b := NewWithModules("")
+	if err := b.AddOverlay(root, files); err != nil {
+		t.Fatal(err)
+	}
+	u, err := b.FindTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := u.Package("example.com/ov/a")
+	if want := filepath.Join(root, "example.com", "ov", "a"); a.SourcePath != want {
+		t.Errorf("got the source path %s, want %s", a.SourcePath, want)
+	}
+	if got := a.Type("A").Members[0].Type; got != u.Type(types.Name{Package: "example.com/ov/b", Name: "B"}) {
+		t.Errorf("A.B is %v, want the B of the overlay", got)
+	}
+	m := u.Type(types.Name{Package: "github.com/vine-io/gogogen/runtime/meta", Name: "Meta"})
+	if m.Kind != types.Struct || len(m.Members) == 0 {
+		t.Errorf("Meta wasn't read from disk: %v", m.Kind)
+	}
+	bp := u.Package("example.com/ov/b")
+	for _, name := range []string{"Ignored", "Test"} {
+		if bp.Has(name) {
+			t.Errorf("%s is from an excluded file", name)
+		}
+	}
+	if !b.userRequested["example.com/ov/a"] || !b.userRequested["example.com/ov/b"] || b.userRequested["github.com/vine-io/gogogen/runtime/meta"] {
+		t.Errorf("only the overlay packages are user requested: %v", b.userRequested)
+	}
+}
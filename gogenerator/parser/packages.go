@@ -0,0 +1,291 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// moduleLoader resolves import paths and directories to packages with
+// golang.org/x/tools/go/packages, which asks the go command. It therefore
+// understands go.mod, go.work, replace directives and vendor directories the
+// same way `go build` does.
+type moduleLoader struct {
+	// The directory the go command is run in; relative paths are resolved
+	// against it.
+	dir string
+
+	// Map of import paths (and the user-provided names which resolved to
+	// them) to the loaded packages.
+	packages map[string]*packages.Package
+
+	// Guards packages, load may be called concurrently.
+	mu sync.Mutex
+}
+
+// NewWithModules constructs a new builder which resolves packages in module
+// mode, honoring go.mod, go.work and replace directives. Relative paths are
+// resolved against dir, or the current directory if dir is empty.
+func NewWithModules(dir string) *Builder {
+	b := New()
+	b.modules = &moduleLoader{
+		dir:      dir,
+		packages: map[string]*packages.Package{},
+	}
+	return b
+}
+
+// ModulesEnabled returns whether b resolves packages in module mode.
+func (b *Builder) ModulesEnabled() bool {
+	return b.modules != nil
+}
+
+// load returns the package for the given import path or directory. All of its
+// dependencies are loaded alongside, so that the type checker can find them
+// without asking the go command again.
+func (l *moduleLoader) load(dir string, buildTags []string) (*packages.Package, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if pkg, ok := l.packages[dir]; ok {
+		return pkg, nil
+	}
+
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedModule,
+		Dir:  l.dir,
+	}
+	if len(buildTags) != 0 {
+		cfg.BuildFlags = []string{"-tags=" + strings.Join(buildTags, ",")}
+	}
+	pkgs, err := packages.Load(cfg, dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load %q: %v", dir, err)
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("unable to load %q: expected a single package, got %d", dir, len(pkgs))
+	}
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if _, ok := l.packages[pkg.PkgPath]; !ok {
+			l.packages[pkg.PkgPath] = pkg
+		}
+	})
+
+	pkg := pkgs[0]
+	if len(pkg.GoFiles) == 0 && len(pkg.IgnoredFiles) == 0 && len(pkg.Errors) != 0 {
+		// A directory without Go files, e.g. the root of a module, is still
+		// a valid place to look for packages in.
+		empty := l.emptyDir(cfg, dir)
+		if empty == nil {
+			return nil, &packageNotFoundError{path: dir, err: pkg.Errors[0]}
+		}
+		pkg = empty
+	}
+	l.packages[dir] = pkg
+	return pkg, nil
+}
+
+// emptyDir returns a package without files for a directory or import path
+// whose directory holds no Go files itself, e.g. the root of a module. Its
+// import path, or its directory for an import path, is resolved through the
+// module of the packages below it. It returns nil if there are none.
+func (l *moduleLoader) emptyDir(cfg *packages.Config, dir string) *packages.Package {
+	local := build.IsLocalImport(dir) || filepath.IsAbs(dir)
+	abs := dir
+	if local {
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(l.dir, dir)
+			if !filepath.IsAbs(abs) {
+				wd, err := os.Getwd()
+				if err != nil {
+					return nil
+				}
+				abs = filepath.Join(wd, abs)
+			}
+		}
+		if !isDir(abs) {
+			return nil
+		}
+	}
+
+	cfg = &packages.Config{Mode: packages.NeedName | packages.NeedModule, Dir: cfg.Dir, BuildFlags: cfg.BuildFlags}
+	pkgs, err := packages.Load(cfg, dir+"/...")
+	if err != nil {
+		return nil
+	}
+	for _, pkg := range pkgs {
+		m := pkg.Module
+		if m == nil || m.Dir == "" {
+			continue
+		}
+		if !local {
+			if dir != m.Path && !strings.HasPrefix(dir, m.Path+"/") {
+				continue
+			}
+			if !isDir(filepath.Join(m.Dir, filepath.FromSlash(strings.TrimPrefix(dir, m.Path)))) {
+				continue
+			}
+			return &packages.Package{ID: dir, PkgPath: dir, Module: m}
+		}
+		rel, err := filepath.Rel(m.Dir, abs)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		pkgPath := m.Path
+		if rel != "." {
+			pkgPath = path.Join(m.Path, filepath.ToSlash(rel))
+		}
+		return &packages.Package{ID: pkgPath, PkgPath: pkgPath, Module: m}
+	}
+	return nil
+}
+
+// isDir returns whether path is an existing directory.
+func isDir(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
+
+// packageDir returns the directory holding the package.
+func packageDir(pkg *packages.Package) string {
+	for _, files := range [][]string{pkg.GoFiles, pkg.IgnoredFiles, pkg.OtherFiles} {
+		if len(files) != 0 {
+			return filepath.Dir(files[0])
+		}
+	}
+	// A package without any files, e.g. a new output package, lives at its
+	// path relative to the module.
+	if m := pkg.Module; m != nil && m.Dir != "" {
+		if rel := strings.TrimPrefix(pkg.PkgPath, m.Path); rel != pkg.PkgPath {
+			return filepath.Join(m.Dir, filepath.FromSlash(rel))
+		}
+	}
+	return ""
+}
+
+// importModulePackage is the module mode counterpart of importWithMode. The
+// go command resolves the directory, go/build then selects the files within
+// it, so build tags behave the same in both modes.
+func (b *Builder) importModulePackage(dir string, mode build.ImportMode) (*build.Package, error) {
+	pkg, err := b.modules.load(dir, b.context.BuildTags)
+	if err != nil {
+		return nil, err
+	}
+	pkgDir := packageDir(pkg)
+	if pkgDir == "" {
+		return nil, &packageNotFoundError{path: dir}
+	}
+	buildPkg, err := b.context.ImportDir(pkgDir, mode)
+	if buildPkg != nil {
+		buildPkg.ImportPath = pkg.PkgPath
+	}
+	return buildPkg, err
+}
+
+// PackageDir returns the directory on disk of the package with the given
+// import path, which need not exist yet. In module mode it is resolved
+// relative to the module containing it.
+func (b *Builder) PackageDir(importPath string) (string, error) {
+	if dir, ok := b.absPaths[importPathString(importPath)]; ok {
+		return dir, nil
+	}
+	if b.modules == nil {
+		buildPkg, err := b.importWithMode(importPath, build.FindOnly)
+		if err != nil {
+			return "", err
+		}
+		return buildPkg.Dir, nil
+	}
+
+	if pkg, err := b.modules.load(importPath, b.context.BuildTags); err == nil {
+		if dir := packageDir(pkg); dir != "" {
+			return dir, nil
+		}
+	}
+	// The package doesn't exist yet, so place it in the main module which
+	// owns the longest prefix of its path.
+	var owner *packages.Module
+	for _, pkg := range b.modules.packages {
+		m := pkg.Module
+		if m == nil || m.Dir == "" || !m.Main {
+			continue
+		}
+		if importPath != m.Path && !strings.HasPrefix(importPath, m.Path+"/") {
+			continue
+		}
+		if owner == nil || len(m.Path) > len(owner.Path) {
+			owner = m
+		}
+	}
+	if owner == nil {
+		return "", fmt.Errorf("unable to find the directory of %q in any main module", importPath)
+	}
+	rel := strings.TrimPrefix(importPath, owner.Path)
+	return filepath.Join(owner.Dir, filepath.FromSlash(rel)), nil
+}
+
+// PackagePath returns the import path of the package in the directory dir,
+// which must be in module mode, as the go command tells it: go.work files,
+// replace directives and vendor directories are honored. A directory the go
+// command finds no package in, e.g. an empty one below testdata, gets the
+// path below the closest parent directory it does.
+func (b *Builder) PackagePath(dir string) (string, error) {
+	if b.modules == nil {
+		return "", fmt.Errorf("unable to find the import path of %s: not in module mode", dir)
+	}
+	pkg, err := b.modules.load(dir, b.context.BuildTags)
+	if err == nil {
+		return pkg.PkgPath, nil
+	}
+	if !isErrPackageNotFound(err) || !filepath.IsAbs(dir) || !isDir(dir) {
+		return "", err
+	}
+	for parent := filepath.Dir(dir); parent != filepath.Dir(parent); parent = filepath.Dir(parent) {
+		pkg, perr := b.modules.load(parent, b.context.BuildTags)
+		if perr != nil {
+			continue
+		}
+		rel, rerr := filepath.Rel(parent, dir)
+		if rerr != nil {
+			break
+		}
+		return path.Join(pkg.PkgPath, filepath.ToSlash(rel)), nil
+	}
+	return "", err
+}
+
+// packageNotFoundError reports that a package couldn't be resolved at all.
+type packageNotFoundError struct {
+	path string
+	err  error
+}
+
+func (e *packageNotFoundError) Error() string {
+	if e.err != nil {
+		return fmt.Sprintf("cannot find package %q: %v", e.path, e.err)
+	}
+	return fmt.Sprintf("cannot find package %q", e.path)
+}
+
+func (e *packageNotFoundError) Unwrap() error {
+	return e.err
+}
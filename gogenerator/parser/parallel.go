@@ -0,0 +1,242 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"go/build"
+	"go/parser"
+	tc "go/types"
+	"io/ioutil"
+	"runtime"
+	"sync"
+
+	"github.com/vine-io/gogogen/util/log"
+)
+
+// parallelism returns the number of workers used by prefetch.
+func (b *Builder) parallelism() int {
+	if b.Parallelism > 0 {
+		return b.Parallelism
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// prefetch parses the packages in dirs and everything they import, then
+// type-checks the packages that don't depend on each other at the same time.
+// The results are recorded exactly like importPackage records them, so the
+// importPackage calls which follow only have to look them up. Failures are
+// left to those calls too, which report them as the serial path does.
+func (b *Builder) prefetch(dirs []string, userRequested bool) {
+	if b.parallelism() <= 1 {
+		return
+	}
+	if b.parseAll(dirs, userRequested) {
+		b.checkAll()
+	}
+}
+
+// parseAll parses the packages in dirs and their transitive imports with a
+// bounded pool of workers. It returns whether any package was added.
+func (b *Builder) parseAll(dirs []string, userRequested bool) bool {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, b.parallelism())
+	seen := map[string]bool{}
+	added := false
+
+	// parse must be called with b.mu held.
+	var parse func(dir string, userRequested bool)
+	parse = func(dir string, userRequested bool) {
+		if seen[dir] {
+			return
+		}
+		seen[dir] = true
+		if buildPkg := b.buildPackages[dir]; buildPkg != nil {
+			if _, ok := b.parsed[canonicalizeImportPath(buildPkg.ImportPath)]; ok {
+				return
+			}
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			buildPkg, files, cp, err := b.parseDir(dir)
+			<-sem
+			if err != nil {
+				log.Debugf("prefetch %s: %v", dir, err)
+				return
+			}
+
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			buildPkg = b.rememberBuildPackage(dir, buildPkg)
+			pkgPath, err := b.rememberPackageDir(dir, buildPkg)
+			if err != nil {
+				log.Debugf("prefetch %s: %v", dir, err)
+				return
+			}
+			// The package may have been parsed under another name meanwhile.
+			if _, ok := b.parsed[pkgPath]; ok {
+				return
+			}
+			switch {
+			case cp != nil:
+				b.recordCachedPackage(pkgPath, cp, userRequested)
+			case len(files) != 0:
+				for _, f := range files {
+					b.recordFile(pkgPath, f.name, f.file, userRequested)
+				}
+			default:
+				return
+			}
+			added = true
+			for importedPath := range b.importGraph[pkgPath] {
+				parse(importedPath, false)
+			}
+		}()
+	}
+
+	b.mu.Lock()
+	for _, dir := range dirs {
+		parse(dir, userRequested)
+	}
+	b.mu.Unlock()
+	wg.Wait()
+	return added
+}
+
+// parseDir finds and parses the files of the package in dir, like addDir,
+// without recording anything in b. If the package is in the cache, its entry
+// is returned instead of the files.
+func (b *Builder) parseDir(dir string) (*build.Package, []parsedFile, *cachedPackage, error) {
+	b.mu.Lock()
+	buildPkg, ok := b.buildPackages[dir]
+	b.mu.Unlock()
+	if !ok {
+		var err error
+		if buildPkg, err = b.findBuildPackage(dir); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if cp := b.loadCached(dir, canonicalizeImportPath(buildPkg.ImportPath)); cp != nil {
+		return buildPkg, nil, cp, nil
+	}
+
+	var files []parsedFile
+	for _, absPath := range b.packageFiles(buildPkg) {
+		data, err := ioutil.ReadFile(absPath)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		p, err := parser.ParseFile(b.fset, absPath, data, parser.DeclarationErrors|parser.ParseComments)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		files = append(files, parsedFile{absPath, p})
+	}
+	return buildPkg, files, nil, nil
+}
+
+// checkAll type-checks all parsed packages which haven't been yet. A package
+// is checked as soon as all the packages it imports are, so independent
+// packages are checked at the same time by a bounded pool of workers.
+// Packages in import cycles are never started, importPackage reports them.
+func (b *Builder) checkAll() {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, b.parallelism())
+
+	b.mu.Lock()
+
+	// Map of unchecked packages to the unchecked packages they import, and
+	// the other way around.
+	imports := map[importPathString]map[importPathString]bool{}
+	importedBy := map[importPathString][]importPathString{}
+	for pkgPath := range b.parsed {
+		if _, ok := b.typeCheckedPackages[pkgPath]; !ok {
+			imports[pkgPath] = map[importPathString]bool{}
+		}
+	}
+	for pkgPath := range imports {
+		for importedPath := range b.importGraph[pkgPath] {
+			buildPkg := b.buildPackages[importedPath]
+			if buildPkg == nil {
+				continue
+			}
+			dep := canonicalizeImportPath(buildPkg.ImportPath)
+			if _, ok := imports[dep]; !ok || dep == pkgPath || imports[pkgPath][dep] {
+				continue
+			}
+			imports[pkgPath][dep] = true
+			importedBy[dep] = append(importedBy[dep], pkgPath)
+		}
+	}
+
+	// check must be called with b.mu held.
+	var check func(pkgPath importPathString)
+	check = func(pkgPath importPathString) {
+		parsedFiles := b.parsed[pkgPath]
+		cp := b.cache.loaded[pkgPath]
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			log.Debugf("typeCheckPackage %s", pkgPath)
+			// As in importPackage, type errors are not reported for packages
+			// which were just added.
+			pkg, _ := b.check(pkgPath, parsedFiles, cp, prefetchImporter{b})
+			<-sem
+
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if pkg == nil {
+				return
+			}
+			b.typeCheckedPackages[pkgPath] = pkg
+			for _, next := range importedBy[pkgPath] {
+				delete(imports[next], pkgPath)
+				if len(imports[next]) == 0 {
+					check(next)
+				}
+			}
+		}()
+	}
+
+	for pkgPath, deps := range imports {
+		if len(deps) == 0 {
+			check(pkgPath)
+		}
+	}
+	b.mu.Unlock()
+	wg.Wait()
+}
+
+// prefetchImporter is the importer used by checkAll. All imports which could
+// be parsed are checked before the packages importing them, anything else is
+// left to importPackage.
+type prefetchImporter struct {
+	b *Builder
+}
+
+func (a prefetchImporter) Import(path string) (*tc.Package, error) {
+	a.b.mu.Lock()
+	defer a.b.mu.Unlock()
+	if buildPkg := a.b.buildPackages[path]; buildPkg != nil {
+		if pkg := a.b.typeCheckedPackages[canonicalizeImportPath(buildPkg.ImportPath)]; pkg != nil {
+			return pkg, nil
+		}
+	}
+	return a.b.importPackage(path, false)
+}
@@ -15,12 +15,15 @@
 package parser
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/build"
 	"go/parser"
 	"go/token"
 	tc "go/types"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -29,6 +32,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/vine-io/gogogen/gogenerator/types"
 	"github.com/vine-io/gogogen/util/log"
@@ -45,6 +49,21 @@ type Builder struct {
 	// If true, include *_test.go
 	IncludeTestFiles bool
 
+	// The maximum number of packages parsed or type-checked at the same time.
+	// If zero, runtime.GOMAXPROCS(0) is used; one disables concurrency.
+	Parallelism int
+
+	// If set, type-checked packages are cached in this directory, keyed by
+	// the contents of their files, the build tags and the Go version.
+	// Unchanged packages are then loaded from the cache instead of being
+	// parsed and type-checked again.
+	CacheDir string
+
+	// If set, the comment markers of the packages the user requested are
+	// checked against this registry, and unknown, misplaced or malformed
+	// markers are logged as warnings.
+	Markers *types.MarkerRegistry
+
 	// Map of package names to more canonical information about the package.
 	// This might hold the same value for multiple names, e.g. if someone
 	// referenced ./pkg/name or in the case of vendoring, which canonicalizes
@@ -69,6 +88,20 @@ type Builder struct {
 
 	// map of package to list of packages it imports.
 	importGraph map[importPathString]map[string]struct{}
+
+	// If set, packages are resolved in module mode. See NewWithModules.
+	modules *moduleLoader
+
+	// Type parameters aren't named in any package, so they are tracked by
+	// their declaring object instead of living in the Universe.
+	typeParams map[*tc.TypeParam]*types.Type
+
+	// Guards the maps above while prefetch runs.
+	mu sync.Mutex
+
+	// State of the package cache, guarded by cacheMu. See CacheDir.
+	cache   packageCache
+	cacheMu sync.Mutex
 }
 
 // parsedFile is for tracking files with name
@@ -107,6 +140,13 @@ func New() *Builder {
 		userRequested:         map[importPathString]bool{},
 		endLineToCommentGroup: map[fileLine]*ast.CommentGroup{},
 		importGraph:           map[importPathString]map[string]struct{}{},
+		typeParams:            map[*tc.TypeParam]*types.Type{},
+		cache: packageCache{
+			keys:      map[string]string{},
+			pending:   map[importPathString]string{},
+			loaded:    map[importPathString]*cachedPackage{},
+			positions: map[declKey]token.Position{},
+		},
 	}
 }
 
@@ -122,11 +162,21 @@ func (b *Builder) importBuildPackage(dir string) (*build.Package, error) {
 	if buildPkg, ok := b.buildPackages[dir]; ok {
 		return buildPkg, nil
 	}
+	buildPkg, err := b.findBuildPackage(dir)
+	if err != nil {
+		return nil, err
+	}
+	return b.rememberBuildPackage(dir, buildPkg), nil
+}
+
+// findBuildPackage is the uncached part of importBuildPackage, it doesn't
+// modify b.
+func (b *Builder) findBuildPackage(dir string) (*build.Package, error) {
 	// This validates the `package foo // github.com/bar/foo` comments.
 	buildPkg, err := b.importWithMode(dir, build.ImportComment)
 	if err != nil {
 		if _, ok := err.(*build.NoGoError); !ok {
-			return nil, fmt.Errorf("unable to import %q: %v", dir, err)
+			return nil, fmt.Errorf("unable to import %q: %w", dir, err)
 		}
 	}
 	if buildPkg == nil {
@@ -136,7 +186,12 @@ func (b *Builder) importBuildPackage(dir string) (*build.Package, error) {
 			return nil, err
 		}
 	}
+	return buildPkg, nil
+}
 
+// rememberBuildPackage caches buildPkg under dir and its canonical name and
+// returns the package known under the canonical name.
+func (b *Builder) rememberBuildPackage(dir string, buildPkg *build.Package) *build.Package {
 	// Remember it under the user-provided name.
 	log.Debugf("saving buildPackage %s", dir)
 	b.buildPackages[dir] = buildPkg
@@ -144,14 +199,14 @@ func (b *Builder) importBuildPackage(dir string) (*build.Package, error) {
 	if dir != string(canonicalPackage) {
 		// Since `dir` is not the canonical name, see if we knew it under another name.
 		if buildPkg, ok := b.buildPackages[string(canonicalPackage)]; ok {
-			return buildPkg, nil
+			return buildPkg
 		}
 		// Must be new, save it under the canonical name, too.
 		log.Debugf("saving buildPackage %s", canonicalPackage)
 		b.buildPackages[string(canonicalPackage)] = buildPkg
 	}
 
-	return buildPkg, nil
+	return buildPkg
 }
 
 // AddFileForTest adds a file to the set, without verifying that the provided
@@ -170,6 +225,50 @@ func (b *Builder) AddFileForTest(pkg string, path string, src []byte) error {
 	return nil
 }
 
+// AddOverlay adds in-memory go files to the set as user-requested packages,
+// without reading them from disk. The keys of src are the import path of the
+// package of a file followed by its name, e.g. "example.com/pkg/types.go",
+// and each file is given the path it would have under 'root'. Imports which
+// are not in src are resolved as usual. Files are excluded by their build
+// constraints like files on disk.
+func (b *Builder) AddOverlay(root string, src map[string][]byte) error {
+	keys := make([]string, 0, len(src))
+	for key := range src {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pkgPaths := []importPathString{}
+	for _, key := range keys {
+		if !strings.HasSuffix(key, ".go") || (!b.IncludeTestFiles && strings.HasSuffix(key, "_test.go")) {
+			continue
+		}
+		pkgPath := importPathString(path.Dir(key))
+		absPath := filepath.Join(root, filepath.FromSlash(key))
+		if ok, err := b.matchFile(absPath, src[key]); err != nil {
+			return fmt.Errorf("while parsing %q: %v", key, err)
+		} else if !ok {
+			continue
+		}
+		if _, found := b.absPaths[pkgPath]; !found {
+			b.absPaths[pkgPath] = filepath.Dir(absPath)
+			pkgPaths = append(pkgPaths, pkgPath)
+		}
+		if err := b.addFile(pkgPath, absPath, src[key], true); err != nil {
+			return fmt.Errorf("while parsing %q: %v", key, err)
+		}
+	}
+
+	// Overlay packages importing each other are type-checked on demand by
+	// importPackage, since they are already parsed.
+	for _, pkgPath := range pkgPaths {
+		if _, err := b.typeCheckPackage(pkgPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // addFile adds a file to the set. The pkgPath must be of the form
 // "canonical/pkg/path" and the path must be the absolute path to the file. A
 // flag indicates whether this file was user-requested or just from following
@@ -186,7 +285,12 @@ func (b *Builder) addFile(pkgPath importPathString, path string, src []byte, use
 	if err != nil {
 		return err
 	}
+	b.recordFile(pkgPath, path, p, userRequested)
+	return nil
+}
 
+// recordFile adds a parsed file to the set, see addFile.
+func (b *Builder) recordFile(pkgPath importPathString, path string, p *ast.File, userRequested bool) {
 	// This is redundant with addDir, but some tests call AddFileForTest, which
 	// call into here without calling addDir.
 	b.userRequested[pkgPath] = userRequested || b.userRequested[pkgPath]
@@ -206,7 +310,6 @@ func (b *Builder) addFile(pkgPath importPathString, path string, src []byte, use
 		importedPath := strings.Trim(im.Path.Value, `"`)
 		b.importGraph[pkgPath][importedPath] = struct{}{}
 	}
-	return nil
 }
 
 // AddDir adds an entire directory, scanning it for go files. 'dir' should have
@@ -226,24 +329,25 @@ func (b *Builder) AddDirRecursive(dir string) error {
 		log.Warnf("Ignoring directory %v: %v", dir, err)
 	}
 
+	root := b.buildPackages[dir]
+	if root == nil {
+		return fmt.Errorf("unable to find directory %q", dir)
+	}
+
 	// filepath.Walk does not follow symlinks. We therefore evaluate symlinks and use that with
 	// filepath.Walk.
-	realPath, err := filepath.EvalSymlinks(b.buildPackages[dir].Dir)
+	realPath, err := filepath.EvalSymlinks(root.Dir)
 	if err != nil {
 		return err
 	}
 
+	var pkgs []string
 	fn := func(filePath string, info os.FileInfo, err error) error {
 		if info != nil && info.IsDir() {
 			rel := filepath.ToSlash(strings.TrimPrefix(filePath, realPath))
 			if rel != "" {
 				// Make a pkg path.
-				pkg := path.Join(string(canonicalizeImportPath(b.buildPackages[dir].ImportPath)), rel)
-
-				// Add it.
-				if _, err := b.importPackage(pkg, true); err != nil {
-					log.Warnf("Ignoring child directory %v: %v", pkg, err)
-				}
+				pkgs = append(pkgs, path.Join(string(canonicalizeImportPath(root.ImportPath)), rel))
 			}
 		}
 		return nil
@@ -251,6 +355,14 @@ func (b *Builder) AddDirRecursive(dir string) error {
 	if err := filepath.Walk(realPath, fn); err != nil {
 		return err
 	}
+
+	// Process the children together, then add them one by one.
+	b.prefetch(pkgs, true)
+	for _, pkg := range pkgs {
+		if _, err := b.importPackage(pkg, true); err != nil {
+			log.Warnf("Ignoring child directory %v: %v", pkg, err)
+		}
+	}
 	return nil
 }
 
@@ -266,7 +378,7 @@ func (b *Builder) AddDirTo(dir string, u *types.Universe) error {
 	if _, err := b.importPackage(dir, true); err != nil {
 		return err
 	}
-	return b.findTypesIn(canonicalizeImportPath(b.buildPackages[dir].ImportPath), u)
+	return b.findTypesIn(b.canonicalPath(dir), u)
 }
 
 // AddDirectoryTo adds an entire directory to a given Universe. Unlike AddDir,
@@ -280,13 +392,33 @@ func (b *Builder) AddDirectoryTo(dir string, u *types.Universe) (*types.Package,
 	if _, err := b.importPackage(dir, true); err != nil {
 		return nil, err
 	}
-	path := canonicalizeImportPath(b.buildPackages[dir].ImportPath)
+	path := b.canonicalPath(dir)
 	if err := b.findTypesIn(path, u); err != nil {
 		return nil, err
 	}
 	return u.Package(string(path)), nil
 }
 
+// matchFile returns whether the file at path, whose contents are src, is
+// included by the build context.
+func (b *Builder) matchFile(path string, src []byte) (bool, error) {
+	ctxt := *b.context
+	ctxt.OpenFile = func(string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(src)), nil
+	}
+	return ctxt.MatchFile(filepath.Dir(path), filepath.Base(path))
+}
+
+// canonicalPath returns the canonical package path of dir once it has been
+// imported. Packages added by AddOverlay have no build package, their path
+// is already canonical.
+func (b *Builder) canonicalPath(dir string) importPathString {
+	if buildPkg := b.buildPackages[dir]; buildPkg != nil {
+		return canonicalizeImportPath(buildPkg.ImportPath)
+	}
+	return importPathString(dir)
+}
+
 // The implementation of AddDir. A flag indicates whether this directory was
 // user-requested or just from following the import graph.
 func (b *Builder) addDir(dir string, userRequested bool) error {
@@ -295,6 +427,32 @@ func (b *Builder) addDir(dir string, userRequested bool) error {
 	if err != nil {
 		return err
 	}
+	pkgPath, err := b.rememberPackageDir(dir, buildPkg)
+	if err != nil {
+		return err
+	}
+	if cp := b.loadCached(dir, pkgPath); cp != nil {
+		b.recordCachedPackage(pkgPath, cp, userRequested)
+		return nil
+	}
+
+	for _, absPath := range b.packageFiles(buildPkg) {
+		data, err := ioutil.ReadFile(absPath)
+		if err != nil {
+			return fmt.Errorf("while loading %q: %v", absPath, err)
+		}
+		err = b.addFile(pkgPath, absPath, data, userRequested)
+		if err != nil {
+			return fmt.Errorf("while parsing %q: %v", absPath, err)
+		}
+	}
+	return nil
+}
+
+// rememberPackageDir records the directory of buildPkg and returns its
+// canonical package path. It fails if the package was previously found
+// somewhere else.
+func (b *Builder) rememberPackageDir(dir string, buildPkg *build.Package) (importPathString, error) {
 	canonicalPackage := canonicalizeImportPath(buildPkg.ImportPath)
 	pkgPath := canonicalPackage
 	if dir != string(canonicalPackage) {
@@ -304,38 +462,39 @@ func (b *Builder) addDir(dir string, userRequested bool) error {
 	// Sanity check the pkg dir has not changed.
 	if prev, found := b.absPaths[pkgPath]; found {
 		if buildPkg.Dir != prev {
-			return fmt.Errorf("package %q (%s) previously resolved to %s", pkgPath, buildPkg.Dir, prev)
+			return "", fmt.Errorf("package %q (%s) previously resolved to %s", pkgPath, buildPkg.Dir, prev)
 		}
 	} else {
 		b.absPaths[pkgPath] = buildPkg.Dir
 	}
+	return pkgPath, nil
+}
 
+// packageFiles returns the absolute paths of the go files of buildPkg.
+func (b *Builder) packageFiles(buildPkg *build.Package) []string {
 	files := []string{}
 	files = append(files, buildPkg.GoFiles...)
 	if b.IncludeTestFiles {
 		files = append(files, buildPkg.TestGoFiles...)
 	}
 
+	paths := []string{}
 	for _, file := range files {
 		if !strings.HasSuffix(file, ".go") {
 			continue
 		}
-		absPath := filepath.Join(buildPkg.Dir, file)
-		data, err := ioutil.ReadFile(absPath)
-		if err != nil {
-			return fmt.Errorf("while loading %q: %v", absPath, err)
-		}
-		err = b.addFile(pkgPath, absPath, data, userRequested)
-		if err != nil {
-			return fmt.Errorf("while parsing %q: %v", absPath, err)
-		}
+		paths = append(paths, filepath.Join(buildPkg.Dir, file))
 	}
-	return nil
+	return paths
 }
 
 var regexErrPackageNotFound = regexp.MustCompile(`^unable to import ".*?": cannot find package ".*?" in any of:`)
 
 func isErrPackageNotFound(err error) bool {
+	var notFound *packageNotFoundError
+	if errors.As(err, &notFound) {
+		return true
+	}
 	return regexErrPackageNotFound.MatchString(err.Error())
 }
 
@@ -343,6 +502,9 @@ func isErrPackageNotFound(err error) bool {
 // needs to import a go package. 'path' is the import path.
 func (b *Builder) importPackage(dir string, userRequested bool) (*tc.Package, error) {
 	log.Debugf("importPackage %s", dir)
+	if userRequested {
+		b.prefetch([]string{dir}, userRequested)
+	}
 	var pkgPath = importPathString(dir)
 
 	// Get the canonical path if we can.
@@ -428,22 +590,36 @@ func (b *Builder) typeCheckPackage(pkgPath importPathString) (*tc.Package, error
 	if !ok {
 		return nil, fmt.Errorf("No files for pkg %q", pkgPath)
 	}
+	b.typeCheckedPackages[pkgPath] = nil
+	// Note that importAdapter can call b.importPackage which calls this
+	// method. So there can't be cycles in the import graph.
+	pkg, err := b.check(pkgPath, parsedFiles, b.cache.loaded[pkgPath], importAdapter{b})
+	b.typeCheckedPackages[pkgPath] = pkg // record the result whether or not there was an error
+	return pkg, err
+}
+
+// check runs the type checker on the files of a package, or reads it from its
+// cache entry cp if it was loaded from the cache.
+func (b *Builder) check(pkgPath importPathString, parsedFiles []parsedFile, cp *cachedPackage, importer tc.Importer) (*tc.Package, error) {
+	if cp != nil {
+		return b.readCached(pkgPath, cp, importer)
+	}
+
 	files := make([]*ast.File, len(parsedFiles))
 	for i := range parsedFiles {
 		files[i] = parsedFiles[i].file
 	}
-	b.typeCheckedPackages[pkgPath] = nil
 	c := tc.Config{
 		IgnoreFuncBodies: true,
-		// Note that importAdapter can call b.importPackage which calls this
-		// method. So there can't be cycles in the import graph.
-		Importer: importAdapter{b},
+		Importer:         importer,
 		Error: func(err error) {
 			log.Debugf("type checker: %v\n", err)
 		},
 	}
 	pkg, err := c.Check(string(pkgPath), b.fset, files, nil)
-	b.typeCheckedPackages[pkgPath] = pkg // record the result whether or not there was an error
+	if err == nil && b.CacheDir != "" {
+		b.storeCached(pkgPath, parsedFiles, pkg)
+	}
 	return pkg, err
 }
 
@@ -486,6 +662,16 @@ func (b *Builder) FindTypes() (types.Universe, error) {
 			return nil, err
 		}
 	}
+	if b.Markers != nil {
+		for _, pkgPath := range pkgPaths {
+			if !b.userRequested[importPathString(pkgPath)] {
+				continue
+			}
+			for _, p := range b.Markers.CheckPackage(u[pkgPath]) {
+				log.Warnf("%s", p)
+			}
+		}
+	}
 	return u, nil
 }
 
@@ -527,6 +713,7 @@ func (b *Builder) findTypesIn(pkgPath importPathString, u *types.Universe) error
 	}
 
 	s := pkg.Scope()
+	var consts []*tc.Const
 	for _, n := range s.Names() {
 		obj := s.Lookup(n)
 		tn, ok := obj.(*tc.TypeName)
@@ -560,9 +747,12 @@ func (b *Builder) findTypesIn(pkgPath importPathString, u *types.Universe) error
 		}
 		tconst, ok := obj.(*tc.Const)
 		if ok {
-			b.addConstant(*u, nil, tconst)
+			t := b.addConstant(*u, nil, tconst)
+			t.CommentLines = splitLines(b.priorCommentLines(obj.Pos(), 1).Text())
+			consts = append(consts, tconst)
 		}
 	}
+	b.addEnums(*u, consts)
 
 	importedPkgs := []string{}
 	for k := range b.importGraph[pkgPath] {
@@ -576,6 +766,10 @@ func (b *Builder) findTypesIn(pkgPath importPathString, u *types.Universe) error
 }
 
 func (b *Builder) importWithMode(dir string, mode build.ImportMode) (*build.Package, error) {
+	if b.modules != nil {
+		return b.importModulePackage(dir, mode)
+	}
+
 	// This is a bit of a hack.  The srcDir argument to Import() should
 	// properly be the dir of the file which depends on the package to be
 	// imported, so that vendoring can work properly and local paths can
@@ -607,8 +801,11 @@ func splitLines(str string) []string {
 
 func tcFuncNameToName(in string) types.Name {
 	name := strings.TrimPrefix(in, "func ")
-	nameParts := strings.Split(name, "(")
-	return tcNameToName(nameParts[0])
+	// Drop the type parameter list of generic functions as well.
+	if i := strings.IndexAny(name, "(["); i >= 0 {
+		name = name[:i]
+	}
+	return tcNameToName(name)
 }
 
 func tcVarNameToName(in string) types.Name {
@@ -626,6 +823,7 @@ func tcNameToName(in string) types.Name {
 		strings.HasPrefix(in, "chan<-") ||
 		strings.HasPrefix(in, "chan ") ||
 		strings.HasPrefix(in, "func(") ||
+		strings.HasPrefix(in, "func[") ||
 		strings.HasPrefix(in, "func (") ||
 		(strings.HasPrefix(in, "*") && !strings.Contains(in, ".")) ||
 		strings.HasPrefix(in, "map[") ||
@@ -633,6 +831,13 @@ func tcNameToName(in string) types.Name {
 		return types.Name{Name: in}
 	}
 
+	// Type arguments of an instantiated type may have packages of their
+	// own, so only look for the package path in front of them.
+	args := ""
+	if i := strings.Index(in, "["); i >= 0 {
+		in, args = in[:i], in[i:]
+	}
+
 	// Otherwise, if there are '.' characters present, the name has a
 	// package path in front.
 	nameParts := strings.Split(in, ".")
@@ -646,9 +851,71 @@ func tcNameToName(in string) types.Name {
 			name.Name = "*" + name.Name
 		}
 	}
+	name.Name += args
+	return name
+}
+
+// tcNamedToName returns the name of a named type. Generic types are named
+// without their type parameters, e.g. "Array", while instantiations carry
+// their type arguments, e.g. "Array[string]".
+func tcNamedToName(t *tc.Named) types.Name {
+	obj := t.Obj()
+	name := types.Name{Name: obj.Name()}
+	if obj.Pkg() != nil {
+		name.Package = obj.Pkg().Path()
+	}
+	if args := t.TypeArgs(); args.Len() != 0 {
+		parts := make([]string, 0, args.Len())
+		for i := 0; i < args.Len(); i++ {
+			parts = append(parts, args.At(i).String())
+		}
+		name.Name += "[" + strings.Join(parts, ", ") + "]"
+	}
 	return name
 }
 
+// isReceiverInstance returns whether t is the instantiation of a generic type
+// with its own type parameters, e.g. List[T] in the declaration of List, or
+// with those of the receiver of one of its methods, e.g. the receiver type
+// Array[V] of a method of Array. Those are the generic type itself, as far as
+// generators care, unlike Array[T] in the declaration of another generic
+// type.
+func isReceiverInstance(t *tc.Named) bool {
+	args := t.TypeArgs()
+	if args.Len() == 0 {
+		return false
+	}
+	sameArgs := func(params *tc.TypeParamList) bool {
+		if params.Len() != args.Len() {
+			return false
+		}
+		for i := 0; i < args.Len(); i++ {
+			if args.At(i) != params.At(i) {
+				return false
+			}
+		}
+		return true
+	}
+	origin := t.Origin()
+	if sameArgs(origin.TypeParams()) {
+		return true
+	}
+	for i := 0; i < origin.NumMethods(); i++ {
+		if sameArgs(origin.Method(i).Type().(*tc.Signature).RecvTypeParams()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Builder) convertTypeParams(u types.Universe, list *tc.TypeParamList) []*types.Type {
+	var out []*types.Type
+	for i := 0; i < list.Len(); i++ {
+		out = append(out, b.walkType(u, nil, list.At(i)))
+	}
+	return out
+}
+
 func (b *Builder) convertSignature(u types.Universe, t *tc.Signature) *types.Signature {
 	signature := &types.Signature{}
 	for i := 0; i < t.Params().Len(); i++ {
@@ -661,6 +928,7 @@ func (b *Builder) convertSignature(u types.Universe, t *tc.Signature) *types.Sig
 		signature.Receiver = b.walkType(u, nil, r.Type())
 	}
 	signature.Variadic = t.Variadic()
+	signature.TypeParams = b.convertTypeParams(u, t.TypeParams())
 	return signature
 }
 
@@ -674,9 +942,6 @@ func (b *Builder) walkType(u types.Universe, useName *types.Name, in tc.Type) *t
 
 	switch t := in.(type) {
 	case *tc.Struct:
-		if strings.Contains(name.Name, "Resource") {
-			log.Info("")
-		}
 		out := u.Type(name)
 		if out.Kind != types.Unknown {
 			return out
@@ -700,7 +965,9 @@ func (b *Builder) walkType(u types.Universe, useName *types.Name, in tc.Type) *t
 				Tags:         t.Tag(i),
 				Type:         b.walkType(u, tn, tt),
 				CommentLines: splitLines(b.priorCommentLines(f.Pos(), 1).Text()),
+				Position:     b.objectPosition(f),
 			}
+			m.CacheStructTags()
 			out.Members = append(out.Members, m)
 		}
 		return out
@@ -736,8 +1003,7 @@ func (b *Builder) walkType(u types.Universe, useName *types.Name, in tc.Type) *t
 		}
 		out.Kind = types.Array
 		out.Elem = b.walkType(u, nil, t.Elem())
-		// TODO: need to store array length, otherwise raw type name
-		// cannot be properly written.
+		out.Len = t.Len()
 		return out
 	case *tc.Chan:
 		out := u.Type(name)
@@ -746,8 +1012,14 @@ func (b *Builder) walkType(u types.Universe, useName *types.Name, in tc.Type) *t
 		}
 		out.Kind = types.Chan
 		out.Elem = b.walkType(u, nil, t.Elem())
-		// TODO: need to store direction, otherwise raw type name
-		// cannot be properly written.
+		switch t.Dir() {
+		case tc.SendOnly:
+			out.ChanDir = types.SendOnly
+		case tc.RecvOnly:
+			out.ChanDir = types.RecvOnly
+		default:
+			out.ChanDir = types.SendRecv
+		}
 		return out
 	case *tc.Basic:
 		out := u.Type(types.Name{
@@ -782,30 +1054,50 @@ func (b *Builder) walkType(u types.Universe, useName *types.Name, in tc.Type) *t
 			name := tcNameToName(method.String())
 			mt := b.walkType(u, &name, method.Type())
 			mt.CommentLines = splitLines(b.priorCommentLines(method.Pos(), 1).Text())
+			mt.Position = b.objectPosition(method)
 			out.Methods[method.Name()] = mt
 		}
 		return out
+	case *tc.TypeParam:
+		if out, ok := b.typeParams[t]; ok {
+			return out
+		}
+		out := &types.Type{
+			Name: types.Name{Name: t.Obj().Name()},
+			Kind: types.TypeParam,
+		}
+		b.typeParams[t] = out
+		out.Constraint = b.walkType(u, nil, t.Constraint())
+		return out
 	case *tc.Named:
+		if isReceiverInstance(t) {
+			return b.walkType(u, nil, t.Origin())
+		}
 		var out *types.Type
 		switch t.Underlying().(type) {
 		case *tc.Named, *tc.Basic, *tc.Map, *tc.Slice:
-			name := tcNameToName(t.String())
+			name := tcNamedToName(t)
 			out = u.Type(name)
 			if out.Kind != types.Unknown {
 				return out
 			}
 			out.Kind = types.Alias
+			b.walkGeneric(u, out, t)
 			out.Underlying = b.walkType(u, nil, t.Underlying())
 		default:
 			// tc package makes everything "named" with an
 			// underlying anonymous type--we remove that annoying
 			// "feature" for users. This flattens those types
 			// together.
-			name := tcNameToName(t.String())
+			name := tcNamedToName(t)
 			if out := u.Type(name); out.Kind != types.Unknown {
 				return out // short circuit if we've already made this.
 			}
 			out = b.walkType(u, &name, t.Underlying())
+			b.walkGeneric(u, out, t)
+		}
+		if !out.Position.IsValid() {
+			out.Position = b.objectPosition(t.Obj())
 		}
 		// If the underlying type didn't already add methods, add them.
 		// (Interface types will have already added methods.)
@@ -818,11 +1110,17 @@ func (b *Builder) walkType(u types.Universe, useName *types.Name, in tc.Type) *t
 				name := tcNameToName(method.String())
 				mt := b.walkType(u, &name, method.Type())
 				mt.CommentLines = splitLines(b.priorCommentLines(method.Pos(), 1).Text())
+				mt.Position = b.objectPosition(method)
 				out.Methods[method.Name()] = mt
 			}
 		}
 		return out
 	default:
+		// Alias declarations, e.g. any, are only materialized by newer
+		// versions of go/types; they are the type they stand for.
+		if ut := in.Underlying(); ut != nil && ut != in {
+			return b.walkType(u, useName, ut)
+		}
 		out := u.Type(name)
 		if out.Kind != types.Unknown {
 			return out
@@ -833,6 +1131,20 @@ func (b *Builder) walkType(u types.Universe, useName *types.Name, in tc.Type) *t
 	}
 }
 
+// walkGeneric records the type parameters of a generic type, or the type
+// arguments and origin of an instantiated one.
+func (b *Builder) walkGeneric(u types.Universe, out *types.Type, t *tc.Named) {
+	if t.TypeArgs().Len() == 0 {
+		out.TypeParams = b.convertTypeParams(u, t.TypeParams())
+		return
+	}
+	out.Origin = b.walkType(u, nil, t.Origin())
+	out.TypeArgs = nil
+	for i := 0; i < t.TypeArgs().Len(); i++ {
+		out.TypeArgs = append(out.TypeArgs, b.walkType(u, nil, t.TypeArgs().At(i)))
+	}
+}
+
 func (b *Builder) addFunction(u types.Universe, useName *types.Name, in *tc.Func) *types.Type {
 	name := tcFuncNameToName(in.String())
 	if useName != nil {
@@ -840,6 +1152,7 @@ func (b *Builder) addFunction(u types.Universe, useName *types.Name, in *tc.Func
 	}
 	out := u.Function(name)
 	out.Kind = types.DeclarationOf
+	out.Position = b.objectPosition(in)
 	out.Underlying = b.walkType(u, nil, in.Type())
 	return out
 }
@@ -851,6 +1164,7 @@ func (b *Builder) addVariable(u types.Universe, useName *types.Name, in *tc.Var)
 	}
 	out := u.Variable(name)
 	out.Kind = types.DeclarationOf
+	out.Position = b.objectPosition(in)
 	out.Underlying = b.walkType(u, nil, in.Type())
 	return out
 }
@@ -862,10 +1176,47 @@ func (b *Builder) addConstant(u types.Universe, useName *types.Name, in *tc.Cons
 	}
 	out := u.Constant(name)
 	out.Kind = types.DeclarationOf
+	out.Position = b.objectPosition(in)
 	out.Underlying = b.walkType(u, nil, in.Type())
 	return out
 }
 
+// addEnums links the constants of named types to their type, in declaration
+// order, see types.Type.Enum. Only constants declared in the package of their
+// type are considered.
+func (b *Builder) addEnums(u types.Universe, consts []*tc.Const) {
+	// Files may be parsed concurrently, so token.Pos doesn't order them.
+	sort.Slice(consts, func(i, j int) bool {
+		pi, pj := b.objectPosition(consts[i]), b.objectPosition(consts[j])
+		if pi.Filename != pj.Filename {
+			return pi.Filename < pj.Filename
+		}
+		return pi.Offset < pj.Offset
+	})
+
+	// findTypesIn might be called multiple times, so the values are collected
+	// before replacing the previous ones.
+	enums := map[*types.Type][]*types.EnumValue{}
+	for _, c := range consts {
+		named, ok := c.Type().(*tc.Named)
+		if !ok || named.Obj().Pkg() != c.Pkg() {
+			continue
+		}
+		t := b.walkType(u, nil, named)
+		decl := u.Constant(tcVarNameToName(c.String()))
+		enums[t] = append(enums[t], &types.EnumValue{
+			Name:         c.Name(),
+			Value:        c.Val().ExactString(),
+			CommentLines: decl.CommentLines,
+			Position:     decl.Position,
+			Constant:     decl,
+		})
+	}
+	for t, values := range enums {
+		t.Enum = values
+	}
+}
+
 // canonicalizeImportPath takes an import path and returns the actual package.
 // It doesn't support nested vendoring.
 func canonicalizeImportPath(importPath string) importPathString {
@@ -0,0 +1,172 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/vine-io/gogogen/gogenerator/types"
+)
+
+// overlayRoot is where the in-memory packages of the tests are located.
+var overlayRoot = filepath.Join(string(filepath.Separator), "gogogen-parser-test")
+
+// parseOverlay parses the in-memory go files of src, keyed by import path and
+// file name, with b.
+func parseOverlay(t *testing.T, b *Builder, src map[string]string) types.Universe {
+	t.Helper()
+	files := map[string][]byte{}
+	for name, data := range src {
+		files[name] = []byte(data)
+	}
+	if err := b.AddOverlay(overlayRoot, files); err != nil {
+		t.Fatal(err)
+	}
+	u, err := b.FindTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestGenericInstances(t *testing.T) {
+	u := parseOverlay(t, NewWithModules(""), map[string]string{
+		"example.com/generic/types.go": `package generic
+
+type Array[V any] []V
+
+func (a Array[V]) Append(b Array[V]) Array[V] { return append(a, b...) }
+
+type List[T any] struct {
+	Value T
+	Next  *List[T]
+}
+
+type Wrap[T any] struct {
+	A Array[T]
+}
+`,
+	})
+	pkg := u.Package("example.com/generic")
+
+	array := pkg.Type("Array")
+	m := array.Methods["Append"]
+	if m == nil {
+		t.Fatalf("no Append method: %v", array.Methods)
+	}
+	if recv := m.Signature.Receiver; recv != array {
+		t.Errorf("receiver of Append is %v, want the generic Array", recv)
+	}
+	if param := m.Signature.Parameters[0]; param != array {
+		t.Errorf("parameter of Append is %v, want the generic Array", param)
+	}
+
+	list := pkg.Type("List")
+	if next := list.Members[1].Type.Elem; next != list {
+		t.Errorf("List.Next points to %v, want the generic List", next)
+	}
+
+	a := pkg.Type("Wrap").Members[0].Type
+	if !a.IsInstantiated() || a.Origin != array {
+		t.Fatalf("Wrap.A is %v, want an instantiation of Array", a)
+	}
+	if len(a.TypeArgs) != 1 || a.TypeArgs[0].Kind != types.TypeParam || a.TypeArgs[0].Name.Name != "T" {
+		t.Errorf("Wrap.A has the type arguments %v, want [T]", a.TypeArgs)
+	}
+}
+
+func TestAddDirRecursiveModuleRoot(t *testing.T) {
+	root := t.TempDir()
+	for name, data := range map[string]string{
+		"go.mod":   "module example.com/pm\n\ngo 1.18\n",
+		"a/a.go":   "package a\n\ntype A struct{}\n",
+		"a/b/b.go": "package b\n\ntype B struct{}\n",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The root of the module holds no Go files, in either form.
+	for _, dir := range []string{"example.com/pm", "./"} {
+		b := NewWithModules(root)
+		if err := b.AddDirRecursive(dir); err != nil {
+			t.Fatalf("%s: %v", dir, err)
+		}
+		u, err := b.FindTypes()
+		if err != nil {
+			t.Fatalf("%s: %v", dir, err)
+		}
+		for _, name := range []types.Name{{Package: "example.com/pm/a", Name: "A"}, {Package: "example.com/pm/a/b", Name: "B"}} {
+			if u.Type(name).Kind != types.Struct {
+				t.Errorf("%s: %v not found", dir, name)
+			}
+		}
+	}
+
+	if err := NewWithModules(root).AddDirRecursive("example.com/pm/missing"); err == nil {
+		t.Errorf("expected an error for a missing directory")
+	}
+}
+
+func TestParallelParse(t *testing.T) {
+	pkgs := []string{
+		"github.com/vine-io/gogogen/runtime/meta",
+		"github.com/vine-io/gogogen/runtime/dao",
+		"github.com/vine-io/gogogen/gogenerator/types",
+		"github.com/vine-io/gogogen/gogenerator/namer",
+	}
+	parse := func(parallelism int) types.Universe {
+		t.Helper()
+		b := NewWithModules("")
+		b.Parallelism = parallelism
+		for _, pkg := range pkgs {
+			if err := b.AddDir(pkg); err != nil {
+				t.Fatal(err)
+			}
+		}
+		u, err := b.FindTypes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+
+	serial, parallel := parse(1), parse(8)
+	if len(serial) != len(parallel) {
+		t.Errorf("got %d packages in parallel, %d serially", len(parallel), len(serial))
+	}
+	for path, want := range serial {
+		got, ok := parallel[path]
+		switch {
+		case !ok:
+			t.Errorf("package %s missing in parallel", path)
+		case !reflect.DeepEqual(got, want):
+			for name, wt := range want.Types {
+				if gt := got.Types[name]; !reflect.DeepEqual(gt, wt) {
+					t.Errorf("type %s.%s differs in parallel:\n%#v\nserially:\n%#v", path, name, gt, wt)
+				}
+			}
+			t.Errorf("package %s differs in parallel", path)
+		}
+	}
+}
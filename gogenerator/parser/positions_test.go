@@ -0,0 +1,128 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/vine-io/gogogen/gogenerator/types"
+)
+
+// writeModule writes the files of a module "example.com" to a temporary
+// directory, keyed by their path relative to it, and returns the directory.
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	files["go.mod"] = "module example.com\n\ngo 1.18\n"
+	for name, data := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// parseModule parses the packages pkgs of the module in root.
+func parseModule(t *testing.T, root string, pkgs ...string) types.Universe {
+	t.Helper()
+	b := NewWithModules(root)
+	for _, pkg := range pkgs {
+		if err := b.AddDir(pkg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	u, err := b.FindTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestPositions(t *testing.T) {
+	root := writeModule(t, map[string]string{
+		"pos/types.go": `package pos
+
+type T struct {
+	Name string
+}
+
+func (T) M() {}
+
+func F() {}
+
+var V int
+
+const C = 1
+`,
+	})
+	pkg := parseModule(t, root, "example.com/pos").Package("example.com/pos")
+	file := filepath.Join(root, "pos", "types.go")
+
+	typ := pkg.Type("T")
+	for _, tc := range []struct {
+		name      string
+		pos       token.Position
+		line, col int
+	}{
+		{"type T", typ.Position, 3, 6},
+		{"member Name", typ.Members[0].Position, 4, 2},
+		{"method M", typ.Methods["M"].Position, 7, 10},
+		{"func F", pkg.Function("F").Position, 9, 6},
+		{"var V", pkg.Variable("V").Position, 11, 5},
+		{"const C", pkg.Constant("C").Position, 13, 7},
+	} {
+		if tc.pos.Filename != file || tc.pos.Line != tc.line || tc.pos.Column != tc.col {
+			t.Errorf("%s: got position %v, want %s:%d:%d", tc.name, tc.pos, file, tc.line, tc.col)
+		}
+	}
+	if pos := types.String.Position; pos.IsValid() {
+		t.Errorf("builtin string: got position %v, want none", pos)
+	}
+}
+
+func TestCheckPackageMarkers(t *testing.T) {
+	root := writeModule(t, map[string]string{
+		"mk/types.go": `package mk
+
+// +gogo:deepcopy=true
+type T struct{ A []int }
+
+type U struct {
+	// +gogo:deepcopy=true
+	B int
+}
+`,
+	})
+	u := parseModule(t, root, "example.com/mk")
+	r := types.NewMarkerRegistry(&types.Marker{Name: "gogo:deepcopy", Scope: types.TypeScope, Value: types.BoolMarker})
+
+	var got []string
+	for _, p := range r.CheckPackage(u.Package("example.com/mk")) {
+		got = append(got, fmt.Sprintf("%d:%d: %s", p.Position.Line, p.Position.Column, p.Message))
+	}
+	want := []string{`8:2: marker "+gogo:deepcopy" is not allowed on a field, only on a type`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vine-io/gogogen/gogenerator/types"
+	"github.com/vine-io/gogogen/util/log"
+)
+
+// Reload parses and type-checks again the packages pkgPaths, whose files
+// changed on disk, and the packages importing them, directly or not. The
+// entries of the user-requested ones are rebuilt in u, which must have been
+// returned by FindTypes; the other packages of u are left alone.
+//
+// It returns the user-requested packages which were reloaded, sorted. If it
+// fails, some of them may be missing from u until they are reloaded again.
+func (b *Builder) Reload(u types.Universe, pkgPaths ...string) ([]string, error) {
+	forgotten := b.forget(pkgPaths)
+	var reloaded []string
+	for _, pkgPath := range forgotten {
+		if b.userRequested[pkgPath] {
+			reloaded = append(reloaded, string(pkgPath))
+		}
+	}
+
+	// The types of the other packages naming the types of the reloaded ones,
+	// such as []pkg.Type or Generic[pkg.Type], are walked again too.
+	for _, pkgPath := range reloaded {
+		delete(u, pkgPath)
+	}
+	for _, p := range u {
+		for name := range p.Types {
+			for _, pkgPath := range reloaded {
+				if strings.Contains(name, pkgPath+".") {
+					delete(p.Types, name)
+					break
+				}
+			}
+		}
+	}
+
+	for _, pkgPath := range reloaded {
+		if err := b.AddDir(pkgPath); err != nil {
+			return reloaded, fmt.Errorf("unable to reload %q: %v", pkgPath, err)
+		}
+	}
+	for _, pkgPath := range reloaded {
+		if err := b.findTypesIn(importPathString(pkgPath), &u); err != nil {
+			return reloaded, err
+		}
+		if b.Markers != nil {
+			for _, p := range b.Markers.CheckPackage(u[pkgPath]) {
+				log.Warnf("%s", p)
+			}
+		}
+	}
+	return reloaded, nil
+}
+
+// forget drops everything b knows about the packages pkgPaths and those
+// importing them, except whether the user requested them, and returns them
+// sorted.
+func (b *Builder) forget(pkgPaths []string) []importPathString {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	forgotten := map[importPathString]bool{}
+	var visit func(pkgPath importPathString)
+	visit = func(pkgPath importPathString) {
+		if forgotten[pkgPath] {
+			return
+		}
+		forgotten[pkgPath] = true
+		for importer, imports := range b.importGraph {
+			if _, ok := imports[string(pkgPath)]; ok {
+				visit(importer)
+			}
+		}
+	}
+	for _, pkgPath := range pkgPaths {
+		visit(importPathString(pkgPath))
+	}
+
+	var files = map[string]bool{}
+	for pkgPath := range forgotten {
+		for _, f := range b.parsed[pkgPath] {
+			files[f.name] = true
+		}
+		delete(b.parsed, pkgPath)
+		delete(b.absPaths, pkgPath)
+		delete(b.typeCheckedPackages, pkgPath)
+		delete(b.importGraph, pkgPath)
+		delete(b.cache.loaded, pkgPath)
+		delete(b.cache.pending, pkgPath)
+	}
+	// The files of the packages may have been added or removed.
+	for dir, buildPkg := range b.buildPackages {
+		if forgotten[canonicalizeImportPath(buildPkg.ImportPath)] {
+			delete(b.buildPackages, dir)
+		}
+	}
+	if b.modules != nil {
+		b.modules.mu.Lock()
+		for dir, pkg := range b.modules.packages {
+			if forgotten[canonicalizeImportPath(pkg.PkgPath)] {
+				delete(b.modules.packages, dir)
+			}
+		}
+		b.modules.mu.Unlock()
+	}
+	for line := range b.endLineToCommentGroup {
+		if files[line.file] {
+			delete(b.endLineToCommentGroup, line)
+		}
+	}
+	// The keys of the importers of the packages change with them.
+	b.cacheMu.Lock()
+	b.cache.keys = map[string]string{}
+	b.cacheMu.Unlock()
+
+	result := make([]importPathString, 0, len(forgotten))
+	for pkgPath := range forgotten {
+		result = append(result, pkgPath)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
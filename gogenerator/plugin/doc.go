@@ -0,0 +1,39 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package plugin implements the protocol between the gogogen driver and
+// generators built as separate executables, in the manner of protoc plugins.
+//
+// The driver parses the input packages, then runs the plugin with a JSON
+// encoded Request on its stdin: the universe of the parsed types, the import
+// paths of the input packages and the registered markers. The plugin writes
+// a JSON encoded Response on its stdout, holding the files it generates or
+// the error it failed with. The driver writes the files through the usual
+// generator.Context pipeline, so that they get the boilerplate, are formatted
+// and honor --verify-only, --dry-run and --output-archive.
+//
+// A plugin written in Go gets the universe back as the structs of the types
+// package, with its builtin types being the ones of the types package:
+//
+//	func main() {
+//		plugin.Main(func(req *plugin.Request) ([]*plugin.File, error) {
+//			var files []*plugin.File
+//			for _, path := range req.Inputs {
+//				pkg := req.Universe[path]
+//				...
+//			}
+//			return files, nil
+//		})
+//	}
+package plugin
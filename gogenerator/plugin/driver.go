@@ -0,0 +1,186 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/vine-io/gogogen/gogenerator/args"
+	"github.com/vine-io/gogogen/gogenerator/generator"
+	"github.com/vine-io/gogogen/gogenerator/types"
+	"github.com/vine-io/gogogen/util/log"
+)
+
+// Driver runs a plugin executable as a generator.
+type Driver struct {
+	// The path of the plugin, or its name to look up in $PATH.
+	Path string
+	// Passed to the plugin as Request.Parameter.
+	Parameter string
+}
+
+// Run sends the universe of c and its input packages to the plugin, along
+// with the markers of r which may be nil, and returns the files it generates.
+// The plugin's stderr is passed through to ours.
+func (d *Driver) Run(c *generator.Context, r *types.MarkerRegistry) ([]*File, error) {
+	req := &Request{
+		Version:   ProtocolVersion,
+		Parameter: d.Parameter,
+		Inputs:    append([]string{}, c.Inputs...),
+		Universe:  c.Universe,
+	}
+	sort.Strings(req.Inputs)
+	if r != nil {
+		req.Markers = r.Markers()
+	}
+	in, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("encoding the request: %v", err)
+	}
+
+	bin, err := exec.LookPath(d.Path)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %v", d.Path, err)
+	}
+	out := &bytes.Buffer{}
+	cmd := exec.Command(bin)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = out
+	cmd.Stderr = os.Stderr
+	log.Infof("Running plugin %q", bin)
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("plugin %s: %v", d.Path, err)
+	}
+
+	resp := &Response{}
+	if err := json.NewDecoder(out).Decode(resp); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("plugin %s: reading the response: %v", d.Path, err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("plugin %s: %s", d.Path, resp.Error)
+	}
+	if err := checkFiles(resp.Files); err != nil {
+		return nil, fmt.Errorf("plugin %s: %v", d.Path, err)
+	}
+	return resp.Files, nil
+}
+
+// checkFiles returns an error if a file can't be written where it says.
+func checkFiles(files []*File) error {
+	seen := map[string]bool{}
+	for _, f := range files {
+		if f.Package == "" {
+			return fmt.Errorf("file %q has no package", f.Name)
+		}
+		if !strings.HasSuffix(f.Name, ".go") || strings.ContainsAny(f.Name, `/\`) {
+			return fmt.Errorf("file %q of package %s must be the name of a go file", f.Name, f.Package)
+		}
+		key := path.Join(f.Package, f.Name)
+		if seen[key] {
+			return fmt.Errorf("file %s is generated twice", key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// Packages is a function for args.GeneratorArgs.Execute: it runs the plugin
+// and returns the packages which generate its files. If the plugin fails, the
+// error is reported to c.Diagnostics.
+func (d *Driver) Packages(c *generator.Context, arguments *args.GeneratorArgs) generator.Packages {
+	packages, err := d.MakePackages(c, arguments)
+	if err != nil {
+		c.Diagnostics.Report(err)
+	}
+	return packages
+}
+
+// MakePackages runs the plugin and returns the packages which generate its
+// files.
+func (d *Driver) MakePackages(c *generator.Context, arguments *args.GeneratorArgs) (generator.Packages, error) {
+	boilerplate, err := arguments.LoadGoBoilerplate()
+	if err != nil {
+		return nil, fmt.Errorf("failed loading boilerplate: %v", err)
+	}
+	files, err := d.Run(c, arguments.Markers)
+	if err != nil {
+		return nil, err
+	}
+
+	byPackage := map[string][]*File{}
+	var paths []string
+	for _, f := range files {
+		if byPackage[f.Package] == nil {
+			paths = append(paths, f.Package)
+		}
+		byPackage[f.Package] = append(byPackage[f.Package], f)
+	}
+	sort.Strings(paths)
+
+	packages := generator.Packages{}
+	for _, pkgPath := range paths {
+		files := byPackage[pkgPath]
+		name, source := path.Base(pkgPath), ""
+		if p, ok := c.Universe[pkgPath]; ok {
+			name, source = p.Name, p.SourcePath
+		}
+		if files[0].PackageName != "" {
+			name = files[0].PackageName
+		}
+		generators := make([]generator.Generator, 0, len(files))
+		for _, f := range files {
+			generators = append(generators, &genFile{
+				DefaultGen: generator.DefaultGen{
+					OptionalName: strings.TrimSuffix(f.Name, ".go"),
+					OptionalBody: []byte(f.Body),
+				},
+				imports: f.Imports,
+			})
+		}
+		packages = append(packages, &generator.DefaultPackage{
+			PackageName:   name,
+			PackagePath:   pkgPath,
+			Source:        source,
+			HeaderText:    boilerplate,
+			GeneratorList: generators,
+		})
+	}
+	return packages, nil
+}
+
+// genFile produces a file returned by a plugin.
+type genFile struct {
+	generator.DefaultGen
+	imports []string
+}
+
+// Filter ignores all types, the body of the file is already generated.
+func (g *genFile) Filter(*generator.Context, *types.Type) bool { return false }
+
+func (g *genFile) Imports(*generator.Context) map[string]string {
+	imports := map[string]string{}
+	for _, line := range g.imports {
+		imports[line] = line
+	}
+	return imports
+}
@@ -0,0 +1,145 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/vine-io/gogogen/gogenerator/types"
+)
+
+// ProtocolVersion is the version of the protocol spoken by this package. A
+// plugin refuses requests of another version.
+const ProtocolVersion = 1
+
+// Request is what the driver sends to a plugin.
+type Request struct {
+	// The version of the protocol of the driver.
+	Version int
+	// The parameter given to the driver for the plugin, if any.
+	Parameter string
+	// The import paths of the packages selected for generation, sorted.
+	Inputs []string
+	// The markers registered with the driver, which it checked the comments
+	// of the input packages against.
+	Markers []*types.Marker
+	// All the parsed types: the input packages and what they import.
+	Universe types.Universe
+}
+
+type wireRequest struct {
+	Version   int             `json:"version"`
+	Parameter string          `json:"parameter,omitempty"`
+	Inputs    []string        `json:"inputs"`
+	Markers   []*types.Marker `json:"markers,omitempty"`
+	Universe  *wireUniverse   `json:"universe"`
+}
+
+func (r *Request) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&wireRequest{
+		Version:   r.Version,
+		Parameter: r.Parameter,
+		Inputs:    r.Inputs,
+		Markers:   r.Markers,
+		Universe:  encodeUniverse(r.Universe),
+	})
+}
+
+func (r *Request) UnmarshalJSON(data []byte) error {
+	w := &wireRequest{}
+	if err := json.Unmarshal(data, w); err != nil {
+		return err
+	}
+	u := types.Universe{}
+	if w.Universe != nil {
+		var err error
+		if u, err = decodeUniverse(w.Universe); err != nil {
+			return fmt.Errorf("decoding the universe: %v", err)
+		}
+	}
+	*r = Request{
+		Version:   w.Version,
+		Parameter: w.Parameter,
+		Inputs:    w.Inputs,
+		Markers:   w.Markers,
+		Universe:  u,
+	}
+	return nil
+}
+
+// File is a go file generated by a plugin. The driver assembles it like the
+// files of the built-in generators: the boilerplate header, the package
+// clause and the imports come first, then Body.
+type File struct {
+	// The import path of the package of the file. It needs not be parsed,
+	// but is generated next to its sources if it is.
+	Package string `json:"package"`
+	// The name of the package, defaults to the one of the parsed package or
+	// to the last element of its import path.
+	PackageName string `json:"packageName,omitempty"`
+	// The name of the file, e.g. "zz_generated.foo.go".
+	Name string `json:"name"`
+	// The import paths the body needs, optionally preceded by a name, e.g.
+	// `foo "example.com/foo"`.
+	Imports []string `json:"imports,omitempty"`
+	// The declarations of the file.
+	Body string `json:"body"`
+}
+
+// Response is what a plugin sends back to the driver.
+type Response struct {
+	// If set, the plugin failed and no file is generated. Plugins report
+	// their errors here rather than by their exit status.
+	Error string  `json:"error,omitempty"`
+	Files []*File `json:"files,omitempty"`
+}
+
+// Func generates the files for a request.
+type Func func(req *Request) ([]*File, error)
+
+// Main implements the main function of a plugin: it reads the request on
+// stdin, runs f and writes its files or error on stdout. It exits with 1 if
+// the request can't be read or the response written.
+func Main(f Func) {
+	if err := Serve(os.Stdin, os.Stdout, f); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[0], err)
+		os.Exit(1)
+	}
+}
+
+// Serve reads a request from r, runs f and writes the response to w. It only
+// returns the errors of reading the request and of writing the response.
+func Serve(r io.Reader, w io.Writer, f Func) error {
+	req := &Request{}
+	if err := json.NewDecoder(r).Decode(req); err != nil {
+		return fmt.Errorf("reading the request: %v", err)
+	}
+
+	resp := &Response{}
+	if req.Version != ProtocolVersion {
+		resp.Error = fmt.Sprintf("unsupported protocol version %d, want %d", req.Version, ProtocolVersion)
+	} else if files, err := f(req); err != nil {
+		resp.Error = err.Error()
+	} else {
+		resp.Files = files
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		return fmt.Errorf("writing the response: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/vine-io/gogogen/gogenerator/args"
+	"github.com/vine-io/gogogen/gogenerator/namer"
+	gentesting "github.com/vine-io/gogogen/gogenerator/testing"
+	"github.com/vine-io/gogogen/gogenerator/types"
+)
+
+// The test binary doubles as the plugin run by the tests.
+const pluginEnv = "GOGOGEN_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(pluginEnv) != "" {
+		Main(stringer)
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// stringer generates String methods for the types with a +example:stringer
+// marker, checking that the universe it gets back is the parsed one.
+func stringer(req *Request) ([]*File, error) {
+	var files []*File
+	for _, path := range req.Inputs {
+		pkg := req.Universe[path]
+		names := make([]string, 0, len(pkg.Types))
+		for name := range pkg.Types {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		body := &strings.Builder{}
+		for _, name := range names {
+			t := pkg.Types[name]
+			if _, ok := types.ExtractCommentTags("+", t.CommentLines)["example:stringer"]; !ok {
+				continue
+			}
+			switch {
+			case t.IsEnum():
+				fmt.Fprintf(body, "func (p %s) String() string {\n\tswitch p {\n", name)
+				for _, v := range t.Enum {
+					fmt.Fprintf(body, "\tcase %s:\n\t\treturn %q\n", v.Name, req.Parameter+v.Name)
+				}
+				fmt.Fprintf(body, "\t}\n\treturn fmt.Sprintf(\"%s(%%s)\", string(p))\n}\n\n", name)
+			case t.Kind == types.Struct:
+				var verbs, fields []string
+				for _, m := range t.Members {
+					verb := "%v"
+					if m.Type == types.String || m.Type.Underlying == types.String {
+						verb = "%q"
+					}
+					verbs = append(verbs, m.Name+"="+verb)
+					fields = append(fields, "r."+m.Name)
+				}
+				fmt.Fprintf(body, "func (r *%s) String() string {\n\treturn fmt.Sprintf(%q, %s)\n}\n\n",
+					name, name+"{"+strings.Join(verbs, " ")+"}", strings.Join(fields, ", "))
+			}
+		}
+		if body.Len() > 0 {
+			files = append(files, &File{Package: path, Name: "stringer_generated.go", Imports: []string{"fmt"}, Body: body.String()})
+		}
+	}
+	return files, nil
+}
+
+func TestGolden(t *testing.T) {
+	os.Setenv(pluginEnv, "1")
+	defer os.Unsetenv(pluginEnv)
+
+	g := args.Default().WithoutDefaultFlagParsing()
+	// Keep the golden files free of the year.
+	g.GoHeaderFilePath = ""
+	g.Owner = "stringer"
+	driver := &Driver{Path: os.Args[0], Parameter: "Phase"}
+
+	gentesting.Run(t, gentesting.Case{
+		Dir:     "testdata/stringer",
+		Package: "example.com/plugin",
+		Runs: []args.Run{{
+			Args:          g,
+			NameSystems:   namer.NameSystems{"public": namer.NewPublicNamer(0)},
+			DefaultSystem: "public",
+			Packages:      driver.Packages,
+		}},
+	})
+}
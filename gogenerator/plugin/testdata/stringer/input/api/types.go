@@ -0,0 +1,20 @@
+package api
+
+// +example:stringer
+type Phase string
+
+const (
+	Pending Phase = "Pending"
+	Running Phase = "Running"
+)
+
+// +example:stringer
+type Resource struct {
+	Name  string
+	Phase Phase
+	Size  int64
+}
+
+type Other struct {
+	Name string
+}
@@ -0,0 +1,390 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"fmt"
+	"go/token"
+	"sort"
+
+	"github.com/vine-io/gogogen/gogenerator/types"
+)
+
+// The types of a universe reference each other, often in cycles, so they are
+// serialized as a table in which they are referenced by their index plus one;
+// 0 stands for nil.
+
+type wireUniverse struct {
+	Packages []*wirePackage `json:"packages"`
+	Types    []*wireType    `json:"types"`
+}
+
+type wirePackage struct {
+	Path        string         `json:"path"`
+	SourcePath  string         `json:"sourcePath,omitempty"`
+	Name        string         `json:"name,omitempty"`
+	DocComments []string       `json:"docComments,omitempty"`
+	Comments    []string       `json:"comments,omitempty"`
+	Types       map[string]int `json:"types,omitempty"`
+	Functions   map[string]int `json:"functions,omitempty"`
+	Variables   map[string]int `json:"variables,omitempty"`
+	Constants   map[string]int `json:"constants,omitempty"`
+	Imports     []string       `json:"imports,omitempty"`
+}
+
+type wireType struct {
+	Name                      types.Name      `json:"name"`
+	Kind                      types.Kind      `json:"kind,omitempty"`
+	Position                  *token.Position `json:"position,omitempty"`
+	CommentLines              []string        `json:"commentLines,omitempty"`
+	SecondClosestCommentLines []string        `json:"secondClosestCommentLines,omitempty"`
+	Members                   []*wireMember   `json:"members,omitempty"`
+	Elem                      int             `json:"elem,omitempty"`
+	Key                       int             `json:"key,omitempty"`
+	Underlying                int             `json:"underlying,omitempty"`
+	Methods                   map[string]int  `json:"methods,omitempty"`
+	Signature                 *wireSignature  `json:"signature,omitempty"`
+	TypeParams                []int           `json:"typeParams,omitempty"`
+	TypeArgs                  []int           `json:"typeArgs,omitempty"`
+	Origin                    int             `json:"origin,omitempty"`
+	Constraint                int             `json:"constraint,omitempty"`
+	Len                       int64           `json:"len,omitempty"`
+	ChanDir                   types.ChanDir   `json:"chanDir,omitempty"`
+	Enum                      []*wireEnum     `json:"enum,omitempty"`
+}
+
+type wireMember struct {
+	Name         string          `json:"name"`
+	Embedded     bool            `json:"embedded,omitempty"`
+	CommentLines []string        `json:"commentLines,omitempty"`
+	Tags         string          `json:"tags,omitempty"`
+	Type         int             `json:"type"`
+	Position     *token.Position `json:"position,omitempty"`
+}
+
+type wireSignature struct {
+	Receiver    int      `json:"receiver,omitempty"`
+	Parameters  []int    `json:"parameters,omitempty"`
+	Results     []int    `json:"results,omitempty"`
+	TypeParams  []int    `json:"typeParams,omitempty"`
+	Variadic    bool     `json:"variadic,omitempty"`
+	CommentLess []string `json:"commentLines,omitempty"`
+}
+
+type wireEnum struct {
+	Name         string          `json:"name"`
+	Value        string          `json:"value"`
+	CommentLines []string        `json:"commentLines,omitempty"`
+	Position     *token.Position `json:"position,omitempty"`
+	Constant     int             `json:"constant,omitempty"`
+}
+
+// encoder builds the table of the types of a universe.
+type encoder struct {
+	ids   map[*types.Type]int
+	types []*wireType
+}
+
+// encodeUniverse returns the wire form of u.
+func encodeUniverse(u types.Universe) *wireUniverse {
+	e := &encoder{ids: map[*types.Type]int{}}
+	w := &wireUniverse{}
+	paths := make([]string, 0, len(u))
+	for path := range u {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		p := u[path]
+		wp := &wirePackage{
+			Path:        p.Path,
+			SourcePath:  p.SourcePath,
+			Name:        p.Name,
+			DocComments: p.DocComments,
+			Comments:    p.Comments,
+			Types:       e.refMap(p.Types),
+			Functions:   e.refMap(p.Functions),
+			Variables:   e.refMap(p.Variables),
+			Constants:   e.refMap(p.Constants),
+		}
+		for imp := range p.Imports {
+			wp.Imports = append(wp.Imports, imp)
+		}
+		sort.Strings(wp.Imports)
+		w.Packages = append(w.Packages, wp)
+	}
+	w.Types = e.types
+	return w
+}
+
+// ref returns the reference to t, adding it and the types it references to
+// the table if needed.
+func (e *encoder) ref(t *types.Type) int {
+	if t == nil {
+		return 0
+	}
+	if id, ok := e.ids[t]; ok {
+		return id
+	}
+	wt := &wireType{
+		Name:                      t.Name,
+		Kind:                      t.Kind,
+		Position:                  position(t.Position),
+		CommentLines:              t.CommentLines,
+		SecondClosestCommentLines: t.SecondClosestCommentLines,
+		Len:                       t.Len,
+		ChanDir:                   t.ChanDir,
+	}
+	// Register t before its references, which may lead back to it.
+	e.types = append(e.types, wt)
+	id := len(e.types)
+	e.ids[t] = id
+
+	for _, m := range t.Members {
+		wt.Members = append(wt.Members, &wireMember{
+			Name:         m.Name,
+			Embedded:     m.Embedded,
+			CommentLines: m.CommentLines,
+			Tags:         m.Tags,
+			Type:         e.ref(m.Type),
+			Position:     position(m.Position),
+		})
+	}
+	wt.Elem = e.ref(t.Elem)
+	wt.Key = e.ref(t.Key)
+	wt.Underlying = e.ref(t.Underlying)
+	wt.Methods = e.refMap(t.Methods)
+	if s := t.Signature; s != nil {
+		wt.Signature = &wireSignature{
+			Receiver:    e.ref(s.Receiver),
+			Parameters:  e.refs(s.Parameters),
+			Results:     e.refs(s.Results),
+			TypeParams:  e.refs(s.TypeParams),
+			Variadic:    s.Variadic,
+			CommentLess: s.CommentLess,
+		}
+	}
+	wt.TypeParams = e.refs(t.TypeParams)
+	wt.TypeArgs = e.refs(t.TypeArgs)
+	wt.Origin = e.ref(t.Origin)
+	wt.Constraint = e.ref(t.Constraint)
+	for _, v := range t.Enum {
+		wt.Enum = append(wt.Enum, &wireEnum{
+			Name:         v.Name,
+			Value:        v.Value,
+			CommentLines: v.CommentLines,
+			Position:     position(v.Position),
+			Constant:     e.ref(v.Constant),
+		})
+	}
+	return id
+}
+
+func (e *encoder) refs(ts []*types.Type) []int {
+	if len(ts) == 0 {
+		return nil
+	}
+	out := make([]int, len(ts))
+	for i, t := range ts {
+		out[i] = e.ref(t)
+	}
+	return out
+}
+
+func (e *encoder) refMap(ts map[string]*types.Type) map[string]int {
+	if len(ts) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(ts))
+	for name := range ts {
+		names = append(names, name)
+	}
+	// Number the types in a stable order.
+	sort.Strings(names)
+	out := make(map[string]int, len(ts))
+	for _, name := range names {
+		out[name] = e.ref(ts[name])
+	}
+	return out
+}
+
+func position(pos token.Position) *token.Position {
+	if !pos.IsValid() {
+		return nil
+	}
+	return &pos
+}
+
+// decoder rebuilds the types of a wire universe.
+type decoder struct {
+	w     *wireUniverse
+	types []*types.Type
+}
+
+// decodeUniverse rebuilds the universe of w. Builtin types are the ones of
+// the types package, e.g. types.String, as in a parsed universe.
+func decodeUniverse(w *wireUniverse) (types.Universe, error) {
+	u := types.Universe{}
+	d := &decoder{w: w, types: make([]*types.Type, len(w.Types))}
+	// Looking a builtin up adds a marker for unknown names, which must not
+	// end up in u.
+	builtins := types.Universe{}.Package("")
+	shared := map[int]bool{}
+	for i, wt := range w.Types {
+		if wt == nil {
+			return nil, fmt.Errorf("type %d is missing", i+1)
+		}
+		if wt.Kind == types.Builtin && wt.Name.Package == "" {
+			if t := builtins.Type(wt.Name.Name); t.Kind == types.Builtin {
+				d.types[i] = t
+				shared[i] = true
+				continue
+			}
+		}
+		d.types[i] = &types.Type{}
+	}
+
+	for i, wt := range w.Types {
+		if shared[i] {
+			// Shared with the types package, left untouched.
+			continue
+		}
+		if err := d.fill(d.types[i], wt); err != nil {
+			return nil, fmt.Errorf("type %s: %v", wt.Name, err)
+		}
+	}
+
+	for _, wp := range w.Packages {
+		p := u.Package(wp.Path)
+		p.SourcePath = wp.SourcePath
+		p.Name = wp.Name
+		if wp.DocComments != nil {
+			p.DocComments = wp.DocComments
+		}
+		if wp.Comments != nil {
+			p.Comments = wp.Comments
+		}
+		for _, m := range []struct {
+			dst map[string]*types.Type
+			src map[string]int
+		}{
+			{p.Types, wp.Types},
+			{p.Functions, wp.Functions},
+			{p.Variables, wp.Variables},
+			{p.Constants, wp.Constants},
+		} {
+			for name, id := range m.src {
+				t, err := d.ref(id)
+				if err != nil {
+					return nil, fmt.Errorf("package %s: %v", wp.Path, err)
+				}
+				m.dst[name] = t
+			}
+		}
+		u.AddImports(wp.Path, wp.Imports...)
+	}
+	return u, nil
+}
+
+// fill sets the fields of t from wt.
+func (d *decoder) fill(t *types.Type, wt *wireType) (err error) {
+	ref := func(id int) *types.Type {
+		r, rerr := d.ref(id)
+		if rerr != nil && err == nil {
+			err = rerr
+		}
+		return r
+	}
+	refs := func(ids []int) []*types.Type {
+		if len(ids) == 0 {
+			return nil
+		}
+		out := make([]*types.Type, len(ids))
+		for i, id := range ids {
+			out[i] = ref(id)
+		}
+		return out
+	}
+
+	t.Name = wt.Name
+	t.Kind = wt.Kind
+	if wt.Position != nil {
+		t.Position = *wt.Position
+	}
+	t.CommentLines = wt.CommentLines
+	t.SecondClosestCommentLines = wt.SecondClosestCommentLines
+	for _, m := range wt.Members {
+		member := types.Member{
+			Name:         m.Name,
+			Embedded:     m.Embedded,
+			CommentLines: m.CommentLines,
+			Tags:         m.Tags,
+			Type:         ref(m.Type),
+		}
+		if m.Position != nil {
+			member.Position = *m.Position
+		}
+		member.CacheStructTags()
+		t.Members = append(t.Members, member)
+	}
+	t.Elem = ref(wt.Elem)
+	t.Key = ref(wt.Key)
+	t.Underlying = ref(wt.Underlying)
+	if len(wt.Methods) > 0 {
+		t.Methods = make(map[string]*types.Type, len(wt.Methods))
+		for name, id := range wt.Methods {
+			t.Methods[name] = ref(id)
+		}
+	}
+	if s := wt.Signature; s != nil {
+		t.Signature = &types.Signature{
+			Receiver:    ref(s.Receiver),
+			Parameters:  refs(s.Parameters),
+			Results:     refs(s.Results),
+			TypeParams:  refs(s.TypeParams),
+			Variadic:    s.Variadic,
+			CommentLess: s.CommentLess,
+		}
+	}
+	t.TypeParams = refs(wt.TypeParams)
+	t.TypeArgs = refs(wt.TypeArgs)
+	t.Origin = ref(wt.Origin)
+	t.Constraint = ref(wt.Constraint)
+	t.Len = wt.Len
+	t.ChanDir = wt.ChanDir
+	for _, v := range wt.Enum {
+		value := &types.EnumValue{
+			Name:         v.Name,
+			Value:        v.Value,
+			CommentLines: v.CommentLines,
+			Constant:     ref(v.Constant),
+		}
+		if v.Position != nil {
+			value.Position = *v.Position
+		}
+		t.Enum = append(t.Enum, value)
+	}
+	return err
+}
+
+func (d *decoder) ref(id int) (*types.Type, error) {
+	if id == 0 {
+		return nil, nil
+	}
+	if id < 0 || id > len(d.types) {
+		return nil, fmt.Errorf("reference to unknown type %d", id)
+	}
+	return d.types[id-1], nil
+}
@@ -0,0 +1,240 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package testing runs generators against test inputs and compares their
+// output with golden files.
+//
+// A test case is a directory, usually under testdata, laid out as:
+//
+//	input/    the go packages the generators run on
+//	golden/   the files they are expected to generate, with a .golden suffix
+//
+// The input packages are type checked together with the files generated
+// for them. Running the tests with -update rewrites the golden files from the output.
+package testing
+
+import (
+	"flag"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	stdtesting "testing"
+
+	"github.com/vine-io/gogogen/gogenerator/args"
+	"github.com/vine-io/gogogen/gogenerator/generator"
+	"github.com/vine-io/gogogen/util/diff"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the generator tests")
+
+const goldenSuffix = ".golden"
+
+// Case is a golden test of one or more generators.
+type Case struct {
+	// The directory of the test case.
+	Dir string
+	// The import path of Dir/input. The go files in a subdirectory of it
+	// belong to the package of the matching import path.
+	Package string
+	// The generators to run. Their arguments are used as with args.Generate.
+	Runs []args.Run
+}
+
+// Run runs the generators of c on its input and reports the generated
+// packages which don't type check, the generated files which differ from their golden file, the golden files which aren't
+// generated and the generated files which have no golden file.
+func Run(t *stdtesting.T, c Case) {
+	t.Helper()
+	src, err := readInput(filepath.Join(c.Dir, "input"), c.Package)
+	if err != nil {
+		t.Fatalf("reading the input: %v", err)
+	}
+	out, err := args.Generate(src, c.Runs...)
+	if err != nil {
+		t.Fatalf("generating: %v", err)
+	}
+	if err := typeCheck(src, out); err != nil {
+		t.Errorf("the generated packages don't compile: %v", err)
+	}
+	compare(t, c.Dir, c.Package, out)
+}
+
+// DiskCase is a golden test of a generator which reads its input packages
+// from disk and writes through a generator.OutputFS, like goproto-gen and
+// gogorm-gen.
+type DiskCase struct {
+	// The directory of the test case.
+	Dir string
+	// The import path of Dir/input, which the generator must be able to
+	// find it by, usually as a package of the module under test.
+	Package string
+	// Generate runs the generator on Package, writing to out.
+	Generate func(out generator.OutputFS) error
+	// If set, the generated packages aren't type checked, for generators
+	// whose output imports packages the module under test doesn't have.
+	NoTypeCheck bool
+}
+
+// RunDisk runs the generator of c on its input and reports what Run does.
+// The input is left untouched.
+func RunDisk(t *stdtesting.T, c DiskCase) {
+	t.Helper()
+	inputDir, err := filepath.Abs(filepath.Join(c.Dir, "input"))
+	if err != nil {
+		t.Fatalf("resolving the input: %v", err)
+	}
+	src, err := readInput(inputDir, c.Package)
+	if err != nil {
+		t.Fatalf("reading the input: %v", err)
+	}
+	output := generator.NewMemoryFS()
+	if err := c.Generate(output); err != nil {
+		t.Fatalf("generating: %v", err)
+	}
+
+	// The written files are keyed by their import path, like the output of
+	// args.Generate.
+	out := map[string][]byte{}
+	for p, data := range output.Files() {
+		rel, err := filepath.Rel(inputDir, p)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			t.Errorf("%s: written outside of the input", p)
+			continue
+		}
+		out[path.Join(c.Package, filepath.ToSlash(rel))] = data
+	}
+	if !c.NoTypeCheck {
+		if err := typeCheck(src, out); err != nil {
+			t.Errorf("the generated packages don't compile: %v", err)
+		}
+	}
+	compare(t, c.Dir, c.Package, out)
+}
+
+// compare compares the generated files out with the golden files of the
+// test case dir, or rewrites them with -update.
+func compare(t *stdtesting.T, dir, pkg string, out map[string][]byte) {
+	t.Helper()
+	// The golden files are named after the generated files, relative to
+	// pkg when they are in it.
+	got := map[string][]byte{}
+	for p, data := range out {
+		if rel := strings.TrimPrefix(p, pkg+"/"); rel != p {
+			p = rel
+		}
+		got[filepath.FromSlash(p)+goldenSuffix] = data
+	}
+
+	goldenDir := filepath.Join(dir, "golden")
+	if *update {
+		if err := writeGolden(goldenDir, got); err != nil {
+			t.Fatalf("updating the golden files: %v", err)
+		}
+		return
+	}
+
+	want, err := readGolden(goldenDir)
+	if err != nil {
+		t.Fatalf("reading the golden files: %v", err)
+	}
+	for _, name := range sortedKeys(got) {
+		wantData, ok := want[name]
+		if !ok {
+			t.Errorf("%s: generated, but has no golden file (run with -update to add it)", name)
+			continue
+		}
+		if d := diff.Unified("golden/"+filepath.ToSlash(name), "generated", wantData, got[name]); d != "" {
+			t.Errorf("%s: output differs from the golden file (run with -update to accept it):\n%s", name, d)
+		}
+	}
+	for _, name := range sortedKeys(want) {
+		if _, ok := got[name]; !ok {
+			t.Errorf("%s: golden file, but isn't generated anymore (run with -update to remove it)", name)
+		}
+	}
+}
+
+// readInput reads the go files under dir, keyed as args.Generate wants them.
+func readInput(dir, pkg string) (map[string][]byte, error) {
+	src := map[string][]byte{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(p, ".go") {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		src[path.Join(pkg, filepath.ToSlash(rel))] = data
+		return nil
+	})
+	return src, err
+}
+
+func readGolden(dir string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if os.IsNotExist(err) && p == dir {
+			return nil
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[rel] = data
+		return nil
+	})
+	return files, err
+}
+
+// writeGolden replaces the files under dir with files.
+func writeGolden(dir string, files map[string][]byte) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	for name, data := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(p, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string][]byte) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
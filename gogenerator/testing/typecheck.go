@@ -0,0 +1,114 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testing
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"sort"
+	"strings"
+)
+
+// typeCheck type checks the packages made of the input and the generated go
+// files, keyed by their import path and file name. Imports of other packages
+// are type checked from their source.
+func typeCheck(files ...map[string][]byte) error {
+	pkgs := map[string]map[string][]byte{}
+	for _, m := range files {
+		for p, data := range m {
+			if !strings.HasSuffix(p, ".go") || strings.HasSuffix(p, "_test.go") {
+				continue
+			}
+			dir := path.Dir(p)
+			if pkgs[dir] == nil {
+				pkgs[dir] = map[string][]byte{}
+			}
+			pkgs[dir][p] = data
+		}
+	}
+
+	fset := token.NewFileSet()
+	im := &overlayImporter{
+		fset:    fset,
+		pkgs:    pkgs,
+		checked: map[string]*types.Package{},
+		source:  importer.ForCompiler(fset, "source", nil).(types.ImporterFrom),
+	}
+	dirs := make([]string, 0, len(pkgs))
+	for dir := range pkgs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		if _, err := im.Import(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// overlayImporter imports the packages it holds the files of, and the
+// others from source.
+type overlayImporter struct {
+	fset    *token.FileSet
+	pkgs    map[string]map[string][]byte
+	checked map[string]*types.Package
+	source  types.ImporterFrom
+}
+
+func (im *overlayImporter) Import(p string) (*types.Package, error) {
+	if pkg, ok := im.checked[p]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("import cycle through %s", p)
+		}
+		return pkg, nil
+	}
+	files, ok := im.pkgs[p]
+	if !ok {
+		return im.source.ImportFrom(p, "", 0)
+	}
+	im.checked[p] = nil
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var parsed []*ast.File
+	for _, name := range names {
+		f, err := parser.ParseFile(im.fset, name, files[name], 0)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, f)
+	}
+
+	var errs []string
+	conf := types.Config{
+		Importer: im,
+		Error:    func(err error) { errs = append(errs, err.Error()) },
+	}
+	pkg, _ := conf.Check(p, im.fset, parsed, nil)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("type checking %s:\n\t%s", p, strings.Join(errs, "\n\t"))
+	}
+	im.checked[p] = pkg
+	return pkg, nil
+}
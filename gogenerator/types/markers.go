@@ -0,0 +1,309 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MarkerScope is a set of places where a comment marker may be written.
+type MarkerScope int
+
+const (
+	// In the comments of the doc.go file of a package.
+	PackageScope MarkerScope = 1 << iota
+	// In the comments of a type declaration.
+	TypeScope
+	// In the comments of a struct field.
+	FieldScope
+)
+
+func (s MarkerScope) String() string {
+	var out []string
+	if s&PackageScope != 0 {
+		out = append(out, "package")
+	}
+	if s&TypeScope != 0 {
+		out = append(out, "type")
+	}
+	if s&FieldScope != 0 {
+		out = append(out, "field")
+	}
+	return strings.Join(out, ",")
+}
+
+// MarkerValue is the kind of value a comment marker takes.
+type MarkerValue int
+
+const (
+	// The marker takes no value, e.g. "+embedded".
+	FlagMarker MarkerValue = iota
+	// The marker is either "true" or "false", e.g. "+gogo:genset=true".
+	BoolMarker
+	// The marker takes any value, or one of Marker.Allowed.
+	StringMarker
+)
+
+func (v MarkerValue) String() string {
+	switch v {
+	case FlagMarker:
+		return "none"
+	case BoolMarker:
+		return "bool"
+	default:
+		return "string"
+	}
+}
+
+// Marker declares a comment marker understood by a generator, i.e. a comment
+// line of the form "+name" or "+name=value", see ExtractCommentTags.
+type Marker struct {
+	// The name of the marker, without the leading "+". A name ending in "*"
+	// declares all the markers starting with what precedes it, e.g.
+	// "protobuf.options.*".
+	Name  string
+	Scope MarkerScope
+	Value MarkerValue
+	// If set, the values a StringMarker accepts. Only the part of the value
+	// up to the first ',' is checked, the rest holds arguments such as in
+	// "+gogo:deepcopy=package,register".
+	Allowed []string
+	// The generator which reads the marker.
+	Generator   string
+	Description string
+}
+
+// match returns whether the marker declares name.
+func (m *Marker) match(name string) bool {
+	if prefix := strings.TrimSuffix(m.Name, "*"); prefix != m.Name {
+		return strings.HasPrefix(name, prefix)
+	}
+	return m.Name == name
+}
+
+// check returns what is wrong with a value of the marker, or "".
+func (m *Marker) check(value string, hasValue bool) string {
+	switch m.Value {
+	case FlagMarker:
+		if hasValue {
+			return fmt.Sprintf("marker %q takes no value", "+"+m.Name)
+		}
+	case BoolMarker:
+		if value != "true" && value != "false" {
+			return fmt.Sprintf("marker %q must be true or false, found: %q", "+"+m.Name, value)
+		}
+	case StringMarker:
+		if len(m.Allowed) == 0 {
+			return ""
+		}
+		v := strings.SplitN(value, ",", 2)[0]
+		for _, allowed := range m.Allowed {
+			if v == allowed {
+				return ""
+			}
+		}
+		return fmt.Sprintf("marker %q must be one of %s, found: %q", "+"+m.Name, strings.Join(m.Allowed, ", "), v)
+	}
+	return ""
+}
+
+// MarkerProblem is an unknown, misplaced or malformed marker found by a
+// MarkerRegistry.
+type MarkerProblem struct {
+	Position token.Position
+	Message  string
+}
+
+func (p MarkerProblem) String() string {
+	if p.Position.Filename == "" {
+		return p.Message
+	}
+	return p.Position.String() + ": " + p.Message
+}
+
+// MarkerRegistry holds the markers declared by generators and checks the
+// comments of parsed packages against them.
+type MarkerRegistry struct {
+	markers []*Marker
+}
+
+// NewMarkerRegistry returns a registry holding markers.
+func NewMarkerRegistry(markers ...*Marker) *MarkerRegistry {
+	r := &MarkerRegistry{}
+	r.Register(markers...)
+	return r
+}
+
+// Register adds markers to the registry. Markers which are already
+// registered, e.g. because two generators read them, are merged.
+func (r *MarkerRegistry) Register(markers ...*Marker) {
+	for _, m := range markers {
+		if existing := r.Lookup(m.Name); existing != nil && existing.Name == m.Name {
+			merged := *existing
+			merged.Scope |= m.Scope
+			if !strings.Contains(merged.Generator, m.Generator) {
+				merged.Generator += "," + m.Generator
+			}
+			*existing = merged
+			continue
+		}
+		copied := *m
+		r.markers = append(r.markers, &copied)
+	}
+}
+
+// Markers returns the registered markers sorted by name.
+func (r *MarkerRegistry) Markers() []*Marker {
+	out := append([]*Marker{}, r.markers...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Lookup returns the marker which declares name, or nil.
+func (r *MarkerRegistry) Lookup(name string) *Marker {
+	for _, m := range r.markers {
+		if m.match(name) {
+			return m
+		}
+	}
+	return nil
+}
+
+// Check returns the problems with the markers in lines, which are comments
+// at pos in the given scope.
+//
+// A marker which isn't registered is only reported if it looks like a
+// registered one: if it extends a registered name, e.g. "+gogo:deepcopy-gen",
+// or is a near miss of one, e.g. "+primarykey". Other markers are left alone
+// since they may be meant for other tools.
+func (r *MarkerRegistry) Check(scope MarkerScope, pos token.Position, lines []string) []MarkerProblem {
+	var out []MarkerProblem
+	for _, line := range lines {
+		line = strings.Trim(line, " ")
+		if !strings.HasPrefix(line, "+") {
+			continue
+		}
+		kv := strings.SplitN(line[1:], "=", 2)
+		name := kv[0]
+		if name == "" || strings.ContainsAny(name, " \t") {
+			// Not a marker, just text starting with a '+'.
+			continue
+		}
+		value := ""
+		if len(kv) == 2 {
+			value = kv[1]
+		}
+
+		m := r.Lookup(name)
+		if m == nil {
+			if msg := r.unknown(name); msg != "" {
+				out = append(out, MarkerProblem{Position: pos, Message: msg})
+			}
+			continue
+		}
+		if m.Scope&scope == 0 {
+			out = append(out, MarkerProblem{Position: pos, Message: fmt.Sprintf("marker %q is not allowed on a %s, only on a %s", "+"+name, scope, m.Scope)})
+			continue
+		}
+		if msg := m.check(value, len(kv) == 2); msg != "" {
+			out = append(out, MarkerProblem{Position: pos, Message: msg})
+		}
+	}
+	return out
+}
+
+// unknown returns the problem with name, which isn't registered, or "" if
+// it doesn't look like a registered marker.
+func (r *MarkerRegistry) unknown(name string) string {
+	for _, m := range r.Markers() {
+		if strings.HasSuffix(m.Name, "*") {
+			continue
+		}
+		if editDistance(strings.ToLower(name), strings.ToLower(m.Name)) <= 2 && len(name) > 3 {
+			return fmt.Sprintf("unknown marker %q, did you mean %q?", "+"+name, "+"+m.Name)
+		}
+	}
+	for _, m := range r.markers {
+		if strings.HasPrefix(name, m.Name) && strings.ContainsAny(name[len(m.Name):len(m.Name)+1], ":.-") {
+			return fmt.Sprintf("unknown marker %q", "+"+name)
+		}
+	}
+	return ""
+}
+
+// CheckPackage returns the problems with the markers in the comments of p,
+// of its types and of their fields.
+func (r *MarkerRegistry) CheckPackage(p *Package) []MarkerProblem {
+	var out []MarkerProblem
+	if p.SourcePath != "" {
+		pos := token.Position{Filename: filepath.Join(p.SourcePath, "doc.go")}
+		out = append(out, r.Check(PackageScope, pos, p.Comments)...)
+	}
+
+	names := make([]string, 0, len(p.Types))
+	for name := range p.Types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t := p.Types[name]
+		out = append(out, r.Check(TypeScope, t.Position, t.SecondClosestCommentLines)...)
+		out = append(out, r.Check(TypeScope, t.Position, t.CommentLines)...)
+		for _, m := range t.Members {
+			// The comments before a member declared on the line of its
+			// struct are the doc of the type, which was checked above.
+			if m.Position.Filename == t.Position.Filename && m.Position.Line == t.Position.Line {
+				continue
+			}
+			out = append(out, r.Check(FieldScope, m.Position, m.CommentLines)...)
+		}
+	}
+	return out
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
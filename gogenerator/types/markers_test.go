@@ -0,0 +1,91 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"go/token"
+	"reflect"
+	"testing"
+)
+
+func TestMarkerRegistryCheck(t *testing.T) {
+	r := NewMarkerRegistry(
+		&Marker{Name: "gogo:deepcopy", Scope: PackageScope | TypeScope, Value: StringMarker, Allowed: []string{"package", "true", "false"}, Generator: "deepcopy-gen"},
+		&Marker{Name: "gogo:genset", Scope: TypeScope, Value: BoolMarker, Generator: "goproto-gen"},
+		&Marker{Name: "primaryKey", Scope: FieldScope, Value: FlagMarker, Generator: "gogorm-gen"},
+		&Marker{Name: "protobuf.options.*", Scope: PackageScope, Value: StringMarker, Generator: "goproto-gen"},
+	)
+	// Markers read by several generators are merged.
+	r.Register(&Marker{Name: "gogo:genset", Scope: PackageScope, Value: BoolMarker, Generator: "gogorm-gen"})
+	if m := r.Lookup("gogo:genset"); m.Scope != PackageScope|TypeScope || m.Generator != "goproto-gen,gogorm-gen" {
+		t.Errorf("merged marker: got scope %v and generator %q", m.Scope, m.Generator)
+	}
+	if got := len(r.Markers()); got != 4 {
+		t.Errorf("got %d markers, want 4", got)
+	}
+
+	pos := token.Position{Filename: "api/types.go", Line: 3, Column: 1}
+	for _, tc := range []struct {
+		scope MarkerScope
+		line  string
+		want  string
+	}{
+		{TypeScope, "+gogo:deepcopy=true", ""},
+		{PackageScope, "+gogo:deepcopy=package,register", ""},
+		{PackageScope, "+protobuf.options.(gogoproto.marshaler_all)=true", ""},
+		{FieldScope, "+primaryKey", ""},
+		{TypeScope, "+k8s:deepcopy-gen=true", ""},
+		{TypeScope, "+ not a marker", ""},
+		{TypeScope, "some text", ""},
+		{TypeScope, "+gogo:deepcopy=yes", `api/types.go:3:1: marker "+gogo:deepcopy" must be one of package, true, false, found: "yes"`},
+		{TypeScope, "+gogo:genset=1", `api/types.go:3:1: marker "+gogo:genset" must be true or false, found: "1"`},
+		{FieldScope, "+primaryKey=true", `api/types.go:3:1: marker "+primaryKey" takes no value`},
+		{TypeScope, "+primaryKey", `api/types.go:3:1: marker "+primaryKey" is not allowed on a type, only on a field`},
+		{FieldScope, "+primarykey", `api/types.go:3:1: unknown marker "+primarykey", did you mean "+primaryKey"?`},
+		{TypeScope, "+gogo:depcopy=true", `api/types.go:3:1: unknown marker "+gogo:depcopy", did you mean "+gogo:deepcopy"?`},
+		{TypeScope, "+gogo:deepcopy-gen=true", `api/types.go:3:1: unknown marker "+gogo:deepcopy-gen"`},
+	} {
+		var got []string
+		for _, p := range r.Check(tc.scope, pos, []string{tc.line}) {
+			got = append(got, p.String())
+		}
+		var want []string
+		if tc.want != "" {
+			want = []string{tc.want}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s on a %v: got %q, want %q", tc.line, tc.scope, got, want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"primarykey", "primarykey", 0},
+		{"kitten", "sitting", 3},
+		{"depcopy", "deepcopy", 1},
+		{"genste", "genset", 2},
+	} {
+		if got := editDistance(tc.a, tc.b); got != tc.want {
+			t.Errorf("editDistance(%q, %q): got %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// StructTags is the parsed form of a struct tag such as
+//
+//	`json:"name,omitempty" gorm:"column:name;serializer:json"`
+//
+// The tag is parsed the first time it is looked into, malformed parts are
+// ignored like reflect.StructTag does.
+type StructTags struct {
+	raw  string
+	once sync.Once
+	tags []StructTag
+}
+
+// StructTag is a single key of a struct tag and its unquoted value.
+type StructTag struct {
+	Key   string
+	Value string
+}
+
+// ParseStructTags returns the parsed form of tag, which is written without the
+// enclosing backquotes.
+func ParseStructTags(tag string) *StructTags {
+	return &StructTags{raw: tag}
+}
+
+// StructTags returns the parsed form of m.Tags. The tags of the members the
+// parser builds are parsed once, those of others on every call; see
+// CacheStructTags.
+func (m Member) StructTags() *StructTags {
+	if m.structTags != nil && m.structTags.raw == m.Tags {
+		return m.structTags
+	}
+	return ParseStructTags(m.Tags)
+}
+
+// CacheStructTags makes m, and the copies of it made afterwards, share the
+// parsed form of m.Tags, so that StructTags only parses them once.
+func (m *Member) CacheStructTags() {
+	m.structTags = ParseStructTags(m.Tags)
+}
+
+// String returns the tag as it was written.
+func (s *StructTags) String() string {
+	return s.raw
+}
+
+// All returns the keys of the tag in the order they are written.
+func (s *StructTags) All() []StructTag {
+	s.once.Do(s.parse)
+	return s.tags
+}
+
+// Lookup returns the value of key, and whether the tag has the key.
+func (s *StructTags) Lookup(key string) (string, bool) {
+	for _, t := range s.All() {
+		if t.Key == key {
+			return t.Value, true
+		}
+	}
+	return "", false
+}
+
+// Get returns the value of key, or "" if the tag doesn't have the key.
+func (s *StructTags) Get(key string) string {
+	v, _ := s.Lookup(key)
+	return v
+}
+
+// Options returns the value of key split into its options. Gorm options are
+// separated by ';', those of all other keys by ','. Empty options are kept,
+// since they are meaningful in positional tags.
+func (s *StructTags) Options(key string) []string {
+	v, ok := s.Lookup(key)
+	if !ok || v == "" {
+		return nil
+	}
+	sep := ","
+	if key == "gorm" {
+		sep = ";"
+	}
+	return strings.Split(v, sep)
+}
+
+// Set returns the tags with the value of key replaced, or added at the end if
+// the tags don't have the key. The other keys keep their values, malformed
+// parts are dropped.
+func (s *StructTags) Set(key, value string) *StructTags {
+	parts := []string{}
+	found := false
+	for _, t := range s.All() {
+		if t.Key == key {
+			if found {
+				continue
+			}
+			t.Value, found = value, true
+		}
+		parts = append(parts, t.Key+":"+strconv.Quote(t.Value))
+	}
+	if !found {
+		parts = append(parts, key+":"+strconv.Quote(value))
+	}
+	return ParseStructTags(strings.Join(parts, " "))
+}
+
+func (s *StructTags) parse() {
+	// This follows reflect.StructTag.Lookup.
+	tag := s.raw
+	for tag != "" {
+		// Skip leading space.
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+
+		// Scan to colon. A space, a quote or a control character is a syntax
+		// error.
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+
+		// Scan quoted string to find value.
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		value, err := strconv.Unquote(tag[:i+1])
+		tag = tag[i+1:]
+		if err != nil {
+			break
+		}
+		s.tags = append(s.tags, StructTag{Key: key, Value: value})
+	}
+}
+
+// JSONTag is the parsed form of a json tag, e.g. `json:"name,omitempty"`.
+type JSONTag struct {
+	// The name of the field, empty if the tag doesn't rename it.
+	Name string
+	// Whether the field is skipped, i.e. `json:"-"`.
+	Ignored   bool
+	OmitEmpty bool
+	// Whether the fields of the member are inlined into the enclosing
+	// object, i.e. `json:",inline"`.
+	Inline bool
+	// Whether the value is encoded as a JSON string, i.e. `json:",string"`.
+	String bool
+}
+
+// JSON returns the json key of the tag.
+func (s *StructTags) JSON() JSONTag {
+	opts := s.Options("json")
+	if len(opts) == 0 {
+		return JSONTag{}
+	}
+	if len(opts) == 1 && opts[0] == "-" {
+		return JSONTag{Ignored: true}
+	}
+	out := JSONTag{Name: opts[0]}
+	for _, opt := range opts[1:] {
+		switch strings.TrimSpace(opt) {
+		case "omitempty":
+			out.OmitEmpty = true
+		case "inline":
+			out.Inline = true
+		case "string":
+			out.String = true
+		}
+	}
+	return out
+}
+
+// GormTag is the parsed form of a gorm tag, e.g.
+// `gorm:"column:name;primaryKey;serializer:json"`.
+type GormTag struct {
+	Column     string
+	Serializer string
+	PrimaryKey bool
+	// Whether the field is skipped, i.e. `gorm:"-"` or `gorm:"-:all"`.
+	Ignored bool
+	// All the settings of the tag. Gorm treats their names case-insensitively,
+	// so they are upper-cased here as gorm does.
+	Settings map[string]string
+}
+
+// Gorm returns the gorm key of the tag.
+func (s *StructTags) Gorm() GormTag {
+	out := GormTag{Settings: map[string]string{}}
+	for _, opt := range s.Options("gorm") {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+		kv := strings.SplitN(opt, ":", 2)
+		k := strings.ToUpper(strings.TrimSpace(kv[0]))
+		v := ""
+		if len(kv) == 2 {
+			v = kv[1]
+		}
+		out.Settings[k] = v
+	}
+
+	out.Column = out.Settings["COLUMN"]
+	out.Serializer = out.Settings["SERIALIZER"]
+	// "-:migration" only hides the field from migrations.
+	if v, ok := out.Settings["-"]; ok && (v == "" || v == "all") {
+		out.Ignored = true
+	}
+	if _, ok := out.Settings["PRIMARYKEY"]; ok {
+		out.PrimaryKey = true
+	}
+	if _, ok := out.Settings["PRIMARY_KEY"]; ok {
+		out.PrimaryKey = true
+	}
+	return out
+}
+
+// ProtobufTag is the parsed form of a protobuf tag, e.g.
+// `protobuf:"bytes,3,opt,name=id,json=id,proto3"`.
+type ProtobufTag struct {
+	// Whether the field is skipped, i.e. `protobuf:"-"`.
+	Ignored bool
+	// The wire type, or the name of a custom protobuf type.
+	Wire   string
+	Number int
+	// One of "opt", "req" or "rep".
+	Label string
+	Name  string
+	JSON  string
+	// Whether the field is a proto3 field.
+	Proto3 bool
+	// The other key=value options of the tag, by key.
+	Extras map[string]string
+}
+
+// Protobuf returns the protobuf key of the tag, or an error if it is
+// malformed. It returns the zero ProtobufTag if the tag has no protobuf key.
+func (s *StructTags) Protobuf() (ProtobufTag, error) {
+	opts := s.Options("protobuf")
+	if len(opts) == 0 {
+		return ProtobufTag{}, nil
+	}
+	if len(opts) == 1 && opts[0] == "-" {
+		return ProtobufTag{Ignored: true}, nil
+	}
+	if len(opts) < 3 {
+		return ProtobufTag{}, fmt.Errorf("malformed 'protobuf' tag, not enough segments")
+	}
+	number, err := strconv.Atoi(opts[1])
+	if err != nil {
+		return ProtobufTag{}, fmt.Errorf("malformed 'protobuf' tag, field ID is %q which is not an integer: %v", opts[1], err)
+	}
+
+	out := ProtobufTag{Wire: opts[0], Number: number, Label: opts[2], Extras: map[string]string{}}
+	for i, opt := range opts[3:] {
+		if strings.Contains(opt, "[") || strings.Contains(opt, "]") {
+			continue
+		}
+		if opt == "proto3" {
+			out.Proto3 = true
+			continue
+		}
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 {
+			return ProtobufTag{}, fmt.Errorf("malformed 'protobuf' tag, tag %d should be key=value, got %q", i+4, opt)
+		}
+		switch kv[0] {
+		case "name":
+			out.Name = kv[1]
+		case "json":
+			out.JSON = kv[1]
+		default:
+			out.Extras[kv[0]] = kv[1]
+		}
+	}
+	return out, nil
+}
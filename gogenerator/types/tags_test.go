@@ -0,0 +1,62 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStructTags(t *testing.T) {
+	m := Member{Name: "Name", Tags: `json:"name,omitempty" gorm:"column:name;primaryKey" protobuf:"bytes,1,opt,name=name,proto3"`}
+	if m.StructTags() == m.StructTags() {
+		t.Errorf("tags of a member not built by the parser are cached")
+	}
+
+	m.CacheStructTags()
+	copied := m
+	tags := copied.StructTags()
+	if tags != m.StructTags() {
+		t.Errorf("copies of a member parse its tags again")
+	}
+
+	if got, want := tags.JSON(), (JSONTag{Name: "name", OmitEmpty: true}); got != want {
+		t.Errorf("json: got %+v, want %+v", got, want)
+	}
+	if got := tags.Gorm(); got.Column != "name" || !got.PrimaryKey {
+		t.Errorf("gorm: got %+v", got)
+	}
+	pb, err := tags.Protobuf()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (ProtobufTag{Wire: "bytes", Number: 1, Label: "opt", Name: "name", Proto3: true, Extras: map[string]string{}}); !reflect.DeepEqual(pb, want) {
+		t.Errorf("protobuf: got %+v, want %+v", pb, want)
+	}
+
+	set := tags.Set("protobuf", "bytes,2,opt,name=id,proto3").Set("yaml", "name")
+	if got, want := set.String(), `json:"name,omitempty" gorm:"column:name;primaryKey" protobuf:"bytes,2,opt,name=id,proto3" yaml:"name"`; got != want {
+		t.Errorf("set: got %s, want %s", got, want)
+	}
+	if got := tags.Get("protobuf"); got != "bytes,1,opt,name=name,proto3" {
+		t.Errorf("set changed the original tags: %s", got)
+	}
+
+	// Changed tags are parsed again.
+	copied.Tags = `json:"other"`
+	if got := copied.StructTags().JSON().Name; got != "other" {
+		t.Errorf("changed tags: got json name %q", got)
+	}
+}
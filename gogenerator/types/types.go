@@ -14,7 +14,17 @@
 
 package types
 
-import "strings"
+import (
+	"go/token"
+	"strings"
+	"sync"
+)
+
+// universeLock guards the maps of universes against the lookups which add
+// packages to them. The maps of each package are guarded by its own lock, so
+// that generators running concurrently can share a universe as long as they
+// go through its methods, and only contend when they look up the same package.
+var universeLock sync.RWMutex
 
 // Ref makes a reference to the given type.  It can only be used for e.g.
 // passing to namers.
@@ -81,6 +91,11 @@ const (
 	// Interface is any type that could have differing types at run time.
 	Interface Kind = "Interface"
 
+	// TypeParam is a type parameter of a generic type or function, e.g. V in:
+	//  type Array[V Builtin] []V
+	// The constraint of the type parameter can be found in Constraint.
+	TypeParam Kind = "TypeParam"
+
 	// The remaining types are included for completeness, but are not well
 	// supported.
 	Array Kind = "Array" // Array is just like slice, but has a fixed length.
@@ -142,10 +157,15 @@ type Package struct {
 	// Packages imported by this package, indexed by (canonicalized)
 	// package path.
 	Imports map[string]*Package
+
+	// Guards the maps above against the lookups which add to them.
+	mu sync.RWMutex
 }
 
 // Has returns true if the given name references a type known to this packages.
 func (p *Package) Has(name string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	_, has := p.Types[name]
 	return has
 }
@@ -154,6 +174,15 @@ func (p *Package) Has(name string) bool {
 // defined, this will add it and return the raw Type value.  The caller is
 // expected to finish initialization
 func (p *Package) Type(typeName string) *Type {
+	p.mu.RLock()
+	t, ok := p.Types[typeName]
+	p.mu.RUnlock()
+	if ok {
+		return t
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	if t, ok := p.Types[typeName]; ok {
 		return t
 	}
@@ -164,7 +193,7 @@ func (p *Package) Type(typeName string) *Type {
 			return t
 		}
 	}
-	t := &Type{Name: Name{Package: p.Path, Name: typeName}}
+	t = &Type{Name: Name{Package: p.Path, Name: typeName}}
 	p.Types[typeName] = t
 	return t
 }
@@ -174,6 +203,8 @@ func (p *Package) Type(typeName string) *Type {
 // caller's responsibility to finish construction of the function by setting
 // Underlying to the correct type.
 func (p *Package) Function(funcName string) *Type {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	if t, ok := p.Functions[funcName]; ok {
 		return t
 	}
@@ -188,6 +219,8 @@ func (p *Package) Function(funcName string) *Type {
 // responsibility to finish construction of the variable by setting Underlying
 // to the correct type.
 func (p *Package) Variable(varName string) *Type {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	if t, ok := p.Variables[varName]; ok {
 		return t
 	}
@@ -202,6 +235,8 @@ func (p *Package) Variable(varName string) *Type {
 // responsibility to finish construction of the constant by setting Underlying
 // to the correct type.
 func (p *Package) Constant(constName string) *Type {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	if t, ok := p.Constants[constName]; ok {
 		return t
 	}
@@ -214,6 +249,8 @@ func (p *Package) Constant(constName string) *Type {
 // HasImport returns true if p imports packageName. Package names include the
 // package directory.
 func (p *Package) HasImport(packageName string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	_, has := p.Imports[packageName]
 	return has
 }
@@ -260,7 +297,10 @@ func (u Universe) Constant(n Name) *Type {
 func (u Universe) AddImports(packagePath string, importPaths ...string) {
 	p := u.Package(packagePath)
 	for _, i := range importPaths {
-		p.Imports[i] = u.Package(i)
+		imp := u.Package(i)
+		p.mu.Lock()
+		p.Imports[i] = imp
+		p.mu.Unlock()
 	}
 }
 
@@ -269,10 +309,19 @@ func (u Universe) AddImports(packagePath string, importPaths ...string) {
 // If a marker is created, it's the caller's responsibility to finish
 // construction of the package.
 func (u Universe) Package(packagePath string) *Package {
+	universeLock.RLock()
+	p, ok := u[packagePath]
+	universeLock.RUnlock()
+	if ok {
+		return p
+	}
+
+	universeLock.Lock()
+	defer universeLock.Unlock()
 	if p, ok := u[packagePath]; ok {
 		return p
 	}
-	p := &Package{
+	p = &Package{
 		Path:        packagePath,
 		DocComments: []string{},
 		Comments:    []string{},
@@ -286,6 +335,26 @@ func (u Universe) Package(packagePath string) *Package {
 	return p
 }
 
+// Lookup returns the Package for the given path, or nil if there is none.
+// Unlike Package, it never creates a marker.
+func (u Universe) Lookup(packagePath string) *Package {
+	universeLock.RLock()
+	defer universeLock.RUnlock()
+	return u[packagePath]
+}
+
+// Snapshot returns a copy of u, which can be ranged over while the lookups
+// of concurrent generators add packages to u.
+func (u Universe) Snapshot() Universe {
+	universeLock.RLock()
+	defer universeLock.RUnlock()
+	s := make(Universe, len(u))
+	for path, p := range u {
+		s[path] = p
+	}
+	return s
+}
+
 // Type represents a subset of possible go types.
 type Type struct {
 	// There are two general categories of types, those explicitly named
@@ -301,6 +370,11 @@ type Type struct {
 	// The general kind of this type
 	Kind Kind
 
+	// If this type (or function, variable, or const if Kind == DeclarationOf)
+	// was declared in a parsed file, this is the position of its name in that
+	// file. Otherwise it is the zero Position, see Position.IsValid.
+	Position token.Position
+
 	// If there are comment lines immediately before the type definition,
 	// they will be recorded here.
 	CommentLines []string
@@ -326,7 +400,7 @@ type Type struct {
 	// If Kind == Struct
 	Members []Member
 
-	// If Kind == Map, Slice, Pointer, or Chan
+	// If Kind == Map, Slice, Pointer, Array, or Chan
 	Elem *Type
 
 	// If Kind == Map, this is the map's type.
@@ -344,11 +418,66 @@ type Type struct {
 	// If Kind == func, this is the signature of the function.
 	Signature *Signature
 
-	// TODO: Add:
-	// * channel direction
-	// * array length
+	// If this is a generic type, these are its type parameters in
+	// declaration order. (All elements will have Kind == TypeParam)
+	TypeParams []*Type
+
+	// If this is an instantiation of a generic type, e.g. Array[string],
+	// these are its type arguments, in the order of Origin.TypeParams.
+	TypeArgs []*Type
+
+	// If this is an instantiation of a generic type, this is the generic
+	// type it was instantiated from.
+	Origin *Type
+
+	// If Kind == TypeParam, this is the constraint of the type parameter.
+	Constraint *Type
+
+	// If Kind == Array, this is the length of the array.
+	Len int64
+
+	// If Kind == Chan, this is the direction of the channel.
+	ChanDir ChanDir
+
+	// If constants of this named type are declared in its package, e.g.
+	// `const Running Phase = "Running"` for `type Phase string`, these are
+	// the constants in declaration order. See IsEnum.
+	Enum []*EnumValue
 }
 
+// EnumValue is a constant of a named type, see Type.Enum.
+type EnumValue struct {
+	// The name of the constant.
+	Name string
+
+	// The value of the constant as a Go literal, e.g. `"Running"` or `1`.
+	Value string
+
+	// If there are comment lines immediately before the constant, they will
+	// be recorded here. Markers on the value can be extracted from them with
+	// ExtractCommentTags.
+	CommentLines []string
+
+	// The position of the constant.
+	Position token.Position
+
+	// The declaration of the constant in Package.Constants, its Kind is
+	// DeclarationOf.
+	Constant *Type
+}
+
+// ChanDir is the direction of a channel, see Type.ChanDir.
+type ChanDir int
+
+const (
+	// SendRecv is a bidirectional channel, e.g. chan T.
+	SendRecv ChanDir = iota
+	// SendOnly is a send-only channel, e.g. chan<- T.
+	SendOnly
+	// RecvOnly is a receive-only channel, e.g. <-chan T.
+	RecvOnly
+)
+
 // String returns the name of type.
 func (t *Type) String() string {
 	return t.Name.String()
@@ -379,9 +508,33 @@ func (t *Type) IsAssignable() bool {
 		}
 		return true
 	}
+	if t.Kind == Array {
+		return t.Elem.IsAssignable()
+	}
+	if t.Kind == Alias && t.Underlying.Kind == Array {
+		return t.Underlying.IsAssignable()
+	}
 	return false
 }
 
+// IsGeneric returns whether the type declares type parameters, e.g.
+// Array[V Builtin]. Generic types can't be used without instantiation.
+func (t *Type) IsGeneric() bool {
+	return len(t.TypeParams) != 0
+}
+
+// IsInstantiated returns whether the type is an instantiation of a generic
+// type, e.g. Array[string].
+func (t *Type) IsInstantiated() bool {
+	return t.Origin != nil
+}
+
+// IsEnum returns whether constants of the type are declared in its package,
+// i.e. whether Enum holds the values of the type.
+func (t *Type) IsEnum() bool {
+	return len(t.Enum) != 0
+}
+
 // IsAnonymousStruct returns true if the type is an anonymous struct or an alias
 // to an anonymous struct.
 func (t *Type) IsAnonymousStruct() bool {
@@ -406,6 +559,12 @@ type Member struct {
 
 	// The type of this member.
 	Type *Type
+
+	// The position of the member in the type definition.
+	Position token.Position
+
+	// The parsed form of Tags, see CacheStructTags.
+	structTags *StructTags
 }
 
 // String returns the name and type of the member.
@@ -422,6 +581,10 @@ type Signature struct {
 	Parameters []*Type
 	Results    []*Type
 
+	// If a generic function, these are its type parameters.
+	// (All elements will have Kind == TypeParam)
+	TypeParams []*Type
+
 	// True if the last in parameter is of the form ...T.
 	Variadic bool
 
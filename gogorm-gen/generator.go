@@ -17,9 +17,9 @@ package goproto_gen
 import (
 	"bytes"
 	"fmt"
+	"go/token"
 	"io"
 	"path"
-	"reflect"
 	"sort"
 	"strings"
 
@@ -68,7 +68,8 @@ func (g *genGormIDL) Filter(c *generator.Context, t *types.Type) bool {
 			// Type specified "true".
 			return true
 		}
-		log.Fatalf(`Comment tag "gorm" must be true or false, found: %q`, tagVals[0])
+		c.Diagnostics.Errorf(t.Position, `comment tag "gorm" must be true or false, found: %q`, tagVals[0])
+		return false
 	}
 	if !g.generateAll {
 		// We're not generating everything.
@@ -111,7 +112,7 @@ func isGormable(seen map[*types.Type]bool, t *types.Type) bool {
 	case types.Interface:
 		return false
 	default:
-		log.Warnf("WARNING: type %q is not portable: %s", t.Kind, t.Name)
+		log.Warnf("%v", generator.Errorf(t.Position, "type %q is not portable: %s", t.Kind, t.Name))
 		return false
 	}
 }
@@ -150,11 +151,14 @@ func (g *genGormIDL) GenerateType(c *generator.Context, t *types.Type, w io.Writ
 		t: t,
 	}
 
-	if !extractBoolTagOrDie(tagEnable, t.CommentLines) {
+	enabled, err := extractBoolTag(tagEnable, t.CommentLines)
+	if err != nil {
+		return generator.Wrapf(t.Position, err, "type %v", t)
+	}
+	if !enabled {
 		return nil
 	}
 
-	var err error
 	switch t.Kind {
 	case types.Alias:
 		err = b.doAlias(sw)
@@ -248,7 +252,7 @@ type bodyGen struct {
 }
 
 func (b bodyGen) unknown(sw *generator.SnippetWriter) error {
-	return fmt.Errorf("not sure how to generate: %#v", b.t)
+	return generator.Errorf(b.t.Position, "not sure how to generate: %#v", b.t)
 }
 
 func (b bodyGen) doAlias(sw *generator.SnippetWriter) error {
@@ -300,7 +304,7 @@ func (b bodyGen) doStruct(sw *generator.SnippetWriter) error {
 	if fields == nil {
 		memberFields, err := membersToFields(b.locator, alias, b.localPackage, b.omitFieldTypes)
 		if err != nil {
-			return fmt.Errorf("type %v cannot be converted to gorm: %v", b.t, err)
+			return generator.Wrapf(b.t.Position, err, "type %v cannot be converted to gorm", b.t)
 		}
 		fields = memberFields
 	}
@@ -311,7 +315,11 @@ func (b bodyGen) doStruct(sw *generator.SnippetWriter) error {
 	embedded := false
 	sw.Dof(`type XX_$.Name.Name$ struct {`, b.t)
 	for i, field := range fields {
-		if !extractFieldBoolTagOrDie(tagEnable, field.CommentLines) {
+		enabled, err := extractFieldBoolTag(tagEnable, field.CommentLines)
+		if err != nil {
+			return generator.Wrapf(field.Position, err, "field %s of type %v", field.Name, b.t)
+		}
+		if !enabled {
 			continue
 		}
 		if field.embedded {
@@ -374,7 +382,7 @@ func (m *$.Name.Name$) GormDBDataType(db *gorm.DB, field *schema.Field) string {
 	}
 
 	if pkField == nil && !embedded {
-		return fmt.Errorf("type %v missing field for primaryKey", b.t)
+		return generator.Errorf(b.t.Position, "type %v missing field for primaryKey", b.t)
 	}
 
 	for _, field := range fields {
@@ -414,7 +422,7 @@ func (m *$.Name.Name$) GormDBDataType(db *gorm.DB, field *schema.Field) string {
 			sw.Doln("return m")
 			sw.Doln("}")
 		} else if ft.Elem != nil { // slice
-			if ft.Elem.Kind == "Builtin" {
+			if ft.Elem.Kind == types.Builtin || ft.Elem.Kind == types.Pointer {
 				sw.Dof(fmt.Sprintf(`func (m *$.Name.Name$) Set%s(in []%s) *$.Name.Name$ {`, fname, ft.Elem.Name.Name), b.t)
 			} else {
 				sw.Dof(fmt.Sprintf(`func (m *$.Name.Name$) Set%s(in []*%s) *$.Name.Name$ {`, fname, ft.Elem.Name.Name), b.t)
@@ -451,7 +459,7 @@ func (m *$.Name.Name$) GormDBDataType(db *gorm.DB, field *schema.Field) string {
 			"uint", "uint8", "uint16", "uint32", "uint64":
 			sw.Dof(`return "$.GormName$", m.$.Name$, m.$.Name$ == 0`, pkField)
 		default:
-			return fmt.Errorf("type %s invalid type for primaryKey", b.t.Name.Name)
+			return generator.Errorf(pkField.Position, "type %s invalid type for primaryKey", b.t.Name.Name)
 		}
 		sw.Doln("}")
 		sw.Doln("")
@@ -876,25 +884,15 @@ func scanMember(sw *generator.SnippetWriter, m types.Member) {
 		ft = ft.Underlying
 	}
 
-	tags := reflect.StructTag(m.Tags)
+	tags := m.StructTags()
 
-	var gname string
-	if tag := tags.Get("gorm"); len(tag) > 0 {
-		parts := strings.Split(tag, ";")
-		for _, part := range parts {
-			if strings.HasPrefix(part, "column:") {
-				gname = strings.TrimPrefix(part, "column:")
-				break
-			}
-		}
-	}
-
-	if tag := tags.Get("json"); len(tag) > 0 {
-		parts := strings.Split(tag, ",")
-		if len(gname) == 0 && len(parts[0]) != 0 {
-			gname = parts[0]
+	gname := tags.Gorm().Column
+	if _, ok := tags.Lookup("json"); ok {
+		tag := tags.JSON()
+		if len(gname) == 0 {
+			gname = tag.Name
 		}
-		if gname != "-" {
+		if !tag.Ignored {
 			i := 0
 			length := len(gname)
 			buf := &bytes.Buffer{}
@@ -983,6 +981,9 @@ type gormField struct {
 	Extras        map[string]string
 
 	CommentLines []string
+
+	// The position of the Go member the field was generated from.
+	Position token.Position
 }
 
 func (f gormField) toTagString() string {
@@ -1076,7 +1077,7 @@ func memberTypeToGormField(locator GormLocator, field *gormField, t *types.Type,
 			field.Serializer = "json"
 		} else {
 			if err := memberTypeToGormField(locator, field, t.Underlying, m); err != nil {
-				log.Warnf("failed to alias: %s %s: err %v", t.Name, t.Underlying.Name, err)
+				log.Warnf("%v", generator.Wrapf(t.Position, err, "failed to alias: %s %s", t.Name, t.Underlying.Name))
 				return err
 			}
 		}
@@ -1105,37 +1106,18 @@ func memberTypeToGormField(locator GormLocator, field *gormField, t *types.Type,
 }
 
 // gormTagToField extracts information from an existing gorm tag
-func gormTagToField(tag string, field *gormField, m types.Member, t *types.Type, localPackage types.Name) error {
-	if len(tag) == 0 || tag == "-" {
-		return nil
-	}
-
+func gormTagToField(tags *types.StructTags, field *gormField, m types.Member, t *types.Type, localPackage types.Name) error {
 	// https://gorm.io/docs/models.html#Fields-Tags
 	// gorm:"column:name;index;serializer:json"
-	tMap := make(map[string]string)
-	parts := strings.Split(tag, ";")
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		if len(part) == 0 {
-			continue
-		}
-		var k, v string
-		if !strings.Contains(part, ":") {
-			k = part
-		} else {
-			itemParts := strings.SplitN(part, ":", 2)
-			k = itemParts[0]
-			if len(itemParts) > 1 {
-				v = itemParts[1]
-			}
-		}
-		tMap[k] = v
+	tag := tags.Gorm()
+	if len(tag.Settings) == 0 || tag.Ignored {
+		return nil
 	}
 
 	field.Name = m.Name
-	field.GormName = tMap["column"]
-	field.Serializer = tMap["serializer"]
-	if _, ok := tMap[tagPrimaryKey]; ok {
+	field.GormName = tag.Column
+	field.Serializer = tag.Serializer
+	if tag.PrimaryKey {
 		field.primaryKey = true
 	}
 
@@ -1150,21 +1132,25 @@ func membersToFields(locator GormLocator, t *types.Type, localPackage types.Name
 			// skip private fields
 			continue
 		}
-		if !extractFieldBoolTagOrDie(tagEnable, m.CommentLines) {
+		enabled, err := extractFieldBoolTag(tagEnable, m.CommentLines)
+		if err != nil {
+			return nil, generator.Wrapf(m.Position, err, "field %s", m.Name)
+		}
+		if !enabled {
 			continue
 		}
 		if _, ok := omitFieldTypes[types.Name{Name: m.Type.Name.Name, Package: m.Type.Name.Package}]; ok {
 			continue
 		}
 
-		tags := reflect.StructTag(m.Tags)
+		tags := m.StructTags()
 		field := gormField{
 			LocalPackage: localPackage,
 			Extras:       make(map[string]string),
+			Position:     m.Position,
 		}
 
-		gormTag := tags.Get("gorm")
-		if gormTag == "-" {
+		if tags.Gorm().Ignored {
 			continue
 		}
 
@@ -1172,23 +1158,25 @@ func membersToFields(locator GormLocator, t *types.Type, localPackage types.Name
 		if v := markers[tagPrimaryKey]; v != nil {
 			field.primaryKey = true
 		}
-		if v := markers[tagEmbedded]; v != nil {
+		// Embedded members whose fields json inlines are embedded in the
+		// table too.
+		if v := markers[tagEmbedded]; v != nil || (m.Embedded && tags.JSON().Inline) {
 			field.embedded = true
 		}
 
-		if err := gormTagToField(gormTag, &field, m, t, localPackage); err != nil {
+		if err := gormTagToField(tags, &field, m, t, localPackage); err != nil {
 			return nil, err
 		}
 
 		// extract information from JSON field tag
-		if tag := tags.Get("json"); len(tag) > 0 {
-			parts := strings.Split(tag, ",")
-			if len(field.GormName) == 0 && len(parts[0]) != 0 {
-				field.GormName = parts[0]
-			}
-			if field.GormName == "-" {
+		if _, ok := tags.Lookup("json"); ok {
+			tag := tags.JSON()
+			if len(field.GormName) == 0 && tag.Ignored {
 				continue
 			}
+			if len(field.GormName) == 0 {
+				field.GormName = tag.Name
+			}
 			i := 0
 			length := len(field.GormName)
 			buf := &bytes.Buffer{}
@@ -1205,7 +1193,7 @@ func membersToFields(locator GormLocator, t *types.Type, localPackage types.Name
 
 		if field.Type == nil {
 			if err := memberTypeToGormField(locator, &field, m.Type, &m); err != nil {
-				return nil, fmt.Errorf("unable to embed type %q as field %q in %q: %v", m.Type, field.Name, t.Name, err)
+				return nil, generator.Wrapf(m.Position, err, "unable to embed type %q as field %q in %q", m.Type, field.Name, t.Name)
 			}
 		}
 		if len(field.Name) == 0 {
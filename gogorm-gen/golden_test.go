@@ -0,0 +1,43 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goproto_gen
+
+import (
+	"testing"
+
+	"github.com/vine-io/gogogen/gogenerator/generator"
+	gentesting "github.com/vine-io/gogogen/gogenerator/testing"
+)
+
+func TestGolden(t *testing.T) {
+	const pkg = "github.com/vine-io/gogogen/gogorm-gen/testdata/basic/input"
+
+	gentesting.RunDisk(t, gentesting.DiskCase{
+		Dir:     "testdata/basic",
+		Package: pkg,
+		// The generated DAO imports github.com/vine-io/apimachinery.
+		NoTypeCheck: true,
+		Generate: func(out generator.OutputFS) error {
+			g := New()
+			// Keep the golden files free of the year.
+			g.Common.GoHeaderFilePath = ""
+			g.Common.GoModules = true
+			g.OutputBase = ""
+			g.Packages = pkg
+			g.Output = out
+			return g.Execute()
+		},
+	})
+}
@@ -16,7 +16,6 @@ package goproto_gen
 
 import (
 	"fmt"
-	"reflect"
 	"strings"
 
 	"github.com/vine-io/gogogen/gogenerator/generator"
@@ -133,11 +132,11 @@ func assignGoTypeToGormPackage(p *gormPackage, t *types.Type, local, global type
 			continue
 		}
 		field := &gormField{}
-		tag := reflect.StructTag(m.Tags).Get("gorm")
-		if tag == "-" {
+		tags := m.StructTags()
+		if tags.Gorm().Ignored {
 			continue
 		}
-		if err := gormTagToField(tag, field, m, t, p.GormTypeName()); err == nil && field.Type != nil {
+		if err := gormTagToField(tags, field, m, t, p.GormTypeName()); err == nil && field.Type != nil {
 			assignGoTypeToGormPackage(p, field.Type, local, global, optional)
 			continue
 		}
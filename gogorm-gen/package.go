@@ -19,7 +19,6 @@ import (
 	"go/ast"
 	"os"
 	"path/filepath"
-	"reflect"
 	"strings"
 
 	"github.com/vine-io/gogogen/gogenerator/generator"
@@ -84,9 +83,11 @@ type gormPackage struct {
 	Imports *ImportTracker
 }
 
-func (p *gormPackage) Clean(outputBase string) error {
+// Clean removes the generated files of the package from dir, the directory
+// which holds them.
+func (p *gormPackage) Clean(dir string) error {
 	for _, s := range []string{p.OutputPath()} {
-		if err := os.Remove(filepath.Join(outputBase, s)); err != nil && !os.IsNotExist(err) {
+		if err := os.Remove(filepath.Join(dir, filepath.Base(s))); err != nil && !os.IsNotExist(err) {
 			return err
 		}
 	}
@@ -102,6 +103,10 @@ func (p *gormPackage) GormTypeName() types.Name {
 }
 
 func (p *gormPackage) filterFunc(c *generator.Context, t *types.Type) bool {
+	// Generic types and their instantiations are only supported as fields.
+	if t.IsGeneric() || t.IsInstantiated() {
+		return false
+	}
 	switch t.Kind {
 	case types.Func, types.Chan:
 		return false
@@ -110,7 +115,12 @@ func (p *gormPackage) filterFunc(c *generator.Context, t *types.Type) bool {
 			return false
 		}
 		// +gogo:genproto
-		if !extractBoolTagOrDie(tagEnable, t.CommentLines) {
+		enabled, err := extractBoolTag(tagEnable, t.CommentLines)
+		if err != nil {
+			c.Diagnostics.Report(generator.Wrapf(t.Position, err, "type %v", t))
+			return false
+		}
+		if !enabled {
 			return false
 		}
 	case types.Builtin:
@@ -154,7 +164,7 @@ func (p *gormPackage) ExtractGeneratedType(t *ast.TypeSpec) bool {
 				continue
 			}
 			tag := strings.Trim(f.Tag.Value, "`")
-			gormTag := reflect.StructTag(tag).Get("gorm")
+			gormTag := types.ParseStructTags(tag).Get("gorm")
 			if len(gormTag) == 0 {
 				continue
 			}
@@ -182,6 +192,11 @@ func (p *gormPackage) ExtractGeneratedType(t *ast.TypeSpec) bool {
 
 func (p *gormPackage) generatorFunc(c *generator.Context) []generator.Generator {
 	generators := []generator.Generator{}
+	// Packages without gorm types get no file, so that a stale one is
+	// removed.
+	if len(c.Order) == 0 {
+		return generators
+	}
 
 	p.Imports.AddType(&types.Type{
 		Kind: types.Gorm,
@@ -192,6 +207,33 @@ func (p *gormPackage) generatorFunc(c *generator.Context) []generator.Generator
 		},
 	})
 
+	p.Imports.AddType(&types.Type{
+		Kind: types.Gorm,
+		Name: types.Name{
+			Name:    "context",
+			Package: "context",
+			Path:    "context",
+		},
+	})
+
+	p.Imports.AddType(&types.Type{
+		Kind: types.Gorm,
+		Name: types.Name{
+			Name:    "reflect",
+			Package: "reflect",
+			Path:    "reflect",
+		},
+	})
+
+	p.Imports.AddType(&types.Type{
+		Kind: types.Gorm,
+		Name: types.Name{
+			Name:    "driver",
+			Package: "driver",
+			Path:    "database/sql/driver",
+		},
+	})
+
 	p.Imports.AddType(&types.Type{
 		Kind: types.Gorm,
 		Name: types.Name{
@@ -291,11 +333,3 @@ func (p *gormPackage) OutputPath() string {
 var (
 	_ = generator.Package(&gormPackage{})
 )
-
-func tagHeaderTrim(tag string) (string, string) {
-	parts := strings.Split(tag, ":")
-	if len(parts) > 1 {
-		return parts[0], strings.Trim(parts[1], `"`)
-	}
-	return tag, ""
-}
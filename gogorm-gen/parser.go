@@ -25,9 +25,9 @@ import (
 	"go/token"
 	"io/ioutil"
 	"os"
-	"reflect"
 	"strings"
 
+	"github.com/vine-io/gogogen/gogenerator/types"
 	customreflect "github.com/vine-io/gogogen/util/third_party/forked/golang/reflect"
 )
 
@@ -446,7 +446,7 @@ func updateStructTags(decl ast.Decl, structTags map[string]map[string]string, to
 					continue
 				}
 				// append new tags
-				if v := reflect.StructTag(value).Get(name); len(v) > 0 {
+				if v := types.ParseStructTags(value).Get(name); len(v) > 0 {
 					tags = append(tags, customreflect.StructTag{Name: name, Value: v})
 				}
 			}
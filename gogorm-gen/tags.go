@@ -16,25 +16,16 @@ package goproto_gen
 
 import (
 	"github.com/vine-io/gogogen/gogenerator/types"
-	"github.com/vine-io/gogogen/util/log"
 )
 
-// extractBoolTagOrDie gets the comment-tags for the key and asserts that, if
+// extractBoolTag gets the comment-tags for the key and asserts that, if
 // it exists, the value is boolean.  If the tag did not exist, it returns false.
-func extractBoolTagOrDie(key string, lines []string) bool {
-	val, err := types.ExtractSingleBoolCommentTag("+", key, false, lines)
-	if err != nil {
-		log.Fatal(err)
-	}
-	return val
+func extractBoolTag(key string, lines []string) (bool, error) {
+	return types.ExtractSingleBoolCommentTag("+", key, false, lines)
 }
 
-// extractFieldBoolTagOrDie gets the comment-tags for the key and asserts that, if
+// extractFieldBoolTag gets the comment-tags for the key and asserts that, if
 // it exists, the value is boolean.  If the tag did not exist, it returns true.
-func extractFieldBoolTagOrDie(key string, lines []string) bool {
-	val, err := types.ExtractSingleBoolCommentTag("+", key, true, lines)
-	if err != nil {
-		log.Fatal(err)
-	}
-	return val
+func extractFieldBoolTag(key string, lines []string) (bool, error) {
+	return types.ExtractSingleBoolCommentTag("+", key, true, lines)
 }
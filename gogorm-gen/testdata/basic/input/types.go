@@ -0,0 +1,27 @@
+package input
+
+// +gogo:gengorm=true
+// +gogo:gengorm:external=false
+// Address is stored as json in its owner's table.
+type Address struct {
+	City   string `json:"city"`
+	Street string `json:"street,omitempty"`
+}
+
+// +gogo:gengorm=true
+// +gogo:gengorm:external
+// User is stored in its own table.
+type User struct {
+	// +primaryKey
+	ID     string            `json:"id"`
+	Name   string            `json:"name"`
+	Age    int32             `json:"age"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Emails []string          `json:"emails,omitempty"`
+	Home   *Address          `json:"home,omitempty"`
+}
+
+// Hidden isn't generated.
+type Hidden struct {
+	Value string
+}
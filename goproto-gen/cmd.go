@@ -31,9 +31,8 @@ import (
 	"github.com/vine-io/gogogen/gogenerator/namer"
 	"github.com/vine-io/gogogen/gogenerator/parser"
 	"github.com/vine-io/gogogen/gogenerator/types"
+	"github.com/vine-io/gogogen/util/format"
 	"github.com/vine-io/gogogen/util/log"
-
-	utilbuild "github.com/vine-io/gogogen/util/build"
 )
 
 const (
@@ -41,6 +40,24 @@ const (
 	tagEmbedded = "embedded"
 )
 
+// Markers are the comment markers read by goproto-gen.
+var Markers = []*types.Marker{
+	{Name: tagEnable, Scope: types.TypeScope | types.FieldScope, Value: types.BoolMarker, Generator: "goproto-gen",
+		Description: "Generates the protobuf message of a struct, or excludes a field from it when false."},
+	{Name: tagEmbedded, Scope: types.FieldScope, Value: types.FlagMarker, Generator: "goproto-gen",
+		Description: "Inlines the fields of the struct into the enclosing message."},
+	{Name: "protobuf", Scope: types.TypeScope, Value: types.BoolMarker, Generator: "goproto-gen",
+		Description: "Forces a type in or out of the generated IDL."},
+	{Name: "protobuf.nullable", Scope: types.TypeScope, Value: types.BoolMarker, Generator: "goproto-gen",
+		Description: "Makes fields of a map or slice type nullable when true."},
+	{Name: "protobuf.as", Scope: types.TypeScope, Value: types.StringMarker, Generator: "goproto-gen",
+		Description: "Gives the message the contents of the named Go type."},
+	{Name: "protobuf.embed", Scope: types.TypeScope, Value: types.StringMarker, Generator: "goproto-gen",
+		Description: "Makes the message a single field of the named type."},
+	{Name: "protobuf.options.*", Scope: types.TypeScope, Value: types.StringMarker, Generator: "goproto-gen",
+		Description: "Sets a message option, e.g. +protobuf.options.(gogoproto.goproto_stringer)=false."},
+}
+
 type Generator struct {
 	Common               args.GeneratorArgs
 	GeneratedName        string
@@ -55,23 +72,42 @@ type Generator struct {
 	KeepGogoproto        bool
 	SkipGeneratedRewrite bool
 	DropEmbeddedFields   string
+
+	// If not empty, only the packages of Packages and MetadataPackages with
+	// these import paths are generated, the others are only read.
+	Only []string
+
+	// If set, the generated files, and the sources whose struct tags are
+	// rewritten, are written to this output instead of the one picked by
+	// Common. It is left open.
+	Output generator.OutputFS
+
+	// While executing, the shadow copy the output packages are generated
+	// in, see shadowPackages.
+	shadow *generator.Shadow
 }
 
 func New() *Generator {
 	sourceTree := args.DefaultSourceTree()
+	goModules := args.DefaultGoModules()
+	outputBase := sourceTree
+	if goModules {
+		// Write the generated files next to their packages.
+		outputBase = ""
+	}
 	common := args.GeneratorArgs{
-		OutputBase:       sourceTree,
-		GoHeaderFilePath: filepath.Join(sourceTree, utilbuild.BoilerplatePath()),
+		GoModules:        goModules,
+		OutputBase:       outputBase,
+		GoHeaderFilePath: args.DefaultGoHeaderFile(),
+		Markers:          types.NewMarkerRegistry(Markers...),
 	}
 	//defaultProtoImport := filepath.Join(sourceTree, "github.com", "gogo", "protobuf", "gogoproto")
-	cwd, err := os.Getwd()
-	if err != nil {
-		log.Fatalf("Cannot get current directory.")
-	}
+	// The vendor directory is relative to the working directory anyway.
+	cwd, _ := os.Getwd()
 	return &Generator{
 		Common:             common,
 		GeneratedName:      "generated",
-		OutputBase:         sourceTree,
+		OutputBase:         outputBase,
 		VendorOutputBase:   filepath.Join(cwd, "vendor"),
 		ProtoImport:        []string{},
 		MetadataPackages:   strings.Join([]string{}, ","),
@@ -86,12 +122,26 @@ func (g *Generator) BindFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&g.GeneratedName, "generated-name", g.GeneratedName, "The Name of the generated file")
 	fs.BoolVar(&g.Common.VerifyOnly, "verify-only", g.Common.VerifyOnly,
 		"If true, only verify existing output, do not write anything.")
+	fs.BoolVar(&g.Common.DryRun, "dry-run", g.Common.DryRun,
+		"If true, list the files which would be generated or changed instead of writing them.")
+	fs.StringVar(&g.Common.OutputArchive, "output-archive", g.Common.OutputArchive,
+		"If set, write the files which would be generated or changed to this .tar, .tar.gz, .tgz or .zip archive instead of the source tree; - writes a tar to stdout.")
 	fs.StringVarP(&g.Packages, "packages", "p", g.Packages,
 		"comma-separated list of directories to get input types from. Directories prefixed with '-' are not generated, directories prefixed with '+' only create types with explicit IDL instructions.")
 	fs.StringVar(&g.MetadataPackages, "metadata-packages", g.MetadataPackages,
 		"comma-separated list of directories to get metadata input types from which are needed by any API. Directories prefixed with '-' are not generated, directories prefixed with '+' only create types with explicit IDL instructions.")
+	fs.BoolVar(&g.Common.GoGenerate, "go-generate", g.Common.GoGenerate,
+		"If true, process the package go generate runs the command for, given by $GOPACKAGE and the working directory, and write the output next to it. Relative packages are relative to it.")
+	fs.BoolVar(&g.Common.Watch, "watch", g.Common.Watch,
+		"If true, keep running and generate again the packages whose files change, instead of exiting.")
+	fs.DurationVar(&g.Common.WatchInterval, "watch-interval", args.DefaultWatchInterval,
+		"How often the files of the packages are polled for changes in watch mode.")
+	fs.BoolVar(&g.Common.GoModules, "go-modules", g.Common.GoModules,
+		"If true, resolve packages in module mode; defaults to true inside a Go module or workspace.")
+	fs.StringVar(&g.Common.CacheDir, "cache-dir", g.Common.CacheDir,
+		"If set, cache type-checked packages in this directory to speed up later runs.")
 	fs.StringVarP(&g.OutputBase, "output-base", "o", g.OutputBase,
-		"Output base; defaults to $GOPATH/src/")
+		"Output base; if empty, output is written next to the source packages. Defaults to empty in module mode, $GOPATH/src/ otherwise.")
 	fs.StringVar(&g.VendorOutputBase, "vendor-output-base", g.VendorOutputBase,
 		"The vendor/ directory to look for packages in; defaults to $PWD/vendor/.")
 	fs.StringSliceVar(&g.ProtoImport, "proto-import", g.ProtoImport,
@@ -99,7 +149,9 @@ func (g *Generator) BindFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&g.Conditional, "conditional", g.Conditional,
 		"An optional Golang build tag condition to add to the generated Go code")
 	fs.BoolVar(&g.Clean, "clean", g.Clean,
-		"If true, remove all generated files for the specified Packages.")
+		"If true, remove all generated files for the specified Packages; only those recorded in the manifest if --manifest is set.")
+	fs.StringVar(&g.Common.Manifest, "manifest", g.Common.Manifest,
+		"If set, record the generated files in this manifest, with the hashes of the files and the options they are generated from, for the check subcommand and --clean.")
 	fs.BoolVar(&g.OnlyIDL, "only-idl", g.OnlyIDL,
 		"If true, only generate the IDL for each package.")
 	fs.BoolVar(&g.KeepGogoproto, "keep-gogoproto", g.KeepGogoproto,
@@ -108,15 +160,173 @@ func (g *Generator) BindFlags(fs *pflag.FlagSet) {
 		"If true, skip fixing up the generated.pb.go file (debugging only).")
 	fs.StringVar(&g.DropEmbeddedFields, "drop-embedded-fields", g.DropEmbeddedFields,
 		"Comma-delimited list of embedded Go types to omit from generated protobufs")
+	args.MarkPathFlags(fs, "go-header-file", "output-archive", "cache-dir", "output-base", "vendor-output-base", "manifest", "proto-import")
+}
+
+// ApplyGoGenerate makes the generator process the package which "go
+// generate" runs it for if Common.GoGenerate is set: Packages defaults to
+// it, relative packages are resolved against it, and the files are written
+// next to the sources unless an output base is given. The options given on
+// the command line, in the environment or in the options file are told by
+// opts.
+func (g *Generator) ApplyGoGenerate(opts *args.Options) error {
+	if !g.Common.GoGenerate {
+		return nil
+	}
+	gg, err := args.LoadGoGenerate(g.Common.GoModules)
+	if err != nil {
+		return err
+	}
+	given := func(name string) bool {
+		return opts != nil && opts.Sources[name] != ""
+	}
+
+	if given("packages") {
+		g.Packages = resolvePackages(gg, g.Packages)
+	} else {
+		g.Packages = gg.Package
+	}
+	g.MetadataPackages = resolvePackages(gg, g.MetadataPackages)
+	if !given("output-base") {
+		g.OutputBase = ""
+	}
+	log.Infof("Generating for package %s (go:generate in %s)", gg.Package, gg.File)
+	return nil
+}
+
+// resolvePackages resolves the relative packages of a comma-separated list
+// of packages, prefixed or not with '-' or '+', against the package of gg.
+func resolvePackages(gg *args.GoGenerate, list string) string {
+	if list == "" {
+		return list
+	}
+	pkgs := strings.Split(list, ",")
+	for i, pkg := range pkgs {
+		prefix := ""
+		if strings.HasPrefix(pkg, "-") || strings.HasPrefix(pkg, "+") {
+			prefix, pkg = pkg[:1], pkg[1:]
+		}
+		pkgs[i] = prefix + gg.Resolve(pkg)
+	}
+	return strings.Join(pkgs, ",")
+}
+
+// Run runs the generator. In verify mode, the error is a *generator.VerifyError if
+// generated files are out of date. In watch mode, it logs the errors and
+// keeps running, see Watch.
+func Run(g *Generator) error {
+	err := g.Execute()
+	if g.Common.Watch {
+		if err != nil {
+			log.Errorf("Error: %v", err)
+		}
+		err = g.Watch()
+	}
+	return err
+}
+
+// Watch runs the generator again each time the files of the packages it
+// generates change, see args.Watcher. It only returns if they can't be
+// found. Since the generator rewrites the sources of its packages, they are
+// all parsed again, but only those which changed are generated.
+func (g *Generator) Watch() error {
+	dirs, err := g.Common.PackageDirs(g.GeneratedPackages())
+	if err != nil {
+		return err
+	}
+	g.Common.NewWatcher(dirs).Run(g.Common.WatchInterval, func(changed []string) error {
+		defer func() { g.Only = nil }()
+		g.Only = changed
+		return g.Execute()
+	})
+	return nil
+}
+
+// GeneratedPackages returns the import paths of the packages of Packages and
+// MetadataPackages which are generated.
+func (g *Generator) GeneratedPackages() []string {
+	var pkgPaths []string
+	for _, list := range []string{g.MetadataPackages, g.Packages} {
+		for _, d := range strings.Split(list, ",") {
+			if pkgPath, generated := packageEntry(d); pkgPath != "" && generated {
+				pkgPaths = append(pkgPaths, pkgPath)
+			}
+		}
+	}
+	return pkgPaths
+}
+
+// onlyPackages returns the comma-separated list of packages with the
+// generated ones which aren't in pkgPaths prefixed with '-', so that they are
+// only read. All of them are generated if pkgPaths is empty.
+func onlyPackages(list string, pkgPaths []string) string {
+	if list == "" || len(pkgPaths) == 0 {
+		return list
+	}
+	entries := strings.Split(list, ",")
+	for i, d := range entries {
+		pkgPath, generated := packageEntry(d)
+		if !generated {
+			continue
+		}
+		found := false
+		for _, p := range pkgPaths {
+			found = found || p == pkgPath
+		}
+		if !found {
+			entries[i] = "-" + strings.TrimPrefix(d, "+")
+		}
+	}
+	return strings.Join(entries, ",")
 }
 
-func Run(g *Generator) {
+// packageEntry returns the import path of an entry of a list of packages,
+// and whether the package is generated.
+func packageEntry(d string) (string, bool) {
+	generated := !strings.HasPrefix(d, "-")
+	d = strings.TrimPrefix(strings.TrimPrefix(d, "-"), "+")
+	if i := strings.Index(d, "="); i >= 0 {
+		d = d[:i]
+	}
+	return d, generated
+}
+
+// Execute runs the generator. In verify mode, it returns a
+// *generator.VerifyError if generated files are out of date.
+func (g *Generator) Execute() (err error) {
 	if g.Common.VerifyOnly {
-		g.OnlyIDL = true
 		g.Clean = false
 	}
 
+	output := g.Output
+	if !g.Common.VerifyOnly && output == nil {
+		// Archive entries are named relative to our output base.
+		common := g.Common
+		common.OutputBase = g.OutputBase
+		if output, err = common.NewOutputFS(); err != nil {
+			return fmt.Errorf("unable to open the output: %v", err)
+		}
+		if output == nil {
+			output = generator.DiskFS{}
+		}
+		defer func() {
+			if cerr := args.CloseOutputFS(output); cerr != nil && err == nil {
+				err = fmt.Errorf("unable to write the output: %v", cerr)
+			}
+		}()
+	}
+
+	if g.Clean && g.Common.Manifest != "" {
+		// The manifest tells exactly which files were generated.
+		return g.Common.CleanManifest(g.GeneratedPackages(), output)
+	}
+
 	b := parser.New()
+	if g.Common.GoModules {
+		b = parser.NewWithModules("")
+	}
+	b.CacheDir = g.Common.CacheDir
+	b.Markers = g.Common.Markers
 	b.AddBuildTags("proto")
 
 	omitTypes := map[types.Name]struct{}{}
@@ -135,7 +345,7 @@ func Run(g *Generator) {
 
 	boilerplate, err := g.Common.LoadGoBoilerplate()
 	if err != nil {
-		log.Fatalf("Failed loading boilerplate (consider using the go-header-file flag): %v", err)
+		return fmt.Errorf("failed loading boilerplate (consider using the go-header-file flag): %v", err)
 	}
 
 	protobufNames := NewProtobufNamer()
@@ -144,13 +354,13 @@ func Run(g *Generator) {
 
 	var packages []string
 	if len(g.MetadataPackages) != 0 {
-		packages = append(packages, strings.Split(g.MetadataPackages, ",")...)
+		packages = append(packages, strings.Split(onlyPackages(g.MetadataPackages, g.Only), ",")...)
 	}
 	if len(g.Packages) != 0 {
-		packages = append(packages, strings.Split(g.Packages, ",")...)
+		packages = append(packages, strings.Split(onlyPackages(g.Packages, g.Only), ",")...)
 	}
 	if len(packages) == 0 {
-		log.Fatalf("Both metadata-packages and packages are empty. At least one package must be specified.")
+		return fmt.Errorf("both metadata-packages and packages are empty, at least one package must be specified")
 	}
 
 	for _, d := range packages {
@@ -181,21 +391,64 @@ func Run(g *Generator) {
 		}
 	}
 
-	if !g.Common.VerifyOnly {
-		for _, p := range outputPackages {
-			if err := p.(*protobufPackage).Clean(g.OutputBase); err != nil {
-				log.Fatalf("Unable to clean package %s: %v", p.Name(), err)
+	// Generation runs in a shadow copy of the output packages, whose changes
+	// are then verified or exported, so that unchanged files are left
+	// untouched and dropped ones removed.
+	if err := g.shadowPackages(b, outputPackages); err != nil {
+		return fmt.Errorf("unable to copy the packages to generate: %v", err)
+	}
+	defer func() {
+		// A panic leaves err unset, and the half generated copy mustn't be
+		// exported then.
+		if r := recover(); r != nil {
+			g.shadow.Remove()
+			g.shadow = nil
+			panic(r)
+		}
+		switch {
+		case err != nil:
+		case g.Common.VerifyOnly:
+			err = g.shadow.Verify()
+		default:
+			if err = g.shadow.Export(output); err == nil {
+				err = g.recordManifest()
 			}
 		}
+		g.shadow.Remove()
+		g.shadow = nil
+	}()
+
+	for _, p := range outputPackages {
+		dir, err := g.packageDir(b, p.(*protobufPackage))
+		if err != nil {
+			return fmt.Errorf("unable to find package %s: %v", p.Name(), err)
+		}
+		if err := p.(*protobufPackage).Clean(dir); err != nil {
+			return fmt.Errorf("unable to clean package %s: %v", p.Name(), err)
+		}
 	}
 
 	if g.Clean {
-		return
+		return nil
 	}
 
+	// The output packages are parsed from their cleaned shadow copy, so that
+	// the generated files they still hold on disk are left out.
+	shadowed := map[string][]byte{}
 	for _, p := range protobufNames.List() {
+		if g.shadow.Has(p.Path()) {
+			if err := g.shadow.ReadGoFiles(p.Path(), shadowed); err != nil {
+				return fmt.Errorf("unable to read the copy of package %q: %v", p.Path(), err)
+			}
+			continue
+		}
 		if err := b.AddDir(p.Path()); err != nil {
-			log.Fatalf("Unable to add directory %q: %v", p.Path(), err)
+			return fmt.Errorf("unable to add directory %q: %v", p.Path(), err)
+		}
+	}
+	if len(shadowed) != 0 {
+		if err := b.AddOverlay(g.shadow.Root, shadowed); err != nil {
+			return fmt.Errorf("unable to add the copies of the output packages: %v", err)
 		}
 	}
 
@@ -208,17 +461,16 @@ func Run(g *Generator) {
 		"public",
 	)
 	if err != nil {
-		log.Fatalf("Failed making a context: %v", err)
+		return fmt.Errorf("failed making a context: %v", err)
 	}
 
-	c.Verify = g.Common.VerifyOnly
 	c.FileTypes["protoidl"] = NewProtoFile()
 
 	// order package by imports, importees first
 	deps := deps(c, protobufNames.packages)
 	order, err := importOrder(deps)
 	if err != nil {
-		log.Fatalf("Failed to order packages by imports: %v", err)
+		return fmt.Errorf("failed to order packages by imports: %v", err)
 	}
 	topologicalPos := map[string]int{}
 	for i, p := range order {
@@ -232,7 +484,9 @@ func Run(g *Generator) {
 			// if we're not outputting the package, don't include it in either package list
 			continue
 		}
-		p.Vendored = strings.Contains(c.Universe[p.PackagePath].SourcePath, "/vendor/")
+		// In module mode vendored packages are found next to their sources
+		// like any other package.
+		p.Vendored = g.OutputBase != "" && strings.Contains(c.Universe[p.PackagePath].SourcePath, "/vendor/")
 		if p.Vendored {
 			vendoredOutputPackages = append(vendoredOutputPackages, p)
 		} else {
@@ -241,25 +495,39 @@ func Run(g *Generator) {
 	}
 
 	if err := protobufNames.AssignTypesToPackages(c); err != nil {
-		log.Fatalf("Failed to identify Common types: %v", err)
+		return fmt.Errorf("failed to identify Common types: %v", err)
 	}
 
-	if err := c.ExecutePackages(g.VendorOutputBase, vendoredOutputPackages); err != nil {
-		log.Fatalf("Failed executing vendor generator: %v", err)
+	if err := c.ExecutePackages(g.outputBase(g.VendorOutputBase), vendoredOutputPackages); err != nil {
+		return fmt.Errorf("failed executing vendor generator: %v", err)
 	}
-	if err := c.ExecutePackages(g.OutputBase, localOutputPackages); err != nil {
-		log.Fatalf("Failed executing local generator: %v", err)
+	if err := c.ExecutePackages(g.outputBase(g.OutputBase), localOutputPackages); err != nil {
+		return fmt.Errorf("failed executing local generator: %v", err)
 	}
 
 	if g.OnlyIDL {
-		return
+		return nil
 	}
 
 	if _, err := exec.LookPath("protoc"); err != nil {
-		log.Fatalf("Unable to find 'protoc': %v", err)
+		return fmt.Errorf("unable to find 'protoc': %v", err)
 	}
 
-	searchArgs := []string{"-I", ".", "-I", g.OutputBase}
+	// protoc resolves the imports between the IDLs by their import paths, so
+	// they are staged from the shadow copy in a tree laid out like GOPATH.
+	protoBase := g.OutputBase
+	if protoBase == "" || g.shadow != nil {
+		protoBase, err = os.MkdirTemp("", "goproto-gen")
+		if err != nil {
+			return fmt.Errorf("unable to create staging directory: %v", err)
+		}
+		defer os.RemoveAll(protoBase)
+		if err := g.stageProtos(b, protoBase, protobufNames.packages); err != nil {
+			return fmt.Errorf("unable to stage IDL: %v", err)
+		}
+	}
+
+	searchArgs := []string{"-I", ".", "-I", protoBase}
 	if stat, _ := os.Stat("vendor"); stat != nil {
 		searchArgs = append(searchArgs, "-I", "vendor")
 	}
@@ -268,7 +536,7 @@ func Run(g *Generator) {
 			searchArgs = append(searchArgs, "-I", s)
 		}
 	}
-	args := append(searchArgs, fmt.Sprintf("--gogo_out=%s", g.OutputBase))
+	args := append(searchArgs, fmt.Sprintf("--gogo_out=%s", protoBase))
 
 	buf := &bytes.Buffer{}
 	if len(g.Conditional) > 0 {
@@ -279,15 +547,15 @@ func Run(g *Generator) {
 	for _, outputPackage := range outputPackages {
 		p := outputPackage.(*protobufPackage)
 
-		path := filepath.Join(g.OutputBase, p.ImportPath())
-		outputPath := filepath.Join(g.OutputBase, p.OutputPath())
-		if p.Vendored {
+		path := filepath.Join(protoBase, p.ImportPath())
+		outputPath := filepath.Join(protoBase, p.OutputPath())
+		if p.Vendored && g.shadow == nil {
 			path = filepath.Join(g.VendorOutputBase, p.ImportPath())
 			outputPath = filepath.Join(g.VendorOutputBase, p.OutputPath())
 		}
 
 		// generate the gogoprotobuf protoc
-		fmt.Println("protoc ", strings.Join(args, " "), path)
+		log.Infof("protoc %s %s", strings.Join(args, " "), path)
 		cmd := exec.Command("protoc", append(args, path)...)
 		out, err := cmd.CombinedOutput()
 		if len(out) > 0 {
@@ -295,7 +563,19 @@ func Run(g *Generator) {
 		}
 		if err != nil {
 			log.Info(strings.Join(cmd.Args, " "))
-			log.Fatalf("Unable to generate protoc on %s: %v", p.PackageName, err)
+			return fmt.Errorf("unable to generate protoc on %s: %v", p.PackageName, err)
+		}
+
+		if protoBase != g.OutputBase {
+			dir, err := g.packageDir(b, p)
+			if err != nil {
+				return fmt.Errorf("unable to find package %s: %v", p.PackageName, err)
+			}
+			staged := outputPath
+			outputPath = filepath.Join(dir, filepath.Base(p.OutputPath()))
+			if err := copyFile(staged, outputPath); err != nil {
+				return fmt.Errorf("unable to copy generated %s: %v", outputPath, err)
+			}
 		}
 
 		if g.SkipGeneratedRewrite {
@@ -304,35 +584,19 @@ func Run(g *Generator) {
 
 		// alter the generated protobuf file to remove the generated types (but leave the serializers) and rewrite the
 		// package statement to match the desired package name
-		if err := RewriteGeneratedGogoProtobufFile(outputPath, p.ExtractGeneratedType, p.OptionalTypeName, buf.Bytes()); err != nil {
-			log.Fatalf("Unable to rewrite generated %s: %v", outputPath, err)
-		}
-
-		// sort imports
-		cmd = exec.Command("goimports", "-w", outputPath)
-		out, err = cmd.CombinedOutput()
-		if len(out) > 0 {
-			log.Info(string(out))
-		}
-		if err != nil {
-			log.Info(strings.Join(cmd.Args, " "))
-			log.Fatalf("Unable to rewrite imports for %s: %v", p.PackageName, err)
+		if err := RewriteGeneratedGogoProtobufFile(outputPath, p.ExtractGeneratedType, p.OptionalTypeName, p.ArrayField, buf.Bytes()); err != nil {
+			return fmt.Errorf("unable to rewrite generated %s: %v", outputPath, err)
 		}
 
-		// format and simplify the generated file
-		cmd = exec.Command("gofmt", "-s", "-w", outputPath)
-		out, err = cmd.CombinedOutput()
-		if len(out) > 0 {
-			log.Info(string(out))
-		}
-		if err != nil {
-			log.Info(strings.Join(cmd.Args, " "))
-			log.Fatalf("Unable to apply gofmt for %s: %v", p.PackageName, err)
+		// remove the imports of the dropped types, sort the others and
+		// simplify the generated file, like goimports and gofmt -s
+		if err := format.File(outputPath); err != nil {
+			return fmt.Errorf("unable to format generated %s: %v", outputPath, err)
 		}
 	}
 
 	if g.SkipGeneratedRewrite {
-		return
+		return nil
 	}
 
 	if !g.KeepGogoproto {
@@ -341,11 +605,11 @@ func Run(g *Generator) {
 			p := outputPackage.(*protobufPackage)
 			p.OmitGogo = true
 		}
-		if err := c.ExecutePackages(g.VendorOutputBase, vendoredOutputPackages); err != nil {
-			log.Fatalf("Failed executing vendor generator: %v", err)
+		if err := c.ExecutePackages(g.outputBase(g.VendorOutputBase), vendoredOutputPackages); err != nil {
+			return fmt.Errorf("failed executing vendor generator: %v", err)
 		}
-		if err := c.ExecutePackages(g.OutputBase, localOutputPackages); err != nil {
-			log.Fatalf("Failed executing local generator: %v", err)
+		if err := c.ExecutePackages(g.outputBase(g.OutputBase), localOutputPackages); err != nil {
+			return fmt.Errorf("failed executing local generator: %v", err)
 		}
 	}
 
@@ -356,13 +620,14 @@ func Run(g *Generator) {
 			continue
 		}
 
-		pattern := filepath.Join(g.OutputBase, p.PackagePath, "*.go")
-		if p.Vendored {
-			pattern = filepath.Join(g.VendorOutputBase, p.PackagePath, "*.go")
+		dir, err := g.packageDir(b, p)
+		if err != nil {
+			return fmt.Errorf("unable to find package %s: %v", p.PackageName, err)
 		}
+		pattern := filepath.Join(dir, "*.go")
 		files, err := filepath.Glob(pattern)
 		if err != nil {
-			log.Fatalf("Can't glob pattern %q: %v", pattern, err)
+			return fmt.Errorf("can't glob pattern %q: %v", pattern, err)
 		}
 
 		for _, s := range files {
@@ -370,10 +635,67 @@ func Run(g *Generator) {
 				continue
 			}
 			if err := RewriteTypesWithProtobufStructTags(s, p.StructTags); err != nil {
-				log.Fatalf("Unable to rewrite with struct tags %s: %v", s, err)
+				return fmt.Errorf("unable to rewrite with struct tags %s: %v", s, err)
 			}
 		}
 	}
+	return nil
+}
+
+// packageDir returns the directory which holds the generated files of the
+// package: its shadow copy while there is one, and its directory in the
+// source tree otherwise.
+func (g *Generator) packageDir(b *parser.Builder, p *protobufPackage) (string, error) {
+	switch {
+	case g.shadow != nil:
+		return g.shadow.Dir(p.PackagePath), nil
+	case g.OutputBase == "":
+		return b.PackageDir(p.PackagePath)
+	case p.Vendored:
+		return filepath.Join(g.VendorOutputBase, p.PackagePath), nil
+	default:
+		return filepath.Join(g.OutputBase, p.PackagePath), nil
+	}
+}
+
+// outputBase returns the directory ExecutePackages writes to instead of base
+// while the shadow copy is in use.
+func (g *Generator) outputBase(base string) string {
+	if g.shadow != nil {
+		return g.shadow.Root
+	}
+	return base
+}
+
+// stageProtos copies the IDL of every package into base, at the import path
+// protoc expects to find it.
+func (g *Generator) stageProtos(b *parser.Builder, base string, pkgs []*protobufPackage) error {
+	for _, p := range pkgs {
+		dir, err := g.packageDir(b, p)
+		if err != nil {
+			return err
+		}
+		src := filepath.Join(dir, filepath.Base(p.ImportPath()))
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			continue
+		}
+		dst := filepath.Join(base, p.ImportPath())
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		if err := copyFile(src, dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0644)
 }
 
 func deps(c *generator.Context, pkgs []*protobufPackage) map[string][]string {
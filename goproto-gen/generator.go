@@ -17,9 +17,9 @@ package goproto_gen
 import (
 	"bytes"
 	"fmt"
+	"go/token"
 	"io"
 	"path"
-	"reflect"
 	"sort"
 	"strconv"
 	"strings"
@@ -83,7 +83,8 @@ func (g *genProtoIDL) Filter(c *generator.Context, t *types.Type) bool {
 			// Type specified "true".
 			return true
 		}
-		log.Fatalf(`Comment tag "protobuf" must be true or false, found: %q`, tagVals[0])
+		c.Diagnostics.Errorf(t.Position, `comment tag "protobuf" must be true or false, found: %q`, tagVals[0])
+		return false
 	}
 	if !g.generateAll {
 		// We're not generating everything.
@@ -107,6 +108,8 @@ func isProtoable(seen map[*types.Type]bool, t *types.Type) bool {
 		return isProtoable(seen, t.Underlying)
 	case types.Slice, types.Pointer:
 		return isProtoable(seen, t.Elem)
+	case types.Array:
+		return isByteArray(t)
 	case types.Map:
 		return isProtoable(seen, t.Key) && isProtoable(seen, t.Elem)
 	case types.Struct:
@@ -126,22 +129,31 @@ func isProtoable(seen map[*types.Type]bool, t *types.Type) bool {
 	case types.Interface:
 		return false
 	default:
-		log.Warnf("WARNING: type %q is not portable: %s", t.Kind, t.Name)
+		log.Warnf("%v", generator.Errorf(t.Position, "type %q is not portable: %s", t.Kind, t.Name))
 		return false
 	}
 }
 
 // isOptionalAlias should return true if the specified type has an underlying type
 // (is an alias) of a map or slice and has the comment tag protobuf.nullable=true,
-// indicating that the type should be nullable in protobuf.
+// indicating that the type should be nullable in protobuf. An invalid tag is
+// reported by the filter of the package, see optionalAlias.
 func isOptionalAlias(t *types.Type) bool {
+	ok, _ := optionalAlias(t)
+	return ok
+}
+
+// optionalAlias is isOptionalAlias, failing if the protobuf.nullable tag of t
+// is not boolean.
+func optionalAlias(t *types.Type) (bool, error) {
 	if t.Underlying == nil || (t.Underlying.Kind != types.Map && t.Underlying.Kind != types.Slice) {
-		return false
+		return false, nil
 	}
-	if extractBoolTagOrDie("protobuf.nullable", t.CommentLines) == false {
-		return false
+	nullable, err := extractBoolTag("protobuf.nullable", t.CommentLines)
+	if err != nil {
+		return false, generator.Wrapf(t.Position, err, "type %v", t)
 	}
-	return true
+	return nullable, nil
 }
 
 func (g *genProtoIDL) Imports(c *generator.Context) (imports map[string]string) {
@@ -257,7 +269,7 @@ type bodyGen struct {
 }
 
 func (b bodyGen) unknown(sw *generator.SnippetWriter) error {
-	return fmt.Errorf("not sure how to generate: %#v", b.t)
+	return generator.Errorf(b.t.Position, "not sure how to generate: %#v", b.t)
 }
 
 func (b bodyGen) doAlias(sw *generator.SnippetWriter) error {
@@ -330,7 +342,7 @@ func (b bodyGen) doStruct(sw *generator.SnippetWriter) error {
 		case k == "protobuf.as":
 			fields = nil
 			if alias = b.locator.GoTypeForName(types.Name{Name: v[0]}); alias == nil {
-				return fmt.Errorf("type %v references alias %q which does not exist", b.t, v[0])
+				return generator.Errorf(b.t.Position, "type %v references alias %q which does not exist", b.t, v[0])
 			}
 		// protobuf.embed instructs the generator to use the named type in this package
 		// as an embedded message.
@@ -358,7 +370,7 @@ func (b bodyGen) doStruct(sw *generator.SnippetWriter) error {
 	if fields == nil {
 		memberFields, err := membersToFields(b.locator, alias, b.localPackage, b.omitFieldTypes)
 		if err != nil {
-			return fmt.Errorf("type %v cannot be converted to protobuf: %v", b.t, err)
+			return generator.Wrapf(b.t.Position, err, "type %v cannot be converted to protobuf", b.t)
 		}
 		fields = memberFields
 	}
@@ -377,7 +389,11 @@ func (b bodyGen) doStruct(sw *generator.SnippetWriter) error {
 	}
 
 	for i, field := range fields {
-		if !extractFieldBoolTagOrDie(tagEnable, field.CommentLines) {
+		enabled, err := extractFieldBoolTag(tagEnable, field.CommentLines)
+		if err != nil {
+			return generator.Wrapf(field.Position, err, "field %s of type %v", field.Name, b.t)
+		}
+		if !enabled {
 			continue
 		}
 		genComment(out, field.CommentLines, "  ")
@@ -437,6 +453,9 @@ type protoField struct {
 	Extras   map[string]string
 
 	CommentLines []string
+
+	// The position of the Go member the field was generated from.
+	Position token.Position
 }
 
 var (
@@ -509,6 +528,8 @@ func memberTypeToProtobufField(locator ProtobufLocator, field *protoField, t *ty
 		if v, ok := valueField.Extras["(gogoproto.casttype)"]; ok {
 			field.Extras["(gogoproto.castvalue)"] = v
 		}
+		// A map of pointers keeps its values nullable.
+		field.Nullable = valueField.Nullable
 		field.Map = true
 	case types.Pointer:
 		if err := memberTypeToProtobufField(locator, field, t.Elem); err != nil {
@@ -521,11 +542,13 @@ func memberTypeToProtobufField(locator ProtobufLocator, field *protoField, t *ty
 			field.Nullable = true
 		} else {
 			if err := memberTypeToProtobufField(locator, field, t.Underlying); err != nil {
-				log.Warnf("failed to alias: %s %s: err %v", t.Name, t.Underlying.Name, err)
+				log.Warnf("%v", generator.Wrapf(t.Position, err, "failed to alias: %s %s", t.Name, t.Underlying.Name))
 				return err
 			}
-			// If this is not an alias to a slice, cast to the alias
-			if !field.Repeated {
+			// If this is not an alias to a slice, cast to the alias. Instantiated
+			// generic types can't be named by gogoproto.casttype, the field keeps
+			// the type of the underlying map instead.
+			if !field.Repeated && !t.IsInstantiated() {
 				if field.Extras == nil {
 					field.Extras = make(map[string]string)
 				}
@@ -541,6 +564,14 @@ func memberTypeToProtobufField(locator ProtobufLocator, field *protoField, t *ty
 			return err
 		}
 		field.Repeated = true
+	case types.Array:
+		// Fixed size byte arrays (hashes, UUIDs) are sent as bytes, other
+		// arrays have no protobuf counterpart.
+		if isByteArray(t) {
+			field.Type = &types.Type{Name: types.Name{Name: "bytes"}, Kind: types.Protobuf}
+			return nil
+		}
+		return errUnrecognizedType
 	case types.Struct:
 		if len(t.Name.Name) == 0 {
 			return errUnrecognizedType
@@ -554,21 +585,16 @@ func memberTypeToProtobufField(locator ProtobufLocator, field *protoField, t *ty
 }
 
 // protobufTagToField extracts information from an existing protobuf tag
-func protobufTagToField(tag string, field *protoField, m types.Member, t *types.Type, localPackage types.Name) error {
-	if len(tag) == 0 || tag == "-" {
-		return nil
-	}
-
+func protobufTagToField(tags *types.StructTags, field *protoField, m types.Member, t *types.Type, localPackage types.Name) error {
 	// protobuf:"bytes,3,opt,name=Id,customtype=github.com/gogo/protobuf/test.Uuid"
-	parts := strings.Split(tag, ",")
-	if len(parts) < 3 {
-		return fmt.Errorf("member %q of %q malformed 'protobuf' tag, not enough segments\n", m.Name, t.Name)
-	}
-	protoTag, err := strconv.Atoi(parts[1])
+	tag, err := tags.Protobuf()
 	if err != nil {
-		return fmt.Errorf("member %q of %q malformed 'protobuf' tag, field ID is %q which is not an integer: %v\n", m.Name, t.Name, parts[1], err)
+		return generator.Errorf(m.Position, "member %q of %q %v", m.Name, t.Name, err)
+	}
+	if tag.Wire == "" {
+		return nil
 	}
-	field.Tag = protoTag
+	field.Tag = tag.Number
 
 	// In general there is doesn't make sense to parse the protobuf tags to get the type,
 	// as all auto-generated once will have wire type "bytes", "varint" or "fixed64".
@@ -578,20 +604,20 @@ func protobufTagToField(tag string, field *protoField, m types.Member, t *types.
 	//   }
 	// to force the generator to use a given type (that we manually wrote serialization &
 	// deserialization methods for).
-	switch parts[0] {
+	switch tag.Wire {
 	case "varint", "fixed32", "fixed64", "bytes", "group":
 	default:
 		name := types.Name{}
-		if last := strings.LastIndex(parts[0], "."); last != -1 {
-			prefix := parts[0][:last]
+		if last := strings.LastIndex(tag.Wire, "."); last != -1 {
+			prefix := tag.Wire[:last]
 			name = types.Name{
-				Name:    parts[0][last+1:],
+				Name:    tag.Wire[last+1:],
 				Package: prefix,
 				Path:    strings.Replace(prefix, ".", "/", -1),
 			}
 		} else {
 			name = types.Name{
-				Name:    parts[0],
+				Name:    tag.Wire,
 				Package: localPackage.Package,
 				Path:    localPackage.Path,
 			}
@@ -603,30 +629,16 @@ func protobufTagToField(tag string, field *protoField, m types.Member, t *types.
 	}
 
 	protoExtra := make(map[string]string)
-	for i, extra := range parts[3:] {
-		if strings.Contains(extra, "[") || strings.Contains(extra, "]") {
-			continue
-		}
-		if extra == "proto3" {
-			continue
-		}
-		parts := strings.SplitN(extra, "=", 2)
-		if len(parts) != 2 {
-			return fmt.Errorf("member %q of %q malformed 'protobuf' tag, tag %d should be key=value, got %q\n", m.Name, t.Name, i+4, extra)
-		}
-		switch parts[0] {
-		case "name":
-			protoExtra[parts[0]] = parts[1]
+	for k, v := range tag.Extras {
+		switch k {
 		case "casttype", "castkey", "castvalue":
-			parts[0] = fmt.Sprintf("(gogoproto.%s)", parts[0])
-			protoExtra[parts[0]] = strconv.Quote(parts[1])
+			protoExtra[fmt.Sprintf("(gogoproto.%s)", k)] = strconv.Quote(v)
 		}
 	}
 
 	field.Extras = protoExtra
-	if name, ok := protoExtra["name"]; ok {
-		field.Name = name
-		delete(protoExtra, "name")
+	if tag.Name != "" {
+		field.Name = tag.Name
 	}
 
 	return nil
@@ -640,42 +652,48 @@ func membersToFields(locator ProtobufLocator, t *types.Type, localPackage types.
 			// skip private fields
 			continue
 		}
-		if !extractFieldBoolTagOrDie(tagEnable, m.CommentLines) {
+		enabled, err := extractFieldBoolTag(tagEnable, m.CommentLines)
+		if err != nil {
+			return nil, generator.Wrapf(m.Position, err, "field %s", m.Name)
+		}
+		if !enabled {
 			continue
 		}
 		if _, ok := omitFieldTypes[types.Name{Name: m.Type.Name.Name, Package: m.Type.Name.Package}]; ok {
 			continue
 		}
-		tags := reflect.StructTag(m.Tags)
+		tags := m.StructTags()
 		field := protoField{
 			LocalPackage: localPackage,
 			Tag:          -1,
 			Extras:       make(map[string]string),
+			Position:     m.Position,
 		}
 
-		protobufTag := tags.Get("protobuf")
-		if protobufTag == "-" {
+		if tag, _ := tags.Protobuf(); tag.Ignored {
 			continue
 		}
 
+		// Embedded members whose fields json inlines are embedded in the
+		// message too.
 		markers := types.ExtractCommentTags("+", m.CommentLines)
-		if v := markers[tagEmbedded]; v != nil {
+		if v := markers[tagEmbedded]; v != nil || (m.Embedded && tags.JSON().Inline) {
 			field.Embedded = true
 			field.Type = m.Type
 			field.Type.Name.Name = m.Name
 		}
 
-		if err := protobufTagToField(protobufTag, &field, m, t, localPackage); err != nil {
+		if err := protobufTagToField(tags, &field, m, t, localPackage); err != nil {
 			return nil, err
 		}
 
 		// extract information from JSON field tag
-		if tag := tags.Get("json"); len(tag) > 0 {
-			parts := strings.Split(tag, ",")
-			if len(field.Name) == 0 && len(parts[0]) != 0 {
-				field.Name = parts[0]
+		if _, ok := tags.Lookup("json"); ok {
+			tag := tags.JSON()
+			if len(field.Name) == 0 && !tag.Ignored {
+				field.Name = tag.Name
 			}
-			if field.Tag == -1 && field.Name == "-" {
+			if field.Tag == -1 && tag.Ignored {
 				continue
 			}
 			i := 0
@@ -694,7 +712,7 @@ func membersToFields(locator ProtobufLocator, t *types.Type, localPackage types.
 
 		if field.Type == nil {
 			if err := memberTypeToProtobufField(locator, &field, m.Type); err != nil {
-				return nil, fmt.Errorf("unable to embed type %q as field %q in %q: %v", m.Type, field.Name, t.Name, err)
+				return nil, generator.Wrapf(m.Position, err, "unable to embed type %q as field %q in %q", m.Type, field.Name, t.Name)
 			}
 		}
 		if len(field.Name) == 0 {
@@ -716,6 +734,17 @@ func membersToFields(locator ProtobufLocator, t *types.Type, localPackage types.
 		if field.Name != m.Name {
 			field.Extras["(gogoproto.customname)"] = strconv.Quote(m.Name)
 		}
+		// gogo names the json key of a field after the message field and
+		// always omits it when empty, the member's json tag is kept instead.
+		if tag := tags.JSON(); tag.Name != "" && !tag.Ignored {
+			jsonTag := tag.Name
+			if tag.OmitEmpty {
+				jsonTag += ",omitempty"
+			}
+			if jsonTag != field.Name+",omitempty" {
+				field.Extras["(gogoproto.jsontag)"] = strconv.Quote(jsonTag)
+			}
+		}
 		field.CommentLines = m.CommentLines
 		fields = append(fields, field)
 	}
@@ -729,7 +758,7 @@ func membersToFields(locator ProtobufLocator, t *types.Type, localPackage types.
 		tag := field.Tag
 		if tag != -1 {
 			if existing, ok := byTag[tag]; ok {
-				return nil, fmt.Errorf("field %q and %q both have tag %d", field.Name, existing.Name, tag)
+				return nil, generator.Errorf(field.Position, "field %q and %q both have tag %d", field.Name, existing.Name, tag)
 			}
 			byTag[tag] = field
 		}
@@ -0,0 +1,42 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goproto_gen
+
+import (
+	"testing"
+
+	"github.com/vine-io/gogogen/gogenerator/generator"
+	gentesting "github.com/vine-io/gogogen/gogenerator/testing"
+)
+
+func TestGoldenOnlyIDL(t *testing.T) {
+	const pkg = "github.com/vine-io/gogogen/goproto-gen/testdata/onlyidl/input"
+
+	gentesting.RunDisk(t, gentesting.DiskCase{
+		Dir:     "testdata/onlyidl",
+		Package: pkg,
+		Generate: func(out generator.OutputFS) error {
+			g := New()
+			// Keep the golden files free of the year.
+			g.Common.GoHeaderFilePath = ""
+			g.Common.GoModules = true
+			g.OutputBase = ""
+			g.Packages = pkg
+			g.OnlyIDL = true
+			g.Output = out
+			return g.Execute()
+		},
+	})
+}
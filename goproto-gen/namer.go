@@ -17,7 +17,6 @@ package goproto_gen
 import (
 	"fmt"
 	"path"
-	"reflect"
 	"strings"
 
 	"github.com/vine-io/gogogen/gogenerator/generator"
@@ -137,12 +136,12 @@ func assignGoTypeToProtoPackage(p *protobufPackage, t *types.Type, local, global
 			continue
 		}
 		field := &protoField{}
-		tag := reflect.StructTag(m.Tags).Get("protobuf")
-		if tag == "-" {
+		tags := m.StructTags()
+		if tag, _ := tags.Protobuf(); tag.Ignored {
 			continue
 		}
 
-		if err := protobufTagToField(tag, field, m, t, p.ProtoTypeName()); err == nil && field.Type != nil {
+		if err := protobufTagToField(tags, field, m, t, p.ProtoTypeName()); err == nil && field.Type != nil {
 			assignGoTypeToProtoPackage(p, field.Type, local, global, optional)
 			continue
 		}
@@ -163,6 +162,25 @@ func assignGoTypeToProtoPackage(p *protobufPackage, t *types.Type, local, global
 	}
 }
 
+// byteArrayFields returns the names of the fields of t which are fixed size byte arrays.
+func byteArrayFields(t *types.Type) map[string]struct{} {
+	fields := map[string]struct{}{}
+	for _, m := range t.Members {
+		if isByteArray(m.Type) {
+			fields[m.Name] = struct{}{}
+		}
+	}
+	return fields
+}
+
+// isByteArray returns whether t is a fixed size byte array, or an alias of one.
+func isByteArray(t *types.Type) bool {
+	for t.Kind == types.Alias {
+		t = t.Underlying
+	}
+	return t.Kind == types.Array && t.Elem.Name.Name == "byte" && len(t.Elem.Name.Package) == 0
+}
+
 // isTypeApplicableToProtobuf checks to see if a type is relevant for protobuf processing.
 // Currently, it filters out functions and private types.
 func isTypeApplicableToProtobuf(t *types.Type) bool {
@@ -197,6 +215,7 @@ func (n *protobufNamer) AssignTypesToPackages(c *generator.Context) error {
 		p.FilterTypes = make(map[types.Name]struct{})
 		p.LocalNames = make(map[string]struct{})
 		p.OptionalTypeNames = make(map[string]struct{})
+		p.ArrayFields = make(map[string]map[string]struct{})
 		for k, v := range local {
 			if v == p {
 				p.FilterTypes[k] = struct{}{}
@@ -204,6 +223,9 @@ func (n *protobufNamer) AssignTypesToPackages(c *generator.Context) error {
 				if _, ok := optional[k]; ok {
 					p.OptionalTypeNames[k.Name] = struct{}{}
 				}
+				if fields := byteArrayFields(c.Universe.Type(k)); len(fields) != 0 {
+					p.ArrayFields[k.Name] = fields
+				}
 			}
 		}
 	}
@@ -19,7 +19,6 @@ import (
 	"go/ast"
 	"os"
 	"path/filepath"
-	"reflect"
 	"strings"
 
 	"github.com/vine-io/gogogen/gogenerator/generator"
@@ -84,6 +83,10 @@ type protobufPackage struct {
 	// to remove synthetic protobuf fields.
 	OptionalTypeNames map[string]struct{}
 
+	// The fixed size byte array fields of the types in this package, by type name, whose
+	// marshallers need rewriting to copy the arrays.
+	ArrayFields map[string]map[string]struct{}
+
 	// A list of struct tags to generate onto named struct fields
 	StructTags map[string]map[string]string
 
@@ -91,9 +94,11 @@ type protobufPackage struct {
 	Imports *ImportTracker
 }
 
-func (p *protobufPackage) Clean(outputBase string) error {
+// Clean removes the generated files of the package from dir, the directory
+// which holds them.
+func (p *protobufPackage) Clean(dir string) error {
 	for _, s := range []string{p.ImportPath(), p.OutputPath()} {
-		if err := os.Remove(filepath.Join(outputBase, s)); err != nil && !os.IsNotExist(err) {
+		if err := os.Remove(filepath.Join(dir, filepath.Base(s))); err != nil && !os.IsNotExist(err) {
 			return err
 		}
 	}
@@ -109,6 +114,10 @@ func (p *protobufPackage) ProtoTypeName() types.Name {
 }
 
 func (p *protobufPackage) filterFunc(c *generator.Context, t *types.Type) bool {
+	// Generic types and their instantiations are only supported as fields.
+	if t.IsGeneric() || t.IsInstantiated() {
+		return false
+	}
 	switch t.Kind {
 	case types.Func, types.Chan:
 		return false
@@ -117,13 +126,23 @@ func (p *protobufPackage) filterFunc(c *generator.Context, t *types.Type) bool {
 			return false
 		}
 		// +gogo:genproto
-		if !extractBoolTagOrDie(tagEnable, t.CommentLines) {
+		enabled, err := extractBoolTag(tagEnable, t.CommentLines)
+		if err != nil {
+			c.Diagnostics.Report(generator.Wrapf(t.Position, err, "type %v", t))
+			return false
+		}
+		if !enabled {
 			return false
 		}
 	case types.Builtin:
 		return false
 	case types.Alias:
-		if !isOptionalAlias(t) {
+		optional, err := optionalAlias(t)
+		if err != nil {
+			c.Diagnostics.Report(err)
+			return false
+		}
+		if !optional {
 			return false
 		}
 	case types.Slice, types.Array, types.Map:
@@ -148,6 +167,11 @@ func (p *protobufPackage) OptionalTypeName(name string) bool {
 	return ok
 }
 
+func (p *protobufPackage) ArrayField(name, field string) bool {
+	_, ok := p.ArrayFields[name][field]
+	return ok
+}
+
 func (p *protobufPackage) ExtractGeneratedType(t *ast.TypeSpec) bool {
 	if !p.HasGoType(t.Name.Name) {
 		return false
@@ -160,24 +184,22 @@ func (p *protobufPackage) ExtractGeneratedType(t *ast.TypeSpec) bool {
 				continue
 			}
 			tag := strings.Trim(f.Tag.Value, "`")
-			protobufTag := reflect.StructTag(tag).Get("protobuf")
+			tags := types.ParseStructTags(tag)
+			protobufTag := tags.Get("protobuf")
 			if len(protobufTag) == 0 {
 				continue
 			}
 			// `json:"create_time" protobuf:"json=createTime"`
 			// => `json:"create_time" protobuf:"json=create_time"`
-			jsonTag := reflect.StructTag(tag).Get("json")
-			if len(jsonTag) != 0 {
+			if jsonName := tags.JSON().Name; len(jsonName) != 0 {
 				chg := false
-				parts := strings.Split(protobufTag, ",")
 				protobufParts := make([]string, 0)
-				for _, item := range parts {
+				for _, item := range tags.Options("protobuf") {
 					part := strings.TrimSpace(item)
 					if len(part) == 0 {
 						continue
 					}
 					if strings.HasPrefix(part, "json=") {
-						jsonName := strings.Split(jsonTag, ",")[0]
 						if strings.TrimPrefix(part, "json=") != jsonName {
 							part = "json=" + jsonName
 							chg = true
@@ -187,7 +209,7 @@ func (p *protobufPackage) ExtractGeneratedType(t *ast.TypeSpec) bool {
 				}
 				if chg {
 					protobufTag = strings.Join(protobufParts, ",")
-					tag = changeTag(tag, map[string]string{"protobuf": protobufTag})
+					tag = tags.Set("protobuf", protobufTag).String()
 				}
 			}
 			if len(f.Names) > 1 {
@@ -245,44 +267,3 @@ func (p *protobufPackage) OutputPath() string {
 var (
 	_ = generator.Package(&protobufPackage{})
 )
-
-func tagHeaderTrim(tag string) (string, string) {
-	parts := strings.Split(tag, ":")
-	if len(parts) > 1 {
-		return parts[0], strings.Trim(parts[1], `"`)
-	}
-	return tag, ""
-}
-
-func changeTag(tag string, items map[string]string) string {
-
-	replace := func(tag, item string) string {
-		parts := strings.Split(tag, " ")
-		tagSlice := make([]string, 0)
-		header, _ := tagHeaderTrim(item)
-		for _, part := range parts {
-			part = strings.TrimSpace(part)
-			if len(part) == 0 {
-				continue
-			}
-			if strings.HasPrefix(part, header) {
-				part = item
-			}
-			tagSlice = append(tagSlice, part)
-		}
-
-		return strings.Join(tagSlice, " ")
-	}
-
-	for k, v := range items {
-		item := fmt.Sprintf(`%s:"%s"`, k, v)
-		if _, ok := reflect.StructTag(tag).Lookup(k); !ok {
-			tag += " " + item
-			continue
-		}
-
-		tag = replace(tag, item)
-	}
-
-	return tag
-}
@@ -25,9 +25,10 @@ import (
 	"go/token"
 	"io/ioutil"
 	"os"
-	"reflect"
+	"strconv"
 	"strings"
 
+	"github.com/vine-io/gogogen/gogenerator/types"
 	customreflect "github.com/vine-io/gogogen/util/third_party/forked/golang/reflect"
 )
 
@@ -76,7 +77,11 @@ type ExtractFunc func(*ast.TypeSpec) bool
 // and should have its marshal functions adjusted to remove the 'Items' accessor.
 type OptionalFunc func(name string) bool
 
-func RewriteGeneratedGogoProtobufFile(name string, extractFn ExtractFunc, optionalFn OptionalFunc, header []byte) error {
+// ArrayFunc returns true if the field of the provided local type name is a fixed size byte
+// array, which is sent as bytes and whose marshal functions must copy the array.
+type ArrayFunc func(name, field string) bool
+
+func RewriteGeneratedGogoProtobufFile(name string, extractFn ExtractFunc, optionalFn OptionalFunc, arrayFn ArrayFunc, header []byte) error {
 	return rewriteFile(name, header, func(fset *token.FileSet, file *ast.File) error {
 		cmap := ast.NewCommentMap(fset, file, file.Comments)
 
@@ -85,6 +90,11 @@ func RewriteGeneratedGogoProtobufFile(name string, extractFn ExtractFunc, option
 			rewriteOptionalMethods(d, optionalFn)
 		}
 
+		// transform methods of types with fixed size byte arrays
+		for _, d := range file.Decls {
+			rewriteArrayMethods(d, arrayFn)
+		}
+
 		// remove types that are already declared
 		decls := []ast.Decl{}
 		for _, d := range file.Decls {
@@ -269,6 +279,101 @@ func (v *optionalItemsVisitor) Visit(n ast.Node) ast.Visitor {
 	return v
 }
 
+// rewriteArrayMethods adjusts the marshaller methods of a type with fixed size byte array fields,
+// which gogo-protobuf generates for []byte fields, to copy the arrays instead.
+func rewriteArrayMethods(decl ast.Decl, isArray ArrayFunc) {
+	t, ok := decl.(*ast.FuncDecl)
+	if !ok || t.Body == nil {
+		return
+	}
+	ident, _, ok := receiver(t)
+	if !ok {
+		return
+	}
+	ast.Walk(&arrayFieldsVisitor{name: ident.Name, fn: isArray}, t.Body)
+}
+
+type arrayFieldsVisitor struct {
+	name string
+	fn   ArrayFunc
+}
+
+// field returns n if it is a fixed size byte array field of the form m.Field.
+func (v *arrayFieldsVisitor) field(n ast.Expr) (*ast.SelectorExpr, bool) {
+	s, ok := n.(*ast.SelectorExpr)
+	if !ok || !isIdent(s.X, "m") || !v.fn(v.name, s.Sel.Name) {
+		return nil, false
+	}
+	return s, true
+}
+
+// Visit walks the provided node, transforming
+// copy(dAtA[i:], m.Field) -> copy(dAtA[i:], m.Field[:]) and
+// m.Field = append(m.Field[:0], dAtA[iNdEx:postIndex]...) -> copy(m.Field[:], dAtA[iNdEx:postIndex]),
+// once the length of the data is checked, and dropping the
+// if m.Field == nil { m.Field = []byte{} } which follows the latter.
+func (v *arrayFieldsVisitor) Visit(n ast.Node) ast.Visitor {
+	switch t := n.(type) {
+	case *ast.BlockStmt:
+		t.List = v.rewriteStmts(t.List)
+	case *ast.CaseClause:
+		t.Body = v.rewriteStmts(t.Body)
+	case *ast.CallExpr:
+		if isIdent(t.Fun, "copy") && len(t.Args) == 2 {
+			if f, ok := v.field(t.Args[1]); ok {
+				t.Args[1] = &ast.SliceExpr{X: f}
+			}
+		}
+	}
+	return v
+}
+
+func (v *arrayFieldsVisitor) rewriteStmts(stmts []ast.Stmt) []ast.Stmt {
+	out := make([]ast.Stmt, 0, len(stmts))
+	for _, stmt := range stmts {
+		switch t := stmt.(type) {
+		case *ast.AssignStmt:
+			if len(t.Lhs) != 1 || len(t.Rhs) != 1 {
+				break
+			}
+			f, ok := v.field(t.Lhs[0])
+			if !ok {
+				break
+			}
+			if call, ok := t.Rhs[0].(*ast.CallExpr); ok && isIdent(call.Fun, "append") && len(call.Args) == 2 {
+				out = append(out, copyArrayStmts(f, call.Args[1])...)
+				continue
+			}
+		case *ast.IfStmt:
+			if cond, ok := t.Cond.(*ast.BinaryExpr); ok && cond.Op == token.EQL && isIdent(cond.Y, "nil") {
+				if _, ok := v.field(cond.X); ok {
+					continue
+				}
+			}
+		}
+		out = append(out, stmt)
+	}
+	return out
+}
+
+// copyArrayStmts returns the statements copying data, which must be as long as the array, into
+// the array field f.
+func copyArrayStmts(f *ast.SelectorExpr, data ast.Expr) []ast.Stmt {
+	length := &ast.CallExpr{Fun: ast.NewIdent("len"), Args: []ast.Expr{data}}
+	return []ast.Stmt{
+		&ast.IfStmt{
+			Cond: &ast.BinaryExpr{X: length, Op: token.NEQ, Y: &ast.CallExpr{Fun: ast.NewIdent("len"), Args: []ast.Expr{f}}},
+			Body: &ast.BlockStmt{List: []ast.Stmt{
+				&ast.ReturnStmt{Results: []ast.Expr{&ast.CallExpr{
+					Fun:  &ast.SelectorExpr{X: ast.NewIdent("fmt"), Sel: ast.NewIdent("Errorf")},
+					Args: []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote("proto: wrong length %d for field " + f.Sel.Name)}, length},
+				}}},
+			}},
+		},
+		&ast.ExprStmt{X: &ast.CallExpr{Fun: ast.NewIdent("copy"), Args: []ast.Expr{&ast.SliceExpr{X: f}, data}}},
+	}
+}
+
 func isFieldSelector(n ast.Expr, name, field string) bool {
 	s, ok := n.(*ast.SelectorExpr)
 	if !ok || s.Sel == nil || (field != "" && s.Sel.Name != field) {
@@ -446,7 +551,7 @@ func updateStructTags(decl ast.Decl, structTags map[string]map[string]string, to
 					continue
 				}
 				// append new tags
-				if v := reflect.StructTag(value).Get(name); len(v) > 0 {
+				if v := types.ParseStructTags(value).Get(name); len(v) > 0 {
 					if idx := strings.Index(v, "["); idx > 0 {
 						v = v[:idx]
 					}
@@ -0,0 +1,149 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goproto_gen
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// hashTypes is the Go type protobuf code is generated for.
+const hashTypes = `package hash
+
+type Sum [4]byte
+
+type Hash struct {
+	Name string
+	Raw  [4]byte
+	Sum  Sum
+}
+`
+
+// hashGenerated is what gogo-protobuf generates for Hash, whose array fields
+// are bytes in the IDL, trimmed to the statements handling them.
+const hashGenerated = `package hash
+
+import (
+	fmt "fmt"
+	io "io"
+)
+
+type Hash struct {
+	Name string
+	Raw  []byte
+	Sum  Sum
+}
+
+func (m *Hash) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	if len(m.Sum) > 0 {
+		i -= len(m.Sum)
+		copy(dAtA[i:], m.Sum)
+		i--
+		dAtA[i] = 0x1a
+	}
+	if len(m.Raw) > 0 {
+		i -= len(m.Raw)
+		copy(dAtA[i:], m.Raw)
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.Name) > 0 {
+		i -= len(m.Name)
+		copy(dAtA[i:], m.Name)
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Hash) Unmarshal(dAtA []byte) error {
+	iNdEx, postIndex, fieldNum := 0, len(dAtA), 0
+	switch fieldNum {
+	case 1:
+		m.Name = string(dAtA[iNdEx:postIndex])
+	case 2:
+		if postIndex > len(dAtA) {
+			return io.ErrUnexpectedEOF
+		}
+		m.Raw = append(m.Raw[:0], dAtA[iNdEx:postIndex]...)
+		if m.Raw == nil {
+			m.Raw = []byte{}
+		}
+	case 3:
+		m.Sum = append(m.Sum[:0], dAtA[iNdEx:postIndex]...)
+		if m.Sum == nil {
+			m.Sum = []byte{}
+		}
+	default:
+		return fmt.Errorf("proto: illegal tag %d", fieldNum)
+	}
+	return nil
+}
+`
+
+func TestRewriteArrayFields(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "generated.pb.go")
+	if err := os.WriteFile(name, []byte(hashGenerated), 0644); err != nil {
+		t.Fatal(err)
+	}
+	extract := func(t *ast.TypeSpec) bool { return t.Name.Name == "Hash" }
+	optional := func(string) bool { return false }
+	array := func(name, field string) bool { return name == "Hash" && (field == "Raw" || field == "Sum") }
+	if err := RewriteGeneratedGogoProtobufFile(name, extract, optional, array, nil); err != nil {
+		t.Fatal(err)
+	}
+	generated, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The rewritten code compiles against the Go type, whose fields are arrays.
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, src := range []string{hashTypes, string(generated)} {
+		f, err := parser.ParseFile(fset, "", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("hash", fset, files, nil); err != nil {
+		t.Fatalf("rewritten code does not compile: %v\n%s", err, generated)
+	}
+
+	for _, want := range []string{
+		"copy(dAtA[i:], m.Raw[:])",
+		"copy(m.Raw[:], dAtA[iNdEx:postIndex])",
+		`return fmt.Errorf("proto: wrong length %d for field Sum", len(dAtA[iNdEx:postIndex]))`,
+		"copy(dAtA[i:], m.Name)",
+	} {
+		if !strings.Contains(string(generated), want) {
+			t.Errorf("rewritten code does not contain %q:\n%s", want, generated)
+		}
+	}
+	if strings.Contains(string(generated), "[]byte{}") {
+		t.Errorf("rewritten code still initializes an array field with a slice:\n%s", generated)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goproto_gen
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/vine-io/gogogen/gogenerator/generator"
+	"github.com/vine-io/gogogen/gogenerator/parser"
+)
+
+// shadowPackages copies the go and proto files of pkgs into a new shadow
+// copy, which packageDir then returns the directories of.
+func (g *Generator) shadowPackages(b *parser.Builder, pkgs generator.Packages) error {
+	names := map[string][]string{}
+	for _, p := range pkgs {
+		gp := p.(*protobufPackage)
+		names[gp.PackagePath] = []string{filepath.Base(gp.ImportPath())}
+		if !g.OnlyIDL {
+			names[gp.PackagePath] = append(names[gp.PackagePath], filepath.Base(gp.OutputPath()))
+		}
+	}
+	match := func(name string) bool {
+		if strings.HasSuffix(name, "_test.go") {
+			return false
+		}
+		// The go code of the IDL is neither generated nor removed then.
+		if g.OnlyIDL && name == g.GeneratedName+".pb.go" {
+			return false
+		}
+		return strings.HasSuffix(name, ".go") || strings.HasSuffix(name, ".proto")
+	}
+	shadow, err := generator.NewShadow("goproto-gen-shadow", match, func(pkg string) []string { return names[pkg] })
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pkgs {
+		gp := p.(*protobufPackage)
+		// Vendored packages are told apart like in Execute, which needs the
+		// universe.
+		if g.OutputBase != "" {
+			if src, err := b.PackageDir(gp.PackagePath); err == nil && strings.Contains(src, "/vendor/") {
+				gp.Vendored = true
+			}
+		}
+		dir, err := g.packageDir(b, gp)
+		if err == nil {
+			err = shadow.Add(gp.PackagePath, dir)
+		}
+		if err != nil {
+			shadow.Remove()
+			return err
+		}
+	}
+	g.shadow = shadow
+	return nil
+}
+
+// recordManifest records the source tree copies of the generated files of
+// the shadow copy, and the sources they are generated from, in the manifest
+// if any.
+func (g *Generator) recordManifest() error {
+	if err := g.Common.RecordManifest(g.shadow.Manifest()); err != nil {
+		return fmt.Errorf("unable to write the manifest: %v", err)
+	}
+	return nil
+}
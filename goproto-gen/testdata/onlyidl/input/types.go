@@ -0,0 +1,31 @@
+package input
+
+// +gogo:genproto=true
+// Base holds the fields shared by the other types.
+type Base struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// +gogo:genproto=true
+// Item is an element of a Group.
+type Item struct {
+	ID   int64    `json:"id"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+// +gogo:genproto=true
+// Group is a named list of items.
+type Group struct {
+	Base `json:",inline"`
+
+	Items []*Item          `json:"items"`
+	ByID  map[string]*Item `json:"byID,omitempty"`
+	Owner *Item            `json:"owner"`
+	Size  int32            `json:"size"`
+}
+
+// Hidden isn't generated.
+type Hidden struct {
+	Value string
+}
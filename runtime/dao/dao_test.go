@@ -10,7 +10,7 @@ import (
 
 type Kind string
 
-type User[V _] struct {
+type User[V any] struct {
 }
 
 func (u User[V]) Scan(src any) error {
@@ -32,12 +32,12 @@ func GetById[R ~int](ctx context.Context, id R) {
 
 }
 
-var _ JSONValue = (*User)(nil)
+var _ JSONValue = (*User[int])(nil)
 
 type Product struct {
 	Labels Map[string, Kind]
 	Items  Array[Kind]
 
 	Users   JSONArray[*User[int]]
-	UserMap JSONMap[string, *User]
+	UserMap JSONMap[string, *User[int]]
 }
@@ -1,3 +1,5 @@
+// gogogen:owner=deepcopy-gen
+
 //go:build !ignore_autogenerated
 // +build !ignore_autogenerated
 
@@ -18,6 +20,10 @@
 
 package meta
 
+import (
+	"github.com/vine-io/gogogen/runtime/dao"
+)
+
 // DeepCopyInto is an auto-generated deepcopy function, coping the receiver, writing into out. in must be no-nil.
 func (in *Meta) DeepCopyInto(out *Meta) {
 	*out = *in
@@ -55,3 +61,102 @@ func (in *Meta) DeepFrom(o *Meta) {
 	}
 	o.DeepCopyInto(in)
 }
+
+// DeepCopyInto is an auto-generated deepcopy function, coping the receiver, writing into out. in must be no-nil.
+func (in *Resource) DeepCopyInto(out *Resource) {
+	*out = *in
+	in.Meta.DeepCopyInto(&out.Meta)
+	if in.Label != nil {
+		in, out := &in.Label, &out.Label
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(dao.Array[string], len(*in))
+		copy(*out, *in)
+	}
+	if in.Ann != nil {
+		in, out := &in.Ann, &out.Ann
+		*out = make(dao.Map[string, string], len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Subs != nil {
+		in, out := &in.Subs, &out.Subs
+		*out = make(dao.JSONArray[*Sub], len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(Sub)
+				**out = **in
+			}
+		}
+	}
+	if in.SubMap != nil {
+		in, out := &in.SubMap, &out.SubMap
+		*out = make(dao.JSONMap[string, *Sub], len(*in))
+		for key, val := range *in {
+			var outVal *Sub
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = new(Sub)
+				**out = **in
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.Enable != nil {
+		in, out := &in.Enable, &out.Enable
+		*out = new(bool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an auto-generated deepcopy function, copying the receiver, creating a new Resource.
+func (in *Resource) DeepCopy() *Resource {
+	if in == nil {
+		return nil
+	}
+	out := new(Resource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepFrom is an auto-generated deepcopy function, copying from Resource.
+func (in *Resource) DeepFrom(o *Resource) {
+	if in == nil {
+		return
+	}
+	o.DeepCopyInto(in)
+}
+
+// DeepCopyInto is an auto-generated deepcopy function, coping the receiver, writing into out. in must be no-nil.
+func (in *Sub) DeepCopyInto(out *Sub) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an auto-generated deepcopy function, copying the receiver, creating a new Sub.
+func (in *Sub) DeepCopy() *Sub {
+	if in == nil {
+		return nil
+	}
+	out := new(Sub)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepFrom is an auto-generated deepcopy function, copying from Sub.
+func (in *Sub) DeepFrom(o *Sub) {
+	if in == nil {
+		return
+	}
+	o.DeepCopyInto(in)
+}
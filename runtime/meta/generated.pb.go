@@ -131,7 +131,9 @@ func init() {
 	proto.RegisterMapType((map[string]string)(nil), "meta.Meta.AnnotationsEntry")
 	proto.RegisterMapType((map[string]string)(nil), "meta.Meta.TagsEntry")
 	proto.RegisterType((*Resource)(nil), "meta.Resource")
+	proto.RegisterMapType((map[string]string)(nil), "meta.Resource.AnnEntry")
 	proto.RegisterMapType((map[string]string)(nil), "meta.Resource.LabelEntry")
+	proto.RegisterMapType((map[string]*Sub)(nil), "meta.Resource.SubMapEntry")
 	proto.RegisterType((*Sub)(nil), "meta.Sub")
 }
 
@@ -140,41 +142,53 @@ func init() {
 }
 
 var fileDescriptor_74c1045c0ce15659 = []byte{
-	// 537 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x54, 0xcb, 0x6e, 0xd3, 0x4c,
-	0x14, 0xf6, 0xd4, 0x4e, 0xff, 0x66, 0xf2, 0xa3, 0xb6, 0x43, 0x25, 0xdc, 0x20, 0xa6, 0x51, 0x37,
-	0x64, 0x53, 0x5b, 0x2a, 0x12, 0xad, 0x58, 0x54, 0x4a, 0x28, 0xa0, 0x8a, 0x8b, 0xd0, 0xb4, 0x61,
-	0xc1, 0x6e, 0x12, 0x1f, 0xcc, 0xa8, 0xf1, 0xd8, 0xb2, 0xc7, 0x95, 0xba, 0xe3, 0x11, 0x78, 0x0a,
-	0x9e, 0x25, 0xcb, 0x2c, 0xb3, 0xaa, 0x88, 0xf3, 0x10, 0x6c, 0xd1, 0x8c, 0x4d, 0x73, 0xa9, 0x84,
-	0xe8, 0x6e, 0xe6, 0x7c, 0x97, 0x39, 0x27, 0xe7, 0x8b, 0xf1, 0x51, 0x28, 0xd4, 0xd7, 0xbc, 0xef,
-	0x0d, 0xe2, 0xc8, 0xbf, 0x12, 0x12, 0x0e, 0x44, 0xec, 0x87, 0x71, 0x18, 0x87, 0x20, 0xfd, 0x34,
-	0x97, 0x4a, 0x44, 0xe0, 0x47, 0xa0, 0xb8, 0x1f, 0x82, 0x84, 0x94, 0x2b, 0x08, 0xbc, 0x24, 0x8d,
-	0x55, 0x4c, 0x1c, 0x5d, 0x6d, 0x1e, 0x2c, 0xc8, 0xb5, 0xcc, 0x37, 0x60, 0x3f, 0xff, 0x62, 0x6e,
-	0xe6, 0x62, 0x4e, 0xa5, 0x68, 0xff, 0x97, 0x83, 0x9d, 0xf7, 0xa0, 0x38, 0x69, 0x61, 0xe7, 0x52,
-	0xc8, 0xc0, 0x45, 0x2d, 0xd4, 0xae, 0x77, 0xff, 0x1f, 0xdd, 0xec, 0x59, 0xc5, 0xcd, 0x9e, 0xf3,
-	0x56, 0xc8, 0x80, 0x19, 0x84, 0x1c, 0x62, 0xcc, 0x13, 0xf1, 0x09, 0xd2, 0x4c, 0xc4, 0xd2, 0x5d,
-	0x33, 0x3c, 0x52, 0xf1, 0x70, 0xe7, 0xe3, 0x59, 0x85, 0xb0, 0x05, 0x96, 0x76, 0x95, 0x3c, 0x02,
-	0xd7, 0x5e, 0x76, 0xfd, 0xc0, 0x23, 0x60, 0x06, 0x21, 0x4f, 0xb0, 0x9d, 0x8b, 0xc0, 0x75, 0x0c,
-	0xa1, 0x51, 0x11, 0xec, 0xde, 0xd9, 0x29, 0xd3, 0x75, 0xf2, 0x06, 0x6f, 0x0f, 0x52, 0xe0, 0x4a,
-	0xc4, 0xf2, 0x42, 0x44, 0x90, 0x29, 0x1e, 0x25, 0x6e, 0xad, 0x85, 0xda, 0x76, 0x77, 0xb7, 0x22,
-	0x6f, 0xbf, 0x5c, 0x25, 0xb0, 0xbb, 0x1a, 0xd2, 0xc1, 0x9b, 0x79, 0x12, 0x70, 0x05, 0x73, 0x9b,
-	0x75, 0x63, 0xf3, 0xa8, 0xb2, 0xd9, 0xec, 0x2d, 0xc3, 0x6c, 0x95, 0xaf, 0x7b, 0x09, 0x60, 0x08,
-	0xcb, 0xbd, 0xfc, 0xb7, 0xdc, 0xcb, 0xe9, 0x2a, 0x81, 0xdd, 0xd5, 0x90, 0xe7, 0xd8, 0x51, 0x3c,
-	0xcc, 0xdc, 0x8d, 0x96, 0xdd, 0x6e, 0x1c, 0xee, 0x78, 0x7a, 0x71, 0x9e, 0xde, 0x82, 0x77, 0xc1,
-	0xc3, 0xec, 0x95, 0x54, 0xe9, 0xf5, 0xfc, 0xb7, 0xd2, 0x25, 0x66, 0xf8, 0x84, 0xe1, 0x06, 0x97,
-	0x32, 0x56, 0x66, 0xb4, 0xcc, 0xad, 0x1b, 0xf9, 0xe3, 0x05, 0x79, 0x67, 0x8e, 0x96, 0x2e, 0x0f,
-	0x2b, 0x97, 0xc6, 0x02, 0xc2, 0x16, 0x4d, 0x9a, 0x47, 0xb8, 0x7e, 0xfb, 0x28, 0xd9, 0xc2, 0xf6,
-	0x25, 0x5c, 0x97, 0x19, 0x60, 0xfa, 0x48, 0x76, 0x70, 0xed, 0x8a, 0x0f, 0x73, 0x28, 0xf7, 0xcd,
-	0xca, 0xcb, 0x8b, 0xb5, 0x63, 0xd4, 0x3c, 0xc1, 0x5b, 0xab, 0xcf, 0xdd, 0x47, 0xbf, 0xff, 0x03,
-	0xe1, 0x0d, 0x06, 0x59, 0x9c, 0xa7, 0x03, 0xd0, 0x39, 0xc9, 0x12, 0x18, 0xac, 0xa6, 0xef, 0x3c,
-	0x81, 0x01, 0x33, 0x08, 0x39, 0xc1, 0xb5, 0x21, 0xef, 0xc3, 0xd0, 0x5d, 0x33, 0x53, 0xef, 0x96,
-	0x53, 0xff, 0x31, 0xf0, 0xde, 0x69, 0xac, 0x9c, 0xf9, 0x41, 0xa5, 0xae, 0x99, 0x1a, 0x2b, 0x65,
-	0xcd, 0x63, 0x8c, 0xe7, 0x9c, 0x7b, 0x35, 0xfa, 0x1a, 0xdb, 0xe7, 0x79, 0xff, 0x36, 0xca, 0xe8,
-	0x6f, 0x51, 0xe6, 0x61, 0x69, 0x50, 0x9b, 0x47, 0xb9, 0x13, 0x02, 0xd3, 0xf5, 0x6e, 0x6f, 0x34,
-	0xa5, 0xd6, 0x78, 0x4a, 0xad, 0xc9, 0x94, 0xa2, 0x6f, 0x05, 0x45, 0xa3, 0x82, 0xa2, 0x71, 0x41,
-	0xd1, 0xa4, 0xa0, 0xe8, 0x67, 0x41, 0xd1, 0xf7, 0x19, 0xb5, 0xc6, 0x33, 0x6a, 0x4d, 0x66, 0xd4,
-	0xfa, 0xfc, 0xf4, 0x1f, 0x3f, 0x03, 0xfd, 0x75, 0xf3, 0x47, 0x7e, 0xf6, 0x3b, 0x00, 0x00, 0xff,
-	0xff, 0x8d, 0xb8, 0x42, 0xe1, 0x38, 0x04, 0x00, 0x00,
+	// 734 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x55, 0x41, 0x6b, 0xdb, 0x4a,
+	0x10, 0xb6, 0x22, 0xd9, 0xb1, 0xd7, 0x87, 0x24, 0x4b, 0x78, 0x4f, 0xcf, 0x0f, 0x24, 0x13, 0x0a,
+	0x35, 0xa5, 0xb1, 0xa1, 0x85, 0x26, 0x14, 0x1a, 0x6a, 0x37, 0xa1, 0x0d, 0x6d, 0x4a, 0x59, 0xc7,
+	0x3d, 0x94, 0x5e, 0x56, 0xf6, 0x56, 0x15, 0xb1, 0x57, 0xc6, 0x92, 0x02, 0xb9, 0xf5, 0x27, 0xf4,
+	0x67, 0xe5, 0x98, 0x63, 0x4e, 0xa2, 0x51, 0x6e, 0x3a, 0xb5, 0xff, 0xa0, 0xcc, 0xac, 0x12, 0xcb,
+	0x72, 0x0b, 0xc9, 0x65, 0x76, 0x77, 0xbe, 0xf9, 0x66, 0x66, 0x3f, 0xcf, 0x5a, 0x64, 0xc7, 0xf5,
+	0xc2, 0xaf, 0x91, 0xd3, 0x1e, 0xfa, 0x93, 0xce, 0xa9, 0x27, 0xc5, 0xb6, 0xe7, 0x77, 0x5c, 0xdf,
+	0xf5, 0x5d, 0x21, 0x3b, 0xb3, 0x48, 0x86, 0xde, 0x44, 0x74, 0x26, 0x22, 0xe4, 0x1d, 0x57, 0x48,
+	0x31, 0xe3, 0xa1, 0x18, 0xb5, 0xa7, 0x33, 0x3f, 0xf4, 0xa9, 0x01, 0xde, 0xc6, 0x76, 0x8e, 0x0e,
+	0xb4, 0x0e, 0x82, 0x4e, 0xf4, 0x05, 0x4f, 0x78, 0xc0, 0x9d, 0x22, 0x6d, 0xfd, 0x2c, 0x13, 0xe3,
+	0x48, 0x84, 0x9c, 0xb6, 0x88, 0x71, 0xe2, 0xc9, 0x91, 0xa9, 0x35, 0xb5, 0x56, 0xad, 0xb7, 0x79,
+	0x1e, 0xdb, 0xa5, 0x24, 0xb6, 0x8d, 0xb7, 0x9e, 0x1c, 0xa5, 0xb1, 0x8d, 0x18, 0x43, 0x4b, 0x5f,
+	0x12, 0xc2, 0xa7, 0xde, 0x47, 0x31, 0x0b, 0x3c, 0x5f, 0x9a, 0x2b, 0x18, 0xdf, 0xcc, 0xe2, 0x49,
+	0xf7, 0xc3, 0x61, 0x86, 0xa4, 0xb1, 0x9d, 0x8b, 0x63, 0xb9, 0x3d, 0xd4, 0x92, 0x7c, 0x22, 0x4c,
+	0x7d, 0xb1, 0xd6, 0x7b, 0x3e, 0x11, 0x50, 0x0b, 0x30, 0x86, 0x96, 0x3e, 0x20, 0x7a, 0xe4, 0x8d,
+	0x4c, 0x03, 0x03, 0x69, 0x16, 0xa8, 0x0f, 0x0e, 0xf7, 0xd3, 0xd8, 0x06, 0x84, 0x81, 0xa1, 0x9f,
+	0xc9, 0xc6, 0x70, 0x26, 0x78, 0xe8, 0xf9, 0xf2, 0xd8, 0x9b, 0x88, 0x20, 0xe4, 0x93, 0xa9, 0x59,
+	0x6e, 0x6a, 0x2d, 0xbd, 0xd7, 0xce, 0x38, 0x1b, 0xaf, 0x8a, 0x01, 0x69, 0x6c, 0x2f, 0xb3, 0xd8,
+	0xb2, 0x8b, 0x1e, 0x93, 0xb5, 0x68, 0x3a, 0xe2, 0xa1, 0x98, 0xe7, 0xae, 0x60, 0xee, 0x47, 0x59,
+	0xee, 0xb5, 0xc1, 0x22, 0x9c, 0xc6, 0x76, 0x91, 0xc1, 0x8a, 0x0e, 0xe8, 0x79, 0x24, 0xc6, 0x62,
+	0xb1, 0xe7, 0xd5, 0xc5, 0x9e, 0xf7, 0x8b, 0x01, 0xd0, 0xf3, 0x12, 0x8b, 0x2d, 0xbb, 0xe8, 0x1e,
+	0x31, 0x42, 0xee, 0x06, 0x66, 0xb5, 0xa9, 0xb7, 0xea, 0x4f, 0x36, 0xdb, 0x30, 0x1a, 0x6d, 0xf8,
+	0x9d, 0xdb, 0xc7, 0xdc, 0x0d, 0x0e, 0x64, 0x38, 0x3b, 0x9b, 0xeb, 0x0e, 0x2e, 0xd0, 0x1d, 0x18,
+	0x0c, 0x2d, 0xe5, 0xa4, 0xce, 0xa5, 0xf4, 0x43, 0x94, 0x22, 0x30, 0x6b, 0x98, 0xe6, 0xff, 0x5c,
+	0x9a, 0xee, 0x1c, 0x55, 0xd9, 0xb6, 0xb2, 0x6c, 0xf5, 0x1c, 0x92, 0xc6, 0x76, 0x3e, 0x0d, 0xcb,
+	0x1f, 0x1a, 0x3b, 0xa4, 0x76, 0xdb, 0x0b, 0x5d, 0x27, 0xfa, 0x89, 0x38, 0x53, 0xc3, 0xc7, 0x60,
+	0x4b, 0x37, 0x49, 0xf9, 0x94, 0x8f, 0x23, 0xa1, 0x06, 0x8c, 0xa9, 0xc3, 0xf3, 0x95, 0x5d, 0xad,
+	0xb1, 0x47, 0xd6, 0x8b, 0xd5, 0xef, 0xc3, 0xdf, 0xfa, 0x65, 0x90, 0x2a, 0x13, 0x81, 0x1f, 0xcd,
+	0x86, 0x02, 0x46, 0x31, 0x98, 0x8a, 0x61, 0x71, 0xec, 0xfb, 0x53, 0x31, 0x04, 0x49, 0x00, 0x63,
+	0x68, 0xe9, 0x6b, 0x52, 0x1e, 0x73, 0x47, 0x8c, 0xcd, 0x15, 0x14, 0xe3, 0x3f, 0x25, 0xc6, 0x4d,
+	0xa2, 0xf6, 0x3b, 0xc0, 0x94, 0x14, 0xff, 0x64, 0x59, 0xca, 0xe8, 0x4b, 0x63, 0x5b, 0x11, 0x99,
+	0x5a, 0xe8, 0x63, 0x52, 0xc1, 0x4d, 0x60, 0xea, 0x4d, 0x1d, 0x8a, 0x26, 0xb1, 0x5d, 0xc1, 0x50,
+	0x10, 0x2c, 0xc3, 0x58, 0xb6, 0xd2, 0x17, 0x44, 0xe7, 0x52, 0x9a, 0x06, 0x16, 0xfd, 0xb7, 0x50,
+	0xb4, 0x2b, 0xa5, 0x2a, 0x79, 0xfb, 0x34, 0xba, 0x12, 0x1e, 0x1e, 0x50, 0x18, 0x18, 0xda, 0x21,
+	0x46, 0x10, 0x39, 0x81, 0xb9, 0x8a, 0xfc, 0x9a, 0xe2, 0xf7, 0x23, 0xa7, 0xb7, 0x8e, 0xd7, 0x8c,
+	0x1c, 0xfc, 0xe5, 0x21, 0x84, 0xa1, 0xa5, 0x6f, 0x48, 0x25, 0x88, 0x9c, 0x23, 0x3e, 0xcd, 0x66,
+	0xa7, 0x51, 0x28, 0xd9, 0x47, 0x30, 0x9b, 0x20, 0xe8, 0x5c, 0x39, 0xa0, 0x73, 0xc5, 0x63, 0xd9,
+	0x0a, 0xf7, 0x14, 0x92, 0x3b, 0x63, 0x81, 0x4f, 0xb1, 0xaa, 0xa2, 0x0f, 0xd0, 0x03, 0xd1, 0x0a,
+	0x63, 0xd9, 0x0a, 0x2f, 0x9d, 0xbb, 0x02, 0x5f, 0x56, 0x39, 0x77, 0x1d, 0x57, 0xe0, 0x75, 0x5c,
+	0xc1, 0xc0, 0x34, 0x76, 0x09, 0x99, 0x0b, 0x7d, 0xaf, 0xa9, 0x79, 0x46, 0xaa, 0x37, 0x6a, 0xdd,
+	0x8b, 0xb7, 0x4f, 0xea, 0xb9, 0x2b, 0xff, 0x81, 0x6a, 0xe7, 0xa9, 0x79, 0x89, 0xf3, 0x33, 0x37,
+	0x20, 0x7a, 0x3f, 0x72, 0x6e, 0xff, 0xf8, 0xb4, 0xbb, 0xfc, 0xf1, 0x71, 0x57, 0xe5, 0xfc, 0xbb,
+	0x1c, 0xbd, 0xc1, 0xf9, 0x95, 0x55, 0xba, 0xb8, 0xb2, 0x4a, 0x97, 0x57, 0x96, 0xf6, 0x2d, 0xb1,
+	0xb4, 0xf3, 0xc4, 0xd2, 0x2e, 0x12, 0x4b, 0xbb, 0x4c, 0x2c, 0xed, 0x47, 0x62, 0x69, 0xdf, 0xaf,
+	0xad, 0xd2, 0xc5, 0xb5, 0x55, 0xba, 0xbc, 0xb6, 0x4a, 0x9f, 0x1e, 0xde, 0xf1, 0xcb, 0xe2, 0x54,
+	0xf0, 0xdb, 0xf0, 0xf4, 0xf7, 0x00, 0x2d, 0xc0, 0xbd, 0xd0, 0x8b, 0x06, 0x00, 0x00,
 }
 
 func (m *Meta) XSize() (n int) {
@@ -245,6 +259,45 @@ func (m *Resource) XSize() (n int) {
 			n += mapEntrySize + 1 + sovGenerated(uint64(mapEntrySize))
 		}
 	}
+	if len(m.Labels) > 0 {
+		for _, s := range m.Labels {
+			l = len(s)
+			n += 1 + l + sovGenerated(uint64(l))
+		}
+	}
+	if len(m.Ann) > 0 {
+		for k, v := range m.Ann {
+			_ = k
+			_ = v
+			mapEntrySize := 1 + len(k) + sovGenerated(uint64(len(k))) + 1 + len(v) + sovGenerated(uint64(len(v)))
+			n += mapEntrySize + 1 + sovGenerated(uint64(mapEntrySize))
+		}
+	}
+	if m.Enable != nil {
+		n += 2
+	}
+	if m.Age != 0 {
+		n += 1 + sovGenerated(uint64(m.Age))
+	}
+	if len(m.Subs) > 0 {
+		for _, e := range m.Subs {
+			l = e.XSize()
+			n += 1 + l + sovGenerated(uint64(l))
+		}
+	}
+	if len(m.SubMap) > 0 {
+		for k, v := range m.SubMap {
+			_ = k
+			_ = v
+			l = 0
+			if v != nil {
+				l = v.XSize()
+				l += 1 + sovGenerated(uint64(l))
+			}
+			mapEntrySize := 1 + len(k) + sovGenerated(uint64(len(k))) + l
+			n += mapEntrySize + 1 + sovGenerated(uint64(mapEntrySize))
+		}
+	}
 	return n
 }
 
@@ -404,6 +457,99 @@ func (m *Resource) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.SubMap) > 0 {
+		keysForSubMap := make([]string, 0, len(m.SubMap))
+		for k := range m.SubMap {
+			keysForSubMap = append(keysForSubMap, string(k))
+		}
+		sortkeys.Strings(keysForSubMap)
+		for iNdEx := len(keysForSubMap) - 1; iNdEx >= 0; iNdEx-- {
+			v := m.SubMap[string(keysForSubMap[iNdEx])]
+			baseI := i
+			if v != nil {
+				{
+					size, err := v.MarshalToSizedBuffer(dAtA[:i])
+					if err != nil {
+						return 0, err
+					}
+					i -= size
+					i = encodeVarintGenerated(dAtA, i, uint64(size))
+				}
+				i--
+				dAtA[i] = 0x12
+			}
+			i -= len(keysForSubMap[iNdEx])
+			copy(dAtA[i:], keysForSubMap[iNdEx])
+			i = encodeVarintGenerated(dAtA, i, uint64(len(keysForSubMap[iNdEx])))
+			i--
+			dAtA[i] = 0xa
+			i = encodeVarintGenerated(dAtA, i, uint64(baseI-i))
+			i--
+			dAtA[i] = 0x42
+		}
+	}
+	if len(m.Subs) > 0 {
+		for iNdEx := len(m.Subs) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Subs[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintGenerated(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x3a
+		}
+	}
+	if m.Age != 0 {
+		i = encodeVarintGenerated(dAtA, i, uint64(m.Age))
+		i--
+		dAtA[i] = 0x30
+	}
+	if m.Enable != nil {
+		i--
+		if *m.Enable {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x28
+	}
+	if len(m.Ann) > 0 {
+		keysForAnn := make([]string, 0, len(m.Ann))
+		for k := range m.Ann {
+			keysForAnn = append(keysForAnn, string(k))
+		}
+		sortkeys.Strings(keysForAnn)
+		for iNdEx := len(keysForAnn) - 1; iNdEx >= 0; iNdEx-- {
+			v := m.Ann[string(keysForAnn[iNdEx])]
+			baseI := i
+			i -= len(v)
+			copy(dAtA[i:], v)
+			i = encodeVarintGenerated(dAtA, i, uint64(len(v)))
+			i--
+			dAtA[i] = 0x12
+			i -= len(keysForAnn[iNdEx])
+			copy(dAtA[i:], keysForAnn[iNdEx])
+			i = encodeVarintGenerated(dAtA, i, uint64(len(keysForAnn[iNdEx])))
+			i--
+			dAtA[i] = 0xa
+			i = encodeVarintGenerated(dAtA, i, uint64(baseI-i))
+			i--
+			dAtA[i] = 0x22
+		}
+	}
+	if len(m.Labels) > 0 {
+		for iNdEx := len(m.Labels) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Labels[iNdEx])
+			copy(dAtA[i:], m.Labels[iNdEx])
+			i = encodeVarintGenerated(dAtA, i, uint64(len(m.Labels[iNdEx])))
+			i--
+			dAtA[i] = 0x1a
+		}
+	}
 	if len(m.Label) > 0 {
 		keysForLabel := make([]string, 0, len(m.Label))
 		for k := range m.Label {
@@ -1161,6 +1307,368 @@ func (m *Resource) Unmarshal(dAtA []byte) error {
 			}
 			m.Label[mapkey] = mapvalue
 			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Labels", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGenerated
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Labels = append(m.Labels, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ann", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGenerated
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Ann == nil {
+				m.Ann = make(map[string]string)
+			}
+			var mapkey string
+			var mapvalue string
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowGenerated
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					var stringLenmapkey uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowGenerated
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapkey |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapkey := int(stringLenmapkey)
+					if intStringLenmapkey < 0 {
+						return ErrInvalidLengthGenerated
+					}
+					postStringIndexmapkey := iNdEx + intStringLenmapkey
+					if postStringIndexmapkey < 0 {
+						return ErrInvalidLengthGenerated
+					}
+					if postStringIndexmapkey > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapkey = string(dAtA[iNdEx:postStringIndexmapkey])
+					iNdEx = postStringIndexmapkey
+				} else if fieldNum == 2 {
+					var stringLenmapvalue uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowGenerated
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapvalue |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapvalue := int(stringLenmapvalue)
+					if intStringLenmapvalue < 0 {
+						return ErrInvalidLengthGenerated
+					}
+					postStringIndexmapvalue := iNdEx + intStringLenmapvalue
+					if postStringIndexmapvalue < 0 {
+						return ErrInvalidLengthGenerated
+					}
+					if postStringIndexmapvalue > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapvalue = string(dAtA[iNdEx:postStringIndexmapvalue])
+					iNdEx = postStringIndexmapvalue
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipGenerated(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if (skippy < 0) || (iNdEx+skippy) < 0 {
+						return ErrInvalidLengthGenerated
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
+			}
+			m.Ann[mapkey] = mapvalue
+			iNdEx = postIndex
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Enable", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGenerated
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			b := bool(v != 0)
+			m.Enable = &b
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Age", wireType)
+			}
+			m.Age = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGenerated
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Age |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Subs", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGenerated
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Subs = append(m.Subs, &Sub{})
+			if err := m.Subs[len(m.Subs)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SubMap", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowGenerated
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthGenerated
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.SubMap == nil {
+				m.SubMap = make(map[string]*Sub)
+			}
+			var mapkey string
+			var mapvalue *Sub
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowGenerated
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					var stringLenmapkey uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowGenerated
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapkey |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapkey := int(stringLenmapkey)
+					if intStringLenmapkey < 0 {
+						return ErrInvalidLengthGenerated
+					}
+					postStringIndexmapkey := iNdEx + intStringLenmapkey
+					if postStringIndexmapkey < 0 {
+						return ErrInvalidLengthGenerated
+					}
+					if postStringIndexmapkey > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapkey = string(dAtA[iNdEx:postStringIndexmapkey])
+					iNdEx = postStringIndexmapkey
+				} else if fieldNum == 2 {
+					var mapmsglen int
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowGenerated
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						mapmsglen |= int(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					if mapmsglen < 0 {
+						return ErrInvalidLengthGenerated
+					}
+					postmsgIndex := iNdEx + mapmsglen
+					if postmsgIndex < 0 {
+						return ErrInvalidLengthGenerated
+					}
+					if postmsgIndex > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapvalue = &Sub{}
+					if err := mapvalue.Unmarshal(dAtA[iNdEx:postmsgIndex]); err != nil {
+						return err
+					}
+					iNdEx = postmsgIndex
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipGenerated(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if (skippy < 0) || (iNdEx+skippy) < 0 {
+						return ErrInvalidLengthGenerated
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
+			}
+			m.SubMap[mapkey] = mapvalue
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipGenerated(dAtA[iNdEx:])
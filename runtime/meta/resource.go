@@ -1,7 +1,9 @@
 package meta
 
+import "github.com/vine-io/gogogen/runtime/dao"
+
 // +gogo:genproto=true
-// +gogo:deepcopy-gen=true
+// +gogo:deepcopy=true
 // +gogo:gengorm=true
 // +gogo:gengorm:external=interfaces
 // 资源元数据
@@ -14,15 +16,15 @@ type Resource struct {
 
 	Label map[string]string `json:"label" protobuf:"bytes,2,rep,name=label,proto3"`
 
-	//Labels dao.Array[string] `json:"labels" gorm:"column:labels;serializer:json"`
-	//
-	//Ann dao.Map[string, string] `json:"ann" gorm:"column:ann;serializer:json"`
-	//
-	//Subs dao.JSONArray[*Sub] `json:"subs" gorm:"column:subs;serializer:json"`
-	//
-	//SubMap dao.JSONMap[string, *Sub] `json:"subMap" gorm:"column:subMap;serializer:json"`
-	//
-	//Enable *bool `json:"enable" gorm:"column:enable"`
-	//
-	//Age int32 `json:"age" gorm:"column:age"`
+	Labels dao.Array[string] `json:"labels" gorm:"column:labels;serializer:json" protobuf:"bytes,3,rep,name=labels,proto3"`
+
+	Ann dao.Map[string, string] `json:"ann" gorm:"column:ann;serializer:json" protobuf:"bytes,4,rep,name=ann,proto3"`
+
+	Subs dao.JSONArray[*Sub] `json:"subs" gorm:"column:subs;serializer:json" protobuf:"bytes,7,rep,name=subs,proto3"`
+
+	SubMap dao.JSONMap[string, *Sub] `json:"subMap" gorm:"column:subMap;serializer:json" protobuf:"bytes,8,rep,name=subMap,proto3"`
+
+	Enable *bool `json:"enable" gorm:"column:enable" protobuf:"varint,5,opt,name=enable,proto3"`
+
+	Age int32 `json:"age" gorm:"column:age" protobuf:"varint,6,opt,name=age,proto3"`
 }
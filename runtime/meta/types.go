@@ -14,8 +14,15 @@
 // +gogo:deepcopy-gen=package
 package meta
 
+import (
+	"database/sql/driver"
+
+	"github.com/vine-io/gogogen/runtime/dao"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
 // +gogo:deepcopy=true
-// +gogo:deepcopy:interfaces=github.com/vine-io/apimachinery/runtime.Object
 // +gogo:genproto=true
 // +gogo:gengorm=true
 // +gogo:gengorm:external=false
@@ -42,7 +49,7 @@ type Meta struct {
 	Annotations map[string]string `json:"annotations" gorm:"column:annotations;serializer:json" protobuf:"bytes,9,rep,name=annotations,proto3"`
 }
 
-// +gogo:deepcopy-gen=true
+// +gogo:deepcopy=true
 // +gogo:genproto=true
 // +gogo:gengorm=true
 // +gogo:gengorm:external=false
@@ -51,3 +58,20 @@ type Sub struct {
 	// +primaryKey
 	Age int32 `json:"age" gorm:"column:age;primaryKey" protobuf:"varint,2,opt,name=age,proto3"`
 }
+
+// Value returns the json value of a Sub, implements driver.Valuer.
+func (m *Sub) Value() (driver.Value, error) {
+	return dao.GetValue(m)
+}
+
+// Scan scans a json value into a Sub, implements sql.Scanner.
+func (m *Sub) Scan(value any) error {
+	return dao.ScanValue(value, m)
+}
+
+// GormDBDataType implements migrator.GormDBDataTypeInterface.
+func (m *Sub) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return dao.GetGormDBDataType(db, field)
+}
+
+var _ dao.JSONValue = (*Sub)(nil)
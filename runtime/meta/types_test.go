@@ -18,3 +18,29 @@ func TestJSON(t *testing.T) {
 func GoBool(b bool) *bool {
 	return &b
 }
+
+func TestResourceSubs(t *testing.T) {
+	r := Resource{
+		Subs:   []*Sub{{Name: "a", Age: 1}},
+		SubMap: map[string]*Sub{"b": {Name: "b", Age: 2}, "c": nil},
+	}
+
+	data, err := r.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out Resource
+	if err := out.Unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Subs) != 1 || *out.Subs[0] != *r.Subs[0] {
+		t.Errorf("unexpected subs: %v", out.Subs)
+	}
+	if len(out.SubMap) != 2 || *out.SubMap["b"] != *r.SubMap["b"] || out.SubMap["c"] != nil {
+		t.Errorf("unexpected sub map: %v", out.SubMap)
+	}
+
+	if _, err := out.Subs.Value(); err != nil {
+		t.Fatal(err)
+	}
+}
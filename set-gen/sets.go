@@ -15,17 +15,23 @@
 package set_gen
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/vine-io/gogogen/gogenerator/args"
 	"github.com/vine-io/gogogen/gogenerator/generator"
 	"github.com/vine-io/gogogen/gogenerator/namer"
 	"github.com/vine-io/gogogen/gogenerator/types"
-	"github.com/vine-io/gogogen/util/log"
 )
 
 const tagEnable = "gogo:genset"
 
+// Markers are the comment markers read by set-gen.
+var Markers = []*types.Marker{
+	{Name: tagEnable, Scope: types.TypeScope, Value: types.BoolMarker, Generator: "set-gen",
+		Description: "Generates a set of the type when true."},
+}
+
 // NameSystems returns the name system used by the generators in this package.
 func NameSystems() namer.NameSystems {
 	return namer.NameSystems{
@@ -41,11 +47,13 @@ func DefaultNameSystem() string {
 	return "public"
 }
 
-// Packages makes the sets package definition.
-func Packages(_ *generator.Context, arguments *args.GeneratorArgs) generator.Packages {
+// Packages makes the sets package definition. The problems are reported to
+// context.Diagnostics.
+func Packages(context *generator.Context, arguments *args.GeneratorArgs) generator.Packages {
 	boilerplate, err := arguments.LoadGoBoilerplate()
 	if err != nil {
-		log.Fatalf("failed loading boilerplate: %v", err)
+		context.Diagnostics.Report(fmt.Errorf("failed loading boilerplate: %v", err))
+		return nil
 	}
 
 	return generator.Packages{&generator.DefaultPackage{
@@ -104,7 +112,11 @@ func Packages(_ *generator.Context, arguments *args.GeneratorArgs) generator.Pac
 				// or
 				//
 				// // +gogo:genset=true
-				return extractBoolTagOrDie(tagEnable, t.CommentLines) == true
+				enabled, err := extractBoolTag(tagEnable, t.CommentLines)
+				if err != nil {
+					c.Diagnostics.Report(generator.Wrapf(t.Position, err, "type %v", t))
+				}
+				return enabled
 			}
 			return false
 		},
@@ -0,0 +1,41 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package set_gen
+
+import (
+	"testing"
+
+	"github.com/vine-io/gogogen/gogenerator/args"
+	gentesting "github.com/vine-io/gogogen/gogenerator/testing"
+)
+
+func TestGolden(t *testing.T) {
+	g := args.Default().WithoutDefaultFlagParsing()
+	// Keep the golden files free of the year.
+	g.GoHeaderFilePath = ""
+	g.OutputPackagePath = "example.com/sets/sets"
+	g.Owner = "set-gen"
+
+	gentesting.Run(t, gentesting.Case{
+		Dir:     "testdata/builtin",
+		Package: "example.com/sets",
+		Runs: []args.Run{{
+			Args:          g,
+			NameSystems:   NameSystems(),
+			DefaultSystem: DefaultNameSystem(),
+			Packages:      Packages,
+		}},
+	})
+}
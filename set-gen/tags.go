@@ -16,16 +16,10 @@ package set_gen
 
 import (
 	"github.com/vine-io/gogogen/gogenerator/types"
-	"github.com/vine-io/gogogen/util/log"
 )
 
-// extractBoolTagOrDie gets the comment-tags for the key and asserts that, if
-// it exists, the value is boolean. If the tag did not exists, it returns
-// false.
-func extractBoolTagOrDie(key string, lines []string) bool {
-	val, err := types.ExtractSingleBoolCommentTag("+", key, false, lines)
-	if err != nil {
-		log.Fatalf(err.Error())
-	}
-	return val
+// extractBoolTag gets the comment-tags for the key and asserts that, if it
+// exists, the value is boolean. If the tag did not exists, it returns false.
+func extractBoolTag(key string, lines []string) (bool, error) {
+	return types.ExtractSingleBoolCommentTag("+", key, false, lines)
 }
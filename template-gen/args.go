@@ -0,0 +1,81 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package template_gen
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+	"github.com/vine-io/gogogen/gogenerator/args"
+	"github.com/vine-io/gogogen/gogenerator/types"
+)
+
+// CustomArgs is used by the arguments to pass the config to the generator.
+type CustomArgs struct {
+	// The path of the config file declaring the templates.
+	ConfigPath string
+
+	// The config, see Load.
+	Config *Config
+}
+
+// NewDefaults returns arguments for the generator.
+func NewDefaults() (*args.GeneratorArgs, *CustomArgs) {
+	genericArgs := args.Default().WithoutDefaultFlagParsing()
+	customArgs := &CustomArgs{}
+	genericArgs.CustomArgs = customArgs
+	genericArgs.Owner = "template-gen"
+	genericArgs.Markers = types.NewMarkerRegistry()
+	return genericArgs, customArgs
+}
+
+// AddFlags add the generator flags to the flag set.
+func (ca *CustomArgs) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&ca.ConfigPath, "config", "c", ca.ConfigPath,
+		"The JSON file declaring the templates, their marker filters and their output file names.")
+	args.MarkPathFlags(fs, "config")
+}
+
+// Load reads the config file and registers the markers of its templates in
+// the marker registry of genericArgs.
+func (ca *CustomArgs) Load(genericArgs *args.GeneratorArgs) error {
+	if ca.ConfigPath == "" {
+		return fmt.Errorf("no config file given")
+	}
+	cfg, err := LoadConfig(ca.ConfigPath)
+	if err != nil {
+		return err
+	}
+	ca.Config = cfg
+	if genericArgs.Markers != nil {
+		genericArgs.Markers.Register(cfg.Markers()...)
+	}
+	return nil
+}
+
+// Validate checks the given arguments.
+func Validate(genericArgs *args.GeneratorArgs) error {
+	customArgs := genericArgs.CustomArgs.(*CustomArgs)
+
+	if len(genericArgs.InputDirs) == 0 {
+		return fmt.Errorf("input directories cannot be empty")
+	}
+
+	if customArgs.Config == nil {
+		return fmt.Errorf("no template config loaded")
+	}
+
+	return nil
+}
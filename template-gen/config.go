@@ -0,0 +1,182 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package template_gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/vine-io/gogogen/gogenerator/types"
+)
+
+// Config declares the templates run by template-gen. It is read from a JSON
+// file such as:
+//
+//	{
+//	  "templates": [{
+//	    "name": "getters",
+//	    "template": "getters.go.tmpl",
+//	    "output": "getters_generated.go",
+//	    "markers": ["+example:getters=true"]
+//	  }]
+//	}
+type Config struct {
+	Templates []*Template `json:"templates"`
+}
+
+// Template is one template of a Config.
+type Template struct {
+	// The name of the generator, defaults to the base name of the template.
+	Name string `json:"name,omitempty"`
+	// The path of the text/template file, relative to the config file.
+	Template string `json:"template"`
+	// The name of the go file generated in each package with matching types.
+	Output string `json:"output"`
+	// The markers a type must have to be passed to the template, of the form
+	// "+name" or "+name=value". A type without value only needs the marker.
+	Markers []string `json:"markers,omitempty"`
+
+	// The content of the template file.
+	text    string
+	filters []markerFilter
+}
+
+// markerFilter is a parsed entry of Template.Markers.
+type markerFilter struct {
+	name     string
+	value    string
+	hasValue bool
+}
+
+// match returns whether comments hold the marker.
+func (f markerFilter) match(comments []string) bool {
+	values, ok := types.ExtractCommentTags("+", comments)[f.name]
+	if !ok {
+		return false
+	}
+	if !f.hasValue {
+		return true
+	}
+	for _, v := range values {
+		if v == f.value {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadConfig reads the config file at path and the templates it declares.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	cfg := &Config{}
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(cfg.Templates) == 0 {
+		return nil, fmt.Errorf("%s: no templates declared", path)
+	}
+
+	names := map[string]bool{}
+	for i, t := range cfg.Templates {
+		if err := t.load(filepath.Dir(path)); err != nil {
+			return nil, fmt.Errorf("%s: template %d: %v", path, i, err)
+		}
+		if names[t.Name] {
+			return nil, fmt.Errorf("%s: template %q is declared twice", path, t.Name)
+		}
+		names[t.Name] = true
+	}
+	return cfg, nil
+}
+
+// load checks t, parses its markers and reads its template file, relative
+// to dir.
+func (t *Template) load(dir string) error {
+	if t.Template == "" {
+		return fmt.Errorf("no template file")
+	}
+	if t.Name == "" {
+		t.Name = strings.SplitN(filepath.Base(t.Template), ".", 2)[0]
+	}
+	if !strings.HasSuffix(t.Output, ".go") || strings.ContainsAny(t.Output, `/\`) {
+		return fmt.Errorf("output %q must be the name of a go file", t.Output)
+	}
+	if len(t.Markers) == 0 {
+		return fmt.Errorf("no markers, at least one is needed to select the types")
+	}
+
+	t.filters = nil
+	for _, m := range t.Markers {
+		if !strings.HasPrefix(m, "+") || len(m) == 1 {
+			return fmt.Errorf("marker %q must be of the form +name or +name=value", m)
+		}
+		kv := strings.SplitN(m[1:], "=", 2)
+		f := markerFilter{name: kv[0]}
+		if len(kv) == 2 {
+			f.value, f.hasValue = kv[1], true
+		}
+		t.filters = append(t.filters, f)
+	}
+
+	path := t.Template
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	t.text = string(text)
+	return nil
+}
+
+// Match returns whether t is passed to the template, i.e. whether it has all
+// of its markers.
+func (t *Template) Match(typ *types.Type) bool {
+	comments := append(append([]string{}, typ.SecondClosestCommentLines...), typ.CommentLines...)
+	for _, f := range t.filters {
+		if !f.match(comments) {
+			return false
+		}
+	}
+	return true
+}
+
+// Markers returns the markers read by the templates of cfg, to be registered
+// in the marker registry of the generator.
+func (cfg *Config) Markers() []*types.Marker {
+	var markers []*types.Marker
+	for _, t := range cfg.Templates {
+		for _, f := range t.filters {
+			markers = append(markers, &types.Marker{
+				Name:        f.name,
+				Scope:       types.TypeScope,
+				Value:       types.StringMarker,
+				Generator:   "template-gen",
+				Description: fmt.Sprintf("Selects the type for the %q template.", t.Name),
+			})
+		}
+	}
+	return markers
+}
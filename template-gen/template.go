@@ -0,0 +1,238 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package template_gen
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/vine-io/gogogen/gogenerator/args"
+	"github.com/vine-io/gogogen/gogenerator/generator"
+	"github.com/vine-io/gogogen/gogenerator/namer"
+	"github.com/vine-io/gogogen/gogenerator/types"
+	"github.com/vine-io/gogogen/util/log"
+)
+
+// NameSystems returns the name system used by the generators in this package.
+// Every name system is a template function taking a *types.Type, "raw" also
+// adds the import of the type to the generated file.
+func NameSystems() namer.NameSystems {
+	return namer.NameSystems{
+		"public":        namer.NewPublicNamer(0),
+		"private":       namer.NewPrivateNamer(0),
+		"publicPlural":  namer.NewPublicPluralNamer(nil),
+		"privatePlural": namer.NewPrivatePluralNamer(nil),
+		"lowercase":     namer.NewAllLowercasePluralNamer(nil),
+		"raw":           namer.NewRawNamer("", nil),
+	}
+}
+
+// DefaultNameSystem returns the default name system for ordering the types to be
+// processed by the generators in this package.
+func DefaultNameSystem() string {
+	return "public"
+}
+
+// TemplateData is what a template is executed with.
+type TemplateData struct {
+	// The type being generated, nil in the "init" and "finalize" templates.
+	Type *types.Type
+	// All the types of the package passed to the template, in order.
+	Types []*types.Type
+	// The package being generated.
+	Package *types.Package
+	// All the parsed types.
+	Universe types.Universe
+}
+
+// Packages makes a package for every input package with types matching one
+// of the templates of the config. The problems are reported to
+// context.Diagnostics.
+func Packages(context *generator.Context, arguments *args.GeneratorArgs) generator.Packages {
+	boilerplate, err := arguments.LoadGoBoilerplate()
+	if err != nil {
+		context.Diagnostics.Report(fmt.Errorf("failed loading boilerplate: %v", err))
+		return nil
+	}
+	customArgs := arguments.CustomArgs.(*CustomArgs)
+	if customArgs.Config == nil {
+		context.Diagnostics.Report(fmt.Errorf("no template config loaded"))
+		return nil
+	}
+	header := append([]byte(fmt.Sprintf("// +build !%s\n\n", arguments.GeneratedBuildTag)), boilerplate...)
+
+	inputs := append([]string{}, context.Inputs...)
+	sort.Strings(inputs)
+	packages := generator.Packages{}
+	for _, i := range inputs {
+		pkg := context.Universe[i]
+		if pkg == nil {
+			// If the input had no Go files, for example.
+			continue
+		}
+
+		var templates []*Template
+		for _, tmpl := range customArgs.Config.Templates {
+			for _, t := range pkg.Types {
+				if tmpl.Match(t) {
+					templates = append(templates, tmpl)
+					break
+				}
+			}
+		}
+		if len(templates) == 0 {
+			continue
+		}
+
+		log.Debugf("Package %q needs generation", i)
+		packages = append(packages,
+			&generator.DefaultPackage{
+				PackageName: strings.Split(filepath.Base(pkg.Path), ".")[0],
+				PackagePath: pkg.Path,
+				Source:      pkg.SourcePath,
+				HeaderText:  header,
+				GeneratorFunc: func(c *generator.Context) (generators []generator.Generator) {
+					for _, tmpl := range templates {
+						generators = append(generators, newGenTemplate(tmpl, pkg.Path))
+					}
+					return generators
+				},
+				FilterFunc: func(c *generator.Context, t *types.Type) bool {
+					return t.Name.Package == pkg.Path
+				},
+				TypesFrom: []string{pkg.Path},
+			})
+	}
+	return packages
+}
+
+// genTemplate produces the output of a template for one package.
+type genTemplate struct {
+	generator.DefaultGen
+	template      *Template
+	targetPackage string
+	imports       namer.ImportTracker
+	parsed        *template.Template
+}
+
+func newGenTemplate(tmpl *Template, targetPackage string) generator.Generator {
+	return &genTemplate{
+		DefaultGen: generator.DefaultGen{
+			OptionalName: strings.TrimSuffix(tmpl.Output, ".go"),
+		},
+		template:      tmpl,
+		targetPackage: targetPackage,
+		imports:       generator.NewImportTracker(),
+	}
+}
+
+func (g *genTemplate) Name() string {
+	return g.template.Name
+}
+
+func (g *genTemplate) Filter(c *generator.Context, t *types.Type) bool {
+	return g.template.Match(t)
+}
+
+func (g *genTemplate) Namers(c *generator.Context) namer.NameSystems {
+	// Have the raw namer for this file track what it imports.
+	return namer.NameSystems{
+		"raw": namer.NewRawNamer(g.targetPackage, g.imports),
+	}
+}
+
+func (g *genTemplate) Imports(c *generator.Context) (imports map[string]string) {
+	importLines := map[string]string{}
+	for path, name := range g.imports.ImportLines() {
+		if path != g.targetPackage {
+			importLines[path] = name
+		}
+	}
+	return importLines
+}
+
+// Init executes the "init" template, if the template file defines one.
+func (g *genTemplate) Init(c *generator.Context, w io.Writer) error {
+	return g.execute(c, w, "init", nil)
+}
+
+// Finalize executes the "finalize" template, if the template file defines
+// one.
+func (g *genTemplate) Finalize(c *generator.Context, w io.Writer) error {
+	return g.execute(c, w, "finalize", nil)
+}
+
+// GenerateType executes the template file for t.
+func (g *genTemplate) GenerateType(c *generator.Context, t *types.Type, w io.Writer) error {
+	return g.execute(c, w, "", t)
+}
+
+// execute executes the template called name, or the template file itself if
+// name is empty, with t as the type.
+func (g *genTemplate) execute(c *generator.Context, w io.Writer, name string, t *types.Type) error {
+	if g.parsed == nil {
+		parsed, err := template.New(g.template.Template).
+			Funcs(funcMap(c)).
+			Parse(g.template.text)
+		if err != nil {
+			return err
+		}
+		g.parsed = parsed
+	}
+	tmpl := g.parsed
+	if name != "" {
+		if tmpl = g.parsed.Lookup(name); tmpl == nil {
+			return nil
+		}
+	}
+	// The templates range over the universe, which the lookups of the
+	// generators of other packages may add to.
+	universe := c.Universe.Snapshot()
+	return tmpl.Execute(w, &TemplateData{
+		Type:     t,
+		Types:    c.Order,
+		Package:  universe[g.targetPackage],
+		Universe: universe,
+	})
+}
+
+// funcMap returns the functions available to the templates: one per name
+// system of c, and some helpers.
+func funcMap(c *generator.Context) template.FuncMap {
+	funcs := template.FuncMap{
+		// marker returns the first value of a marker of a type, or "".
+		"marker": func(t *types.Type, name string) string {
+			comments := append(append([]string{}, t.SecondClosestCommentLines...), t.CommentLines...)
+			if values := types.ExtractCommentTags("+", comments)[name]; len(values) > 0 {
+				return values[0]
+			}
+			return ""
+		},
+		"lower":      strings.ToLower,
+		"upper":      strings.ToUpper,
+		"hasPrefix":  strings.HasPrefix,
+		"hasSuffix":  strings.HasSuffix,
+		"trimPrefix": strings.TrimPrefix,
+		"trimSuffix": strings.TrimSuffix,
+	}
+	for name, n := range c.Namers {
+		funcs[name] = n.Name
+	}
+	return funcs
+}
@@ -0,0 +1,43 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package template_gen
+
+import (
+	"testing"
+
+	"github.com/vine-io/gogogen/gogenerator/args"
+	gentesting "github.com/vine-io/gogogen/gogenerator/testing"
+)
+
+func TestGolden(t *testing.T) {
+	g, customArgs := NewDefaults()
+	// Keep the golden files free of the year.
+	g.GoHeaderFilePath = ""
+	customArgs.ConfigPath = "testdata/getters/templates.json"
+	if err := customArgs.Load(g); err != nil {
+		t.Fatal(err)
+	}
+
+	gentesting.Run(t, gentesting.Case{
+		Dir:     "testdata/getters",
+		Package: "example.com/template",
+		Runs: []args.Run{{
+			Args:          g,
+			NameSystems:   NameSystems(),
+			DefaultSystem: DefaultNameSystem(),
+			Packages:      Packages,
+		}},
+	})
+}
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"time"
+
+	"example.com/template/other"
+)
+
+// +example:getters=true
+// +example:kind=Resource
+type Resource struct {
+	Name    string
+	Created time.Time
+	Owner   *other.Owner
+}
+
+// +example:getters=true
+// +example:kind=Child
+type Child struct {
+	ID int64
+}
+
+// +example:getters=false
+type Skipped struct {
+	Items []string
+}
@@ -0,0 +1,5 @@
+package other
+
+type Owner struct {
+	Name string
+}
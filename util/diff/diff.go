@@ -0,0 +1,233 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package diff computes line-based unified diffs.
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// The number of unchanged lines shown around each change.
+const context = 3
+
+// The most edits searched for between the common head and tail of the
+// files. Past it the lines in between are shown as replaced, which bounds
+// the memory of the trace to about maxEdits² ints.
+const maxEdits = 1000
+
+type op struct {
+	kind byte // ' ', '-' or '+'
+	text string
+	// The line numbers of the line in old and new, starting at 0.
+	oldLine, newLine int
+}
+
+// Unified returns the unified diff from old to new, whose files are named
+// oldName and newName in the header, or "" if they are equal.
+func Unified(oldName, newName string, old, new []byte) string {
+	if bytes.Equal(old, new) {
+		return ""
+	}
+	ops := edits(splitLines(old), splitLines(new))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+	for start := 0; start < len(ops); {
+		// Find the next change and the end of its hunk, which spans all
+		// the changes less than 2*context lines apart.
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		last := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				last = i
+			} else if i-last > 2*context {
+				break
+			}
+		}
+		from, to := first-context, last+context+1
+		if from < start {
+			from = start
+		}
+		if to > len(ops) {
+			to = len(ops)
+		}
+		writeHunk(&b, ops[from:to])
+		start = to
+	}
+	return b.String()
+}
+
+func writeHunk(b *strings.Builder, ops []op) {
+	oldStart, newStart := ops[0].oldLine, ops[0].newLine
+	oldCount, newCount := 0, 0
+	for _, o := range ops {
+		if o.kind != '+' {
+			oldCount++
+		}
+		if o.kind != '-' {
+			newCount++
+		}
+	}
+	// Empty ranges are numbered after the line they follow.
+	if oldCount > 0 {
+		oldStart++
+	}
+	if newCount > 0 {
+		newStart++
+	}
+	fmt.Fprintf(b, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+	for _, o := range ops {
+		b.WriteByte(o.kind)
+		b.WriteString(o.text)
+		b.WriteByte('\n')
+	}
+}
+
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	lines := strings.Split(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] += "\n\\ No newline at end of file"
+	}
+	return lines
+}
+
+// edits returns the edit script from a to b, which is the shortest one
+// unless they differ by more than maxEdits lines.
+func edits(a, b []string) []op {
+	head := 0
+	for head < len(a) && head < len(b) && a[head] == b[head] {
+		head++
+	}
+	tail := 0
+	for tail < len(a)-head && tail < len(b)-head && a[len(a)-1-tail] == b[len(b)-1-tail] {
+		tail++
+	}
+
+	ops := make([]op, 0, head+tail)
+	for i := 0; i < head; i++ {
+		ops = append(ops, op{kind: ' ', text: a[i], oldLine: i, newLine: i})
+	}
+	for _, o := range myers(a[head:len(a)-tail], b[head:len(b)-tail]) {
+		o.oldLine += head
+		o.newLine += head
+		ops = append(ops, o)
+	}
+	for i := tail; i > 0; i-- {
+		x, y := len(a)-i, len(b)-i
+		ops = append(ops, op{kind: ' ', text: a[x], oldLine: x, newLine: y})
+	}
+	return ops
+}
+
+// replace returns the edit script removing all of a and adding all of b.
+func replace(a, b []string) []op {
+	ops := make([]op, 0, len(a)+len(b))
+	for x := range a {
+		ops = append(ops, op{kind: '-', text: a[x], oldLine: x})
+	}
+	for y := range b {
+		ops = append(ops, op{kind: '+', text: b[y], oldLine: len(a), newLine: y})
+	}
+	return ops
+}
+
+// myers returns the shortest edit script from a to b, computed with the
+// algorithm of Myers, or replace(a, b) if it has more than maxEdits edits.
+func myers(a, b []string) []op {
+	n, m := len(a), len(b)
+	max := n + m
+	off := max + 1
+	v := make([]int, 2*max+3)
+	// trace[d] keeps the furthest x reached on the diagonals -d..d after d
+	// edits, which is all the walk back needs of v.
+	var trace [][]int
+	d := 0
+search:
+	for ; d <= max; d++ {
+		if d > maxEdits {
+			return replace(a, b)
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+		trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+	}
+
+	// Walk the trace back from the end to recover the edits.
+	var ops []op
+	x, y := n, m
+	for ; d >= 0; d-- {
+		prevX, prevY := 0, 0
+		if d > 0 {
+			prev := trace[d-1]
+			at := func(k int) int { return prev[k+d-1] }
+			k := x - y
+			var prevK int
+			if k == -d || (k != d && at(k-1) < at(k+1)) {
+				prevK = k + 1
+			} else {
+				prevK = k - 1
+			}
+			prevX = at(prevK)
+			prevY = prevX - prevK
+		}
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, op{kind: ' ', text: a[x], oldLine: x, newLine: y})
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, op{kind: '+', text: b[y], oldLine: x, newLine: y})
+		} else {
+			x--
+			ops = append(ops, op{kind: '-', text: a[x], oldLine: x, newLine: y})
+		}
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	new := "a\nb\nc\nD\ne\nf\ng\nh\ni\nj\nk\nl"
+	want := `--- old
++++ new
+@@ -1,7 +1,7 @@
+ a
+ b
+ c
+-d
++D
+ e
+ f
+ g
+@@ -9,3 +9,4 @@
+ i
+ j
+ k
++l
+\ No newline at end of file
+`
+	if got := Unified("old", "new", []byte(old), []byte(new)); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if got := Unified("old", "new", []byte(old), []byte(old)); got != "" {
+		t.Errorf("equal files: got %q", got)
+	}
+}
+
+// lcs returns the length of the longest common subsequence of a and b.
+func lcs(a, b []string) int {
+	l := make([][]int, len(a)+1)
+	for i := range l {
+		l[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				l[i][j] = l[i+1][j+1] + 1
+			case l[i+1][j] > l[i][j+1]:
+				l[i][j] = l[i+1][j]
+			default:
+				l[i][j] = l[i][j+1]
+			}
+		}
+	}
+	return l[0][0]
+}
+
+// check verifies that ops turns a into b and that its line numbers follow
+// both files, and returns its number of edits.
+func check(t *testing.T, a, b []string, ops []op) int {
+	t.Helper()
+	var gotA, gotB []string
+	n := 0
+	for _, o := range ops {
+		if o.oldLine != len(gotA) || o.newLine != len(gotB) {
+			t.Fatalf("%+v: want lines %d,%d", o, len(gotA), len(gotB))
+		}
+		if o.kind != '+' {
+			gotA = append(gotA, o.text)
+		}
+		if o.kind != '-' {
+			gotB = append(gotB, o.text)
+		}
+		if o.kind != ' ' {
+			n++
+		}
+	}
+	if strings.Join(gotA, "\n") != strings.Join(a, "\n") || strings.Join(gotB, "\n") != strings.Join(b, "\n") {
+		t.Fatalf("edits of %q to %q give %q to %q", a, b, gotA, gotB)
+	}
+	return n
+}
+
+func TestEditsShortest(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	lines := func() []string {
+		l := make([]string, r.Intn(12))
+		for i := range l {
+			l[i] = string(rune('a' + r.Intn(4)))
+		}
+		return l
+	}
+	for i := 0; i < 1000; i++ {
+		a, b := lines(), lines()
+		n := check(t, a, b, edits(a, b))
+		if want := len(a) + len(b) - 2*lcs(a, b); n != want {
+			t.Fatalf("edits of %q to %q: got %d edits, want %d", a, b, n, want)
+		}
+	}
+}
+
+func TestEditsLarge(t *testing.T) {
+	a := make([]string, 8000)
+	b := make([]string, 8000)
+	for i := range a {
+		a[i] = fmt.Sprint("a", i)
+		b[i] = a[i]
+	}
+	for i := 100; i < len(b); i += 500 {
+		b[i] = "changed"
+	}
+	if n := check(t, a, b, edits(a, b)); n != 32 {
+		t.Errorf("a few changed lines: got %d edits, want 32", n)
+	}
+
+	// Files differing by more than maxEdits lines are shown as replaced
+	// between their common head and tail.
+	for i := 10; i < len(b)-10; i++ {
+		b[i] = fmt.Sprint("b", i)
+	}
+	if n := check(t, a, b, edits(a, b)); n != 2*(len(a)-20) {
+		t.Errorf("all lines changed: got %d edits, want %d", n, 2*(len(a)-20))
+	}
+}
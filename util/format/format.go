@@ -0,0 +1,151 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package format formats generated Go files in-process as goimports and
+// gofmt -s would. Unlike goimports, it never looks up packages: it only
+// removes the unused imports, so the result depends on the source alone.
+package format
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/imports"
+
+	"github.com/vine-io/gogogen/util/third_party/forked/golang/gofmt"
+)
+
+// Source removes the unused imports of src, simplifies it, sorts its imports
+// and formats it. The filename is only used in the errors.
+func Source(filename string, src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	removeUnusedImports(fset, f)
+	gofmt.Simplify(f)
+
+	buf := &bytes.Buffer{}
+	config := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	if err := config.Fprint(buf, fset, f); err != nil {
+		return nil, err
+	}
+	return imports.Process(filename, buf.Bytes(), &imports.Options{
+		Comments:   true,
+		TabIndent:  true,
+		TabWidth:   8,
+		FormatOnly: true,
+	})
+}
+
+// File formats the Go file name in place, see Source. It is left untouched if
+// it is already formatted.
+func File(name string) error {
+	src, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	formatted, err := Source(name, src)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(src, formatted) {
+		return nil
+	}
+	return os.WriteFile(name, formatted, 0644)
+}
+
+// removeUnusedImports removes the imports of f whose name no selector refers
+// to. The name of an unnamed import is guessed from its path; if a selector
+// refers to a package no import is named after, the guesses may be wrong, so
+// the unnamed imports are kept.
+func removeUnusedImports(fset *token.FileSet, f *ast.File) {
+	// The identifiers the parser couldn't resolve in the file are package
+	// names, or declared in the other files of the package.
+	used := map[string]bool{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if x, ok := sel.X.(*ast.Ident); ok && x.Obj == nil {
+				used[x.Name] = true
+			}
+		}
+		return true
+	})
+
+	type unused struct{ name, path string }
+	var named, unnamed []unused
+	known := map[string]bool{}
+	for _, spec := range f.Imports {
+		p, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		switch {
+		case spec.Name == nil:
+			name := assumedName(p)
+			known[name] = true
+			if !used[name] {
+				unnamed = append(unnamed, unused{"", p})
+			}
+		case spec.Name.Name == "_" || spec.Name.Name == ".":
+			// Imported for their side effects or their names.
+		default:
+			known[spec.Name.Name] = true
+			if !used[spec.Name.Name] {
+				named = append(named, unused{spec.Name.Name, p})
+			}
+		}
+	}
+	for name := range used {
+		if !known[name] {
+			unnamed = nil
+			break
+		}
+	}
+	for _, imp := range append(named, unnamed...) {
+		astutil.DeleteNamedImport(fset, f, imp.name, imp.path)
+	}
+}
+
+// assumedName returns the name a package is assumed to have from its import
+// path, as goimports does: the last element of the path, without its major
+// version, "go-" prefix or anything following the first character which can't
+// be part of an identifier.
+func assumedName(importPath string) string {
+	base := path.Base(importPath)
+	if strings.HasPrefix(base, "v") {
+		if _, err := strconv.Atoi(base[1:]); err == nil {
+			if dir := path.Dir(importPath); dir != "." {
+				base = path.Base(dir)
+			}
+		}
+	}
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexFunc(base, func(r rune) bool {
+		return r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
@@ -0,0 +1,110 @@
+// Copyright 2020 lack
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package format
+
+import (
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "unused imports",
+			src: `package p
+import (
+	proto "github.com/gogo/protobuf/proto"
+	github_com_gogo_protobuf_sortkeys "github.com/gogo/protobuf/sortkeys"
+	"io"
+	math "math"
+	"gopkg.in/yaml.v2"
+	_ "embed"
+)
+var _ = proto.Marshal
+var _ = yaml.Marshal
+var _ io.Reader
+`,
+			want: `package p
+
+import (
+	_ "embed"
+	"io"
+
+	proto "github.com/gogo/protobuf/proto"
+	"gopkg.in/yaml.v2"
+)
+
+var _ = proto.Marshal
+var _ = yaml.Marshal
+var _ io.Reader
+`,
+		},
+		{
+			name: "unknown package",
+			src: `package p
+import (
+	"example.com/go-thing"
+	"example.com/unused"
+	fmt "fmt"
+)
+var _ = thing.New
+var _ = other.New
+`,
+			want: `package p
+
+import (
+	"example.com/go-thing"
+	"example.com/unused"
+)
+
+var _ = thing.New
+var _ = other.New
+`,
+		},
+		{
+			name: "simplify",
+			src: `package p
+type T struct{ A int }
+var m = map[string][]*T{"a": []*T{&T{A: 1}}}
+func f(s []int) { for _ = range s[1:len(s)] {} }
+`,
+			want: `package p
+
+type T struct{ A int }
+
+var m = map[string][]*T{"a": {{A: 1}}}
+
+func f(s []int) {
+	for range s[1:] {
+	}
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Source("p.go", []byte(tt.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
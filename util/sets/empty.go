@@ -1,4 +1,6 @@
-// Copyright 2023 lack
+// gogogen:owner=set-gen
+
+// Copyright 2026 lack
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
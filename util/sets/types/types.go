@@ -14,10 +14,10 @@
 
 // Package types just provides input types to the set generator. It also
 // contains a "go generate" block.
-// (You must first `go install github.com/vine-io/gogogen/set-gen`)
+// (You must first `go install github.com/vine-io/gogogen/cmd/set-gen`)
 package types
 
-//go:generate set-gen -i github.com/vine-io/gogogen/util/sets/types -o github.com/vine-io/vineadm/util/sets
+//go:generate set-gen --go-generate -p ..
 
 type ReferenceSetType struct {
 	// There types all case files to be generated